@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var areaValidate = validator.New()
+
+// GetAreas returns all areas in floor plan order
+func GetAreas() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		opt := options.Find().SetSort(bson.D{{Key: "sort_order", Value: 1}, {Key: "name", Value: 1}})
+
+		var areas []models.Area
+		cursor, err := getAreaCollection().Find(ctx, bson.M{}, opt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing areas"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &areas); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, areas)
+	}
+}
+
+// GetAreaByID returns a single area by ID
+func GetAreaByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		areaId := c.Param("area_id")
+		var area models.Area
+
+		err := getAreaCollection().FindOne(ctx, bson.M{"area_id": areaId}).Decode(&area)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the area"})
+			return
+		}
+
+		c.JSON(http.StatusOK, area)
+	}
+}
+
+// CreateArea creates a new area
+func CreateArea() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var area models.Area
+		if err := c.BindJSON(&area); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := areaValidate.Struct(area)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		count, err := getAreaCollection().CountDocuments(ctx, bson.M{"name": area.Name})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for area"})
+			return
+		}
+
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "an area with this name already exists"})
+			return
+		}
+
+		area.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		area.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		area.ID = primitive.NewObjectID()
+		area.AreaID = area.ID.Hex()
+
+		result, insertErr := getAreaCollection().InsertOne(ctx, area)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "area was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateArea updates an existing area
+func UpdateArea() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var area models.Area
+		areaId := c.Param("area_id")
+
+		if err := c.BindJSON(&area); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := areaValidate.StructPartial(area, "Width", "Height")
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if area.Name != nil {
+			validationErr := areaValidate.StructPartial(area, "Name")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+
+			count, err := getAreaCollection().CountDocuments(ctx, bson.M{"name": area.Name, "area_id": bson.M{"$ne": areaId}})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for area"})
+				return
+			}
+
+			if count > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "an area with this name already exists"})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "name", Value: area.Name})
+		}
+
+		if area.SortOrder != nil {
+			updateObj = append(updateObj, bson.E{Key: "sort_order", Value: area.SortOrder})
+		}
+
+		if area.Width != nil {
+			updateObj = append(updateObj, bson.E{Key: "width", Value: area.Width})
+		}
+
+		if area.Height != nil {
+			updateObj = append(updateObj, bson.E{Key: "height", Value: area.Height})
+		}
+
+		area.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: area.UpdatedAt})
+
+		filter := bson.M{"area_id": areaId}
+
+		result, err := getAreaCollection().UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "area update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
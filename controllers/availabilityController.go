@@ -0,0 +1,476 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/events"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errFoodSoldOut       = errors.New("food item is sold out")
+	errNotEnoughPortions = errors.New("not enough portions left")
+)
+
+// Why a food is sold out. Only foods an ingredient sold out come back by
+// themselves when it is restocked.
+const (
+	soldOutManual     = "MANUAL"
+	soldOutNoPortions = "NO_PORTIONS"
+	soldOutIngredient = "INGREDIENT"
+)
+
+// orderItemStatus maps an error adding an order item to its HTTP status
+func orderItemStatus(err error) int {
+	if errors.Is(err, errFoodSoldOut) || errors.Is(err, errNotEnoughPortions) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// eventHeartbeat is how often an idle event stream is pinged so proxies
+// keep it open
+const eventHeartbeat = 30 * time.Second
+
+// foodAvailability is what terminals are told when a food sells out, comes
+// back or has fewer portions left
+type foodAvailability struct {
+	FoodID         string `json:"food_id"`
+	Name           string `json:"name"`
+	Available      bool   `json:"available"`
+	RemainingCount *int   `json:"remaining_count"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// foodAvailable reports whether a food can be ordered. Foods saved before
+// availability was tracked have no flag and are available.
+func foodAvailable(food models.Food) bool {
+	return food.Available == nil || *food.Available
+}
+
+func availabilityOf(food models.Food, reason string) foodAvailability {
+	name := ""
+	if food.Name != nil {
+		name = *food.Name
+	}
+	return foodAvailability{
+		FoodID:         food.FoodID,
+		Name:           name,
+		Available:      foodAvailable(food),
+		RemainingCount: food.RemainingCount,
+		Reason:         reason,
+	}
+}
+
+// checkClaim reports whether quantity portions of a food can be taken as
+// it was read. Foods off sale are refused, and so are counted foods with
+// fewer portions left than asked for.
+func checkClaim(food models.Food, quantity int) error {
+	if quantity < 1 {
+		return fmt.Errorf("at least one portion must be claimed, not %d", quantity)
+	}
+	if !foodAvailable(food) {
+		return fmt.Errorf("%w: %s", errFoodSoldOut, *food.Name)
+	}
+	if food.RemainingCount != nil && *food.RemainingCount < quantity {
+		if *food.RemainingCount <= 0 {
+			return fmt.Errorf("%w: %s", errFoodSoldOut, *food.Name)
+		}
+		return fmt.Errorf("%w: only %d %s left", errNotEnoughPortions, *food.RemainingCount, *food.Name)
+	}
+	return nil
+}
+
+// portionChanges works out the portions an order item change moves: the
+// extra quantity of the same food, or all of a new food, is claimed, and
+// what is no longer needed is given back. No more than the item held is
+// ever given back.
+func portionChanges(current models.OrderItem, foodId string, quantity int) (claimFood string, claim int, releaseFood string, release int) {
+	if quantity < 0 {
+		quantity = 0
+	}
+
+	if foodId != *current.FoodID {
+		return foodId, quantity, *current.FoodID, *current.Quantity
+	}
+	if quantity > *current.Quantity {
+		return foodId, quantity - *current.Quantity, "", 0
+	}
+	return "", 0, foodId, *current.Quantity - quantity
+}
+
+// claimPortions checks a food can be ordered and, when its portions are
+// counted, takes quantity of them in one atomic update. Taking the last
+// portion sells the food out.
+func claimPortions(ctx context.Context, food models.Food, quantity int) error {
+	if err := checkClaim(food, quantity); err != nil {
+		return err
+	}
+	if food.RemainingCount == nil {
+		return nil
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	var claimed models.Food
+	err := getFoodCollection().FindOneAndUpdate(ctx,
+		bson.M{"food_id": food.FoodID, "available": bson.M{"$ne": false}, "remaining_count": bson.M{"$gte": quantity}},
+		bson.M{"$inc": bson.M{"remaining_count": -quantity}, "$set": bson.M{"updated_at": updatedAt}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&claimed)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Someone else may have taken the portions since the food was read
+		var current models.Food
+		if getFoodCollection().FindOne(ctx, bson.M{"food_id": food.FoodID}).Decode(&current) != nil ||
+			!foodAvailable(current) || current.RemainingCount == nil || *current.RemainingCount <= 0 {
+			return fmt.Errorf("%w: %s", errFoodSoldOut, *food.Name)
+		}
+		return fmt.Errorf("%w: only %d %s left", errNotEnoughPortions, *current.RemainingCount, *food.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *claimed.RemainingCount == 0 {
+		markSoldOut(ctx, bson.M{"food_id": claimed.FoodID}, soldOutNoPortions, nil)
+	} else {
+		events.Publish("food.remaining", availabilityOf(claimed, ""))
+	}
+	return nil
+}
+
+// releasePortions gives back portions claimed for an order item that was
+// not taken or was voided. Foods that are not counted are left alone, and
+// only a food sold out for lack of portions comes back; one sold out by
+// hand or by an ingredient stays off sale.
+func releasePortions(ctx context.Context, foodId string, quantity int) {
+	if quantity <= 0 {
+		return
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	var released models.Food
+	err := getFoodCollection().FindOneAndUpdate(ctx,
+		bson.M{"food_id": foodId, "remaining_count": bson.M{"$ne": nil}},
+		bson.M{"$inc": bson.M{"remaining_count": quantity}, "$set": bson.M{"updated_at": updatedAt}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&released)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return
+	}
+	if err != nil {
+		log.Println("portions were not released:", err)
+		return
+	}
+
+	if foodAvailable(released) {
+		events.Publish("food.remaining", availabilityOf(released, ""))
+		return
+	}
+
+	err = getFoodCollection().FindOneAndUpdate(ctx,
+		bson.M{
+			"food_id":         foodId,
+			"available":       false,
+			"sold_out_reason": soldOutNoPortions,
+			"remaining_count": bson.M{"$gt": 0},
+		},
+		bson.M{"$set": bson.M{
+			"available":              true,
+			"sold_out_reason":        "",
+			"sold_out_ingredient_id": nil,
+			"updated_at":             updatedAt,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&released)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return
+	}
+	if err != nil {
+		log.Println("food was not brought back:", err)
+		return
+	}
+
+	events.Publish("food.available", availabilityOf(released, ""))
+}
+
+// markSoldOut takes the available foods matching filter off sale for the
+// given cause, recording the ingredient that ran out if any, and tells the
+// terminals why. Foods already sold out keep their cause.
+func markSoldOut(ctx context.Context, filter bson.M, cause string, ingredient *models.Ingredient) {
+	filter["available"] = bson.M{"$ne": false}
+
+	reason := "no portions left"
+	set := bson.M{"available": false, "sold_out_reason": cause, "sold_out_ingredient_id": nil}
+	if ingredient != nil {
+		reason = "out of " + *ingredient.Name
+		set["sold_out_ingredient_id"] = ingredient.IngredientID
+	}
+
+	var foods []models.Food
+	if err := findAll(ctx, getFoodCollection(), filter, &foods); err != nil {
+		log.Println("foods were not sold out:", err)
+		return
+	}
+	if len(foods) == 0 {
+		return
+	}
+
+	foodIds := make([]string, len(foods))
+	for i, food := range foods {
+		foodIds[i] = food.FoodID
+	}
+
+	set["updated_at"], _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	_, err := getFoodCollection().UpdateMany(ctx,
+		bson.M{"food_id": bson.M{"$in": foodIds}, "available": bson.M{"$ne": false}},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		log.Println("foods were not sold out:", err)
+		return
+	}
+
+	unavailable := false
+	for _, food := range foods {
+		food.Available = &unavailable
+		log.Printf("sold out: %s (%s)", *food.Name, reason)
+		events.Publish("food.sold_out", availabilityOf(food, reason))
+	}
+}
+
+// soldOutByIngredient sells out every food whose recipe uses an ingredient
+// that has run out
+func soldOutByIngredient(ctx context.Context, ingredient models.Ingredient) {
+	var recipes []models.Recipe
+	if err := findAll(ctx, getRecipeCollection(), bson.M{"lines.ingredient_id": ingredient.IngredientID}, &recipes); err != nil {
+		log.Println("recipes were not checked for sold out foods:", err)
+		return
+	}
+	if len(recipes) == 0 {
+		return
+	}
+
+	foodIds := make([]string, len(recipes))
+	for i, recipe := range recipes {
+		foodIds[i] = recipe.FoodID
+	}
+	markSoldOut(ctx, bson.M{"food_id": bson.M{"$in": foodIds}}, soldOutIngredient, &ingredient)
+}
+
+// backInStock puts back on sale the foods an ingredient sold out once it
+// is in stock again. A food still out of another ingredient waits for that
+// one instead; foods sold out by hand or for lack of portions stay off.
+func backInStock(ctx context.Context, ingredient models.Ingredient) {
+	var foods []models.Food
+	err := findAll(ctx, getFoodCollection(), bson.M{
+		"available":              false,
+		"sold_out_reason":        soldOutIngredient,
+		"sold_out_ingredient_id": ingredient.IngredientID,
+	}, &foods)
+	if err != nil {
+		log.Println("foods were not brought back:", err)
+		return
+	}
+
+	for _, food := range foods {
+		var recipe models.Recipe
+		err := getRecipeCollection().FindOne(ctx, bson.M{"food_id": food.FoodID}).Decode(&recipe)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Println("food was not brought back:", err)
+			continue
+		}
+
+		var ingredientIds []string
+		for _, line := range recipe.Lines {
+			ingredientIds = append(ingredientIds, *line.IngredientID)
+		}
+
+		filter := bson.M{
+			"food_id":                food.FoodID,
+			"available":              false,
+			"sold_out_reason":        soldOutIngredient,
+			"sold_out_ingredient_id": ingredient.IngredientID,
+		}
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		var missing models.Ingredient
+		err = getIngredientCollection().FindOne(ctx,
+			bson.M{"ingredient_id": bson.M{"$in": ingredientIds}, "stock": bson.M{"$lte": 0}},
+		).Decode(&missing)
+		if err == nil {
+			getFoodCollection().UpdateOne(ctx, filter,
+				bson.M{"$set": bson.M{"sold_out_ingredient_id": missing.IngredientID, "updated_at": updatedAt}},
+			)
+			continue
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Println("food was not brought back:", err)
+			continue
+		}
+
+		result, err := getFoodCollection().UpdateOne(ctx, filter,
+			bson.M{"$set": bson.M{
+				"available":              true,
+				"sold_out_reason":        "",
+				"sold_out_ingredient_id": nil,
+				"updated_at":             updatedAt,
+			}},
+		)
+		if err != nil || result.MatchedCount == 0 {
+			continue
+		}
+
+		available := true
+		food.Available = &available
+		log.Printf("back on sale: %s (%s restocked)", *food.Name, *ingredient.Name)
+		events.Publish("food.available", availabilityOf(food, ""))
+	}
+}
+
+// availabilityRequest takes a food off sale or puts it back, and sets or
+// stops counting its remaining portions
+type availabilityRequest struct {
+	Available      *bool `json:"available"`
+	RemainingCount *int  `json:"remaining_count" validate:"omitempty,gte=0,lte=10000"`
+	Unlimited      bool  `json:"unlimited"`
+}
+
+// SetFoodAvailability sells a food out ("86" it) or brings it back, and
+// sets how many portions are left. Setting a count without availability
+// makes the food available when the count is above zero. Foods sold out
+// automatically stay off sale until they are brought back here.
+func SetFoodAvailability() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		foodId := c.Param("food_id")
+
+		var request availabilityRequest
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := foodValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if request.Available == nil && request.RemainingCount == nil && !request.Unlimited {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "available, remaining_count or unlimited is required"})
+			return
+		}
+		if request.Unlimited && request.RemainingCount != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "remaining_count cannot be set on an unlimited food"})
+			return
+		}
+
+		var food models.Food
+		err := getFoodCollection().FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
+
+		if request.Unlimited {
+			food.RemainingCount = nil
+		}
+		if request.RemainingCount != nil {
+			food.RemainingCount = request.RemainingCount
+			if request.Available == nil {
+				available := *request.RemainingCount > 0
+				food.Available = &available
+			}
+		}
+		if request.Available != nil {
+			food.Available = request.Available
+		}
+
+		if foodAvailable(food) && food.RemainingCount != nil && *food.RemainingCount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a food with no portions left cannot be available"})
+			return
+		}
+
+		available := foodAvailable(food)
+		food.Available = &available
+		food.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		// Selling a food out here is manual, so a restock does not undo it
+		food.SoldOutReason = ""
+		food.SoldOutIngredientID = nil
+		if !available {
+			food.SoldOutReason = soldOutManual
+			if request.Available == nil {
+				food.SoldOutReason = soldOutNoPortions
+			}
+		}
+
+		_, err = getFoodCollection().UpdateOne(ctx,
+			bson.M{"food_id": foodId},
+			bson.M{"$set": bson.M{
+				"available":              food.Available,
+				"remaining_count":        food.RemainingCount,
+				"sold_out_reason":        food.SoldOutReason,
+				"sold_out_ingredient_id": food.SoldOutIngredientID,
+				"updated_at":             food.UpdatedAt,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "food availability update failed"})
+			return
+		}
+
+		if available {
+			events.Publish("food.available", availabilityOf(food, ""))
+		} else {
+			events.Publish("food.sold_out", availabilityOf(food, "marked sold out"))
+		}
+
+		c.JSON(http.StatusOK, food)
+	}
+}
+
+// StreamEvents streams events such as foods selling out to a terminal as
+// server-sent events until it disconnects
+func StreamEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stream, unsubscribe := events.Subscribe()
+		defer unsubscribe()
+
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case event, ok := <-stream:
+				if !ok {
+					return false
+				}
+				c.SSEvent(event.Type, event)
+				return true
+			case now := <-heartbeat.C:
+				c.SSEvent("ping", gin.H{"at": now})
+				return true
+			}
+		})
+	}
+}
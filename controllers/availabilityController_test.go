@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+func TestCheckClaim(t *testing.T) {
+	name := "Soup"
+	on, off := true, false
+	count := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		food     models.Food
+		quantity int
+		want     error
+	}{
+		{"not counted", models.Food{Name: &name}, 5, nil},
+		{"enough left", models.Food{Name: &name, RemainingCount: count(3)}, 3, nil},
+		{"more than is left", models.Food{Name: &name, RemainingCount: count(2)}, 3, errNotEnoughPortions},
+		{"none left", models.Food{Name: &name, Available: &on, RemainingCount: count(0)}, 1, errFoodSoldOut},
+		{"sold out", models.Food{Name: &name, Available: &off, RemainingCount: count(10)}, 1, errFoodSoldOut},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkClaim(tt.food, tt.quantity)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("checkClaim() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := checkClaim(models.Food{Name: &name}, 0); err == nil {
+		t.Error("checkClaim() of no portions was allowed")
+	}
+}
+
+func TestPortionChanges(t *testing.T) {
+	soup, salad := "soup", "salad"
+	item := func(quantity int) models.OrderItem {
+		return models.OrderItem{FoodID: &soup, Quantity: &quantity}
+	}
+
+	tests := []struct {
+		name        string
+		current     models.OrderItem
+		foodId      string
+		quantity    int
+		claimFood   string
+		claim       int
+		releaseFood string
+		release     int
+	}{
+		{"more of the same food", item(2), soup, 5, soup, 3, "", 0},
+		{"less of the same food", item(5), soup, 2, "", 0, soup, 3},
+		{"unchanged", item(2), soup, 2, "", 0, soup, 0},
+		{"another food", item(2), salad, 3, salad, 3, soup, 2},
+		{"release clamped to what was held", item(2), soup, -4, "", 0, soup, 2},
+		{"another food with a negative quantity", item(2), salad, -1, salad, 0, soup, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claimFood, claim, releaseFood, release := portionChanges(tt.current, tt.foodId, tt.quantity)
+			if claimFood != tt.claimFood || claim != tt.claim {
+				t.Errorf("claim = %q %d, want %q %d", claimFood, claim, tt.claimFood, tt.claim)
+			}
+			if releaseFood != tt.releaseFood || release != tt.release {
+				t.Errorf("release = %q %d, want %q %d", releaseFood, release, tt.releaseFood, tt.release)
+			}
+		})
+	}
+}
@@ -0,0 +1,462 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/database"
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errDayClosed = errors.New("the business day of this order is closed")
+
+// currentBusinessDay returns the open business day, opening a new one
+// when the last day has been closed. A unique index allows one OPEN day,
+// so when two requests open a day at once the loser reads the winner's.
+func currentBusinessDay(ctx context.Context) (models.BusinessDay, error) {
+	day, err := openBusinessDay(ctx)
+	if mongo.IsDuplicateKeyError(err) {
+		return openBusinessDay(ctx)
+	}
+	return day, err
+}
+
+func openBusinessDay(ctx context.Context) (models.BusinessDay, error) {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	id := primitive.NewObjectID()
+
+	upsert := true
+	after := options.After
+	opt := options.FindOneAndUpdateOptions{
+		Upsert:         &upsert,
+		ReturnDocument: &after,
+	}
+
+	var day models.BusinessDay
+	err := getBusinessDayCollection().FindOneAndUpdate(ctx,
+		bson.M{"status": "OPEN"},
+		bson.M{"$setOnInsert": bson.M{
+			"_id":             id,
+			"business_day_id": id.Hex(),
+			"date":            now.In(helpers.RestaurantLocation()).Format("2006-01-02"),
+			"opened_at":       now,
+		}},
+		&opt,
+	).Decode(&day)
+	return day, err
+}
+
+// checkOrderOpen returns errDayClosed when an order belongs to a business
+// day that has been closed
+func checkOrderOpen(ctx context.Context, order models.Order) error {
+	if order.BusinessDayID == "" {
+		return nil
+	}
+
+	count, err := getBusinessDayCollection().CountDocuments(ctx, bson.M{"business_day_id": order.BusinessDayID, "status": "CLOSED"})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errDayClosed
+	}
+	return nil
+}
+
+// zActivity is everything recorded during a business day that its Z
+// report totals
+type zActivity struct {
+	Invoices []models.Invoice
+	Payments []models.Payment
+	Refunds  []models.Refund
+	Voids    []models.OrderItem
+	Tips     []models.Tip
+	Drawers  []models.DrawerSession
+}
+
+// buildZReport totals everything that happened between the opening of a
+// business day and to: invoiced sales, tenders, refunds, voids, tips and
+// the day's cash drawers
+func buildZReport(ctx context.Context, day models.BusinessDay, to time.Time) (models.ZReport, error) {
+	var activity zActivity
+	inRange := bson.M{"$gte": day.OpenedAt, "$lt": to}
+
+	if err := findAll(ctx, getInvoiceCollection(), bson.M{"created_at": inRange}, &activity.Invoices); err != nil {
+		return models.ZReport{}, err
+	}
+	if err := findAll(ctx, getPaymentCollection(), bson.M{"created_at": inRange}, &activity.Payments); err != nil {
+		return models.ZReport{}, err
+	}
+	if err := findAll(ctx, getRefundCollection(), bson.M{"created_at": inRange, "status": refundCounted}, &activity.Refunds); err != nil {
+		return models.ZReport{}, err
+	}
+	if err := findAll(ctx, getOrderItemCollection(), bson.M{"voided": true, "voided_at": inRange}, &activity.Voids); err != nil {
+		return models.ZReport{}, err
+	}
+	if err := findAll(ctx, getTipCollection(), bson.M{"created_at": inRange}, &activity.Tips); err != nil {
+		return models.ZReport{}, err
+	}
+	if err := findAll(ctx, getDrawerSessionCollection(), bson.M{"business_day_id": day.BusinessDayID}, &activity.Drawers); err != nil {
+		return models.ZReport{}, err
+	}
+
+	return totalZReport(day, to, activity), nil
+}
+
+// totalZReport adds up the activity of a business day into its Z report.
+// Discounts are totalled per discount, taxes per category and rate, and
+// tenders and refunds per payment method; every amount is rounded once,
+// after it is added up.
+func totalZReport(day models.BusinessDay, to time.Time, activity zActivity) models.ZReport {
+	report := models.ZReport{
+		BusinessDate: day.Date,
+		From:         day.OpenedAt,
+		To:           to,
+		Discounts:    []models.DiscountLine{},
+		Taxes:        []models.TaxLine{},
+		Tenders:      []models.TenderTotal{},
+		Refunds:      []models.TenderTotal{},
+		Drawers:      []models.DrawerSession{},
+	}
+
+	discounts := map[string]*models.DiscountLine{}
+	taxes := map[string]*models.TaxLine{}
+	for _, invoice := range activity.Invoices {
+		report.InvoiceCount++
+		report.Covers += invoice.Covers
+		report.GrossSales += invoice.SubTotal
+		report.DiscountTotal += invoice.DiscountTotal
+		report.ServiceCharge += invoice.ServiceCharge
+		report.TaxTotal += invoice.TaxTotal
+		report.Total += invoice.Total
+
+		for _, line := range invoice.Discounts {
+			total, ok := discounts[line.DiscountID]
+			if !ok {
+				total = &models.DiscountLine{DiscountID: line.DiscountID, Name: line.Name}
+				discounts[line.DiscountID] = total
+			}
+			total.Amount += line.Amount
+		}
+
+		for _, line := range invoice.Taxes {
+			key := line.TaxCategoryID + "@" + strconv.FormatFloat(line.Rate, 'f', -1, 64)
+			total, ok := taxes[key]
+			if !ok {
+				total = &models.TaxLine{TaxCategoryID: line.TaxCategoryID, Name: line.Name, Rate: line.Rate}
+				taxes[key] = total
+			}
+			total.NetAmount += line.NetAmount
+			total.TaxAmount += line.TaxAmount
+		}
+	}
+
+	for _, line := range discounts {
+		line.Amount = helpers.RoundMoney(line.Amount)
+		report.Discounts = append(report.Discounts, *line)
+	}
+	for _, line := range taxes {
+		line.NetAmount = helpers.RoundMoney(line.NetAmount)
+		line.TaxAmount = helpers.RoundMoney(line.TaxAmount)
+		report.Taxes = append(report.Taxes, *line)
+	}
+	sort.Slice(report.Discounts, func(i, j int) bool { return report.Discounts[i].Name < report.Discounts[j].Name })
+	sort.Slice(report.Taxes, func(i, j int) bool {
+		if report.Taxes[i].Name != report.Taxes[j].Name {
+			return report.Taxes[i].Name < report.Taxes[j].Name
+		}
+		return report.Taxes[i].Rate < report.Taxes[j].Rate
+	})
+
+	tenders := map[string]*models.TenderTotal{}
+	for _, payment := range activity.Payments {
+		if payment.Method == nil || payment.Amount == nil {
+			continue
+		}
+		total, ok := tenders[*payment.Method]
+		if !ok {
+			total = &models.TenderTotal{Method: *payment.Method}
+			tenders[*payment.Method] = total
+		}
+		total.Count++
+		total.Amount += *payment.Amount
+	}
+	report.Tenders = tenderList(tenders)
+
+	refundTenders := map[string]*models.TenderTotal{}
+	for _, refund := range activity.Refunds {
+		if refund.Amount == nil {
+			continue
+		}
+		total, ok := refundTenders[refund.Method]
+		if !ok {
+			total = &models.TenderTotal{Method: refund.Method}
+			refundTenders[refund.Method] = total
+		}
+		total.Count++
+		total.Amount += *refund.Amount
+		report.RefundTotal += *refund.Amount
+	}
+	report.Refunds = tenderList(refundTenders)
+
+	for _, item := range activity.Voids {
+		report.VoidCount++
+		if item.Quantity != nil && item.UnitPrice != nil {
+			report.VoidTotal += float64(*item.Quantity) * *item.UnitPrice
+		}
+	}
+
+	for _, tip := range activity.Tips {
+		if tip.Amount != nil {
+			report.TipTotal += *tip.Amount
+		}
+	}
+
+	if activity.Drawers != nil {
+		report.Drawers = activity.Drawers
+	}
+
+	report.GrossSales = helpers.RoundMoney(report.GrossSales)
+	report.DiscountTotal = helpers.RoundMoney(report.DiscountTotal)
+	report.ServiceCharge = helpers.RoundMoney(report.ServiceCharge)
+	report.TaxTotal = helpers.RoundMoney(report.TaxTotal)
+	report.Total = helpers.RoundMoney(report.Total)
+	report.RefundTotal = helpers.RoundMoney(report.RefundTotal)
+	report.NetTotal = helpers.RoundMoney(report.Total - report.RefundTotal)
+	report.VoidTotal = helpers.RoundMoney(report.VoidTotal)
+	report.TipTotal = helpers.RoundMoney(report.TipTotal)
+
+	return report
+}
+
+// closeDay returns a business day closed with its Z report under Z number
+// seq, as it is stored
+func closeDay(day models.BusinessDay, report models.ZReport, seq int64, closedAt time.Time, closedBy string) models.BusinessDay {
+	report.ZNumber = int(seq)
+
+	day.Status = "CLOSED"
+	day.ClosedAt = &closedAt
+	day.ClosedBy = closedBy
+	day.ZNumber = report.ZNumber
+	day.ZReport = &report
+	return day
+}
+
+// findAll decodes every document matching filter into results
+func findAll(ctx context.Context, collection *mongo.Collection, filter interface{}, results interface{}) error {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, results)
+}
+
+// tenderList turns tender totals into a list sorted by payment method
+func tenderList(tenders map[string]*models.TenderTotal) []models.TenderTotal {
+	list := []models.TenderTotal{}
+	for _, total := range tenders {
+		total.Amount = helpers.RoundMoney(total.Amount)
+		list = append(list, *total)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Method < list[j].Method })
+	return list
+}
+
+// GetBusinessDays returns all business days
+func GetBusinessDays() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var days []models.BusinessDay
+		cursor, err := getBusinessDayCollection().Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing business days"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &days); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, days)
+	}
+}
+
+// GetCurrentBusinessDay returns the open business day, opening one if
+// the last day has been closed
+func GetCurrentBusinessDay() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		day, err := currentBusinessDay(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while opening the business day"})
+			return
+		}
+
+		c.JSON(http.StatusOK, day)
+	}
+}
+
+// GetBusinessDayByID returns a single business day by ID
+func GetBusinessDayByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		businessDayId := c.Param("business_day_id")
+		var day models.BusinessDay
+
+		err := getBusinessDayCollection().FindOne(ctx, bson.M{"business_day_id": businessDayId}).Decode(&day)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the business day"})
+			return
+		}
+
+		c.JSON(http.StatusOK, day)
+	}
+}
+
+// GetBusinessDayReport returns the Z report of a closed day, or a running
+// (X) report of the open day so far
+func GetBusinessDayReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		businessDayId := c.Param("business_day_id")
+		var day models.BusinessDay
+
+		err := getBusinessDayCollection().FindOne(ctx, bson.M{"business_day_id": businessDayId}).Decode(&day)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "business day was not found"})
+			return
+		}
+
+		if day.Status == "CLOSED" && day.ZReport != nil {
+			c.JSON(http.StatusOK, day.ZReport)
+			return
+		}
+
+		report, err := buildZReport(ctx, day, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while building the report"})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// CloseBusinessDay ends the open business day. It refuses while orders are
+// still OPEN or cash drawers are not counted, then locks the day and
+// stores its Z report under the next Z number. Closing needs a manager.
+func CloseBusinessDay() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			ManagerEmail    *string `json:"manager_email"`
+			ManagerPassword *string `json:"manager_password"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		closedBy, ok := authorizeManager(ctx, c, request.ManagerEmail, request.ManagerPassword)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "closing the day requires manager authorization"})
+			return
+		}
+
+		var day models.BusinessDay
+		err := getBusinessDayCollection().FindOne(ctx, bson.M{"status": "OPEN"}).Decode(&day)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "no business day is open"})
+			return
+		}
+
+		var openOrders []models.Order
+		if err := findAll(ctx, getOrderCollection(), bson.M{"status": "OPEN"}, &openOrders); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for open orders"})
+			return
+		}
+
+		if len(openOrders) > 0 {
+			orderIds := make([]string, len(openOrders))
+			for i, order := range openOrders {
+				orderIds[i] = order.OrderID
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "orders are still open", "open_orders": orderIds})
+			return
+		}
+
+		var openDrawers []models.DrawerSession
+		if err := findAll(ctx, getDrawerSessionCollection(), bson.M{"status": "OPEN"}, &openDrawers); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for open drawers"})
+			return
+		}
+
+		if len(openDrawers) > 0 {
+			drawerIds := make([]string, len(openDrawers))
+			for i, drawer := range openDrawers {
+				drawerIds[i] = drawer.DrawerSessionID
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "cash drawers must be counted first", "open_drawers": drawerIds})
+			return
+		}
+
+		closedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		report, err := buildZReport(ctx, day, closedAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while building the Z report"})
+			return
+		}
+
+		seq, err := database.NextSequence(ctx, "zReport")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while numbering the Z report"})
+			return
+		}
+
+		closed := closeDay(day, report, seq, closedAt, closedBy)
+		result, err := getBusinessDayCollection().UpdateOne(ctx,
+			bson.M{"business_day_id": day.BusinessDayID, "status": "OPEN"},
+			bson.M{"$set": bson.M{
+				"status":    closed.Status,
+				"closed_at": closed.ClosedAt,
+				"closed_by": closed.ClosedBy,
+				"z_number":  closed.ZNumber,
+				"z_report":  closed.ZReport,
+			}},
+		)
+		if err != nil || result.MatchedCount == 0 {
+			if released, _ := database.ReleaseSequence(ctx, "zReport", seq); !released {
+				log.Printf("Z number %d was skipped: %v", seq, err)
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "business day was closed by someone else"})
+			return
+		}
+
+		c.JSON(http.StatusOK, closed)
+	}
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+func TestTotalZReport(t *testing.T) {
+	opened := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	closed := opened.Add(14 * time.Hour)
+	day := models.BusinessDay{Date: "2026-10-19", OpenedAt: opened, BusinessDayID: "day-1"}
+
+	cash, card := "CASH", "CARD"
+	money := func(amount float64) *float64 { return &amount }
+	quantity := func(n int) *int { return &n }
+
+	t.Run("no activity", func(t *testing.T) {
+		report := totalZReport(day, closed, zActivity{})
+
+		want := models.ZReport{
+			BusinessDate: "2026-10-19",
+			From:         opened,
+			To:           closed,
+			Discounts:    []models.DiscountLine{},
+			Taxes:        []models.TaxLine{},
+			Tenders:      []models.TenderTotal{},
+			Refunds:      []models.TenderTotal{},
+			Drawers:      []models.DrawerSession{},
+		}
+		if !reflect.DeepEqual(report, want) {
+			t.Errorf("report = %+v, want %+v", report, want)
+		}
+	})
+
+	t.Run("a day of trading", func(t *testing.T) {
+		report := totalZReport(day, closed, zActivity{
+			Invoices: []models.Invoice{
+				{
+					Covers: 2, SubTotal: 40, DiscountTotal: 4, ServiceCharge: 3.6, TaxTotal: 5.04, Total: 44.64,
+					Discounts: []models.DiscountLine{{DiscountID: "happy", Name: "Happy hour", Amount: 4}},
+					Taxes: []models.TaxLine{
+						{TaxCategoryID: "food", Name: "VAT", Rate: 0.14, NetAmount: 36, TaxAmount: 5.04},
+					},
+				},
+				{
+					Covers: 3, SubTotal: 20.1, DiscountTotal: 0.1, ServiceCharge: 0, TaxTotal: 2.2, Total: 22.2,
+					Discounts: []models.DiscountLine{
+						{DiscountID: "happy", Name: "Happy hour", Amount: 0.05},
+						{DiscountID: "staff", Name: "Staff", Amount: 0.05},
+					},
+					Taxes: []models.TaxLine{
+						{TaxCategoryID: "food", Name: "VAT", Rate: 0.14, NetAmount: 10, TaxAmount: 1.4},
+						{TaxCategoryID: "food", Name: "VAT", Rate: 0.08, NetAmount: 10, TaxAmount: 0.8},
+					},
+				},
+			},
+			Payments: []models.Payment{
+				{Method: &cash, Amount: money(30)},
+				{Method: &card, Amount: money(14.64)},
+				{Method: &cash, Amount: money(22.2)},
+				{Method: &cash},
+			},
+			Refunds: []models.Refund{
+				{Method: "CARD", Amount: money(4.64)},
+				{Method: "CARD"},
+			},
+			Voids: []models.OrderItem{
+				{Quantity: quantity(2), UnitPrice: money(3.35)},
+				{Quantity: quantity(1)},
+			},
+			Tips:    []models.Tip{{Amount: money(5)}, {Amount: money(0.1)}, {Amount: money(0.2)}},
+			Drawers: []models.DrawerSession{{DrawerSessionID: "drawer-1"}},
+		})
+
+		if report.InvoiceCount != 2 || report.Covers != 5 {
+			t.Errorf("invoices = %d and covers = %d, want 2 and 5", report.InvoiceCount, report.Covers)
+		}
+		totals := []struct {
+			name      string
+			got, want float64
+		}{
+			{"gross sales", report.GrossSales, 60.1},
+			{"discount total", report.DiscountTotal, 4.1},
+			{"service charge", report.ServiceCharge, 3.6},
+			{"tax total", report.TaxTotal, 7.24},
+			{"total", report.Total, 66.84},
+			{"refund total", report.RefundTotal, 4.64},
+			{"net total", report.NetTotal, 62.2},
+			{"void total", report.VoidTotal, 6.7},
+			{"tip total", report.TipTotal, 5.3},
+		}
+		for _, total := range totals {
+			if total.got != total.want {
+				t.Errorf("%s = %v, want %v", total.name, total.got, total.want)
+			}
+		}
+		if report.VoidCount != 2 {
+			t.Errorf("void count = %d, want 2", report.VoidCount)
+		}
+
+		discounts := []models.DiscountLine{
+			{DiscountID: "happy", Name: "Happy hour", Amount: 4.05},
+			{DiscountID: "staff", Name: "Staff", Amount: 0.05},
+		}
+		if !reflect.DeepEqual(report.Discounts, discounts) {
+			t.Errorf("discounts = %+v, want %+v", report.Discounts, discounts)
+		}
+
+		taxes := []models.TaxLine{
+			{TaxCategoryID: "food", Name: "VAT", Rate: 0.08, NetAmount: 10, TaxAmount: 0.8},
+			{TaxCategoryID: "food", Name: "VAT", Rate: 0.14, NetAmount: 46, TaxAmount: 6.44},
+		}
+		if !reflect.DeepEqual(report.Taxes, taxes) {
+			t.Errorf("taxes = %+v, want %+v", report.Taxes, taxes)
+		}
+
+		tenders := []models.TenderTotal{
+			{Method: "CARD", Count: 1, Amount: 14.64},
+			{Method: "CASH", Count: 2, Amount: 52.2},
+		}
+		if !reflect.DeepEqual(report.Tenders, tenders) {
+			t.Errorf("tenders = %+v, want %+v", report.Tenders, tenders)
+		}
+
+		refunds := []models.TenderTotal{{Method: "CARD", Count: 1, Amount: 4.64}}
+		if !reflect.DeepEqual(report.Refunds, refunds) {
+			t.Errorf("refunds = %+v, want %+v", report.Refunds, refunds)
+		}
+
+		if len(report.Drawers) != 1 || report.Drawers[0].DrawerSessionID != "drawer-1" {
+			t.Errorf("drawers = %+v, want drawer-1", report.Drawers)
+		}
+	})
+}
+
+func TestCloseDay(t *testing.T) {
+	opened := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	closed := opened.Add(14 * time.Hour)
+
+	var numbers []int64
+	for seq := int64(1); seq <= 3; seq++ {
+		day := models.BusinessDay{Status: "OPEN", OpenedAt: opened, BusinessDayID: "day"}
+		report := models.ZReport{Total: 10}
+
+		got := closeDay(day, report, seq, closed, "manager")
+		if got.Status != "CLOSED" || got.ClosedBy != "manager" || got.ClosedAt == nil || !got.ClosedAt.Equal(closed) {
+			t.Errorf("day = %+v, want closed by manager at %v", got, closed)
+		}
+		if got.ZNumber != int(seq) || got.ZReport == nil || got.ZReport.ZNumber != int(seq) {
+			t.Fatalf("Z numbers = %d on the day and %+v on the report, want %d", got.ZNumber, got.ZReport, seq)
+		}
+		if got.ZReport.Total != 10 {
+			t.Errorf("report total = %v, want 10", got.ZReport.Total)
+		}
+		if report.ZNumber != 0 {
+			t.Errorf("report passed in was numbered %d, want it left alone", report.ZNumber)
+		}
+		numbers = append(numbers, int64(got.ZNumber))
+	}
+
+	if missing := missingSequences(numbers, 3); len(missing) != 0 {
+		t.Errorf("Z numbers %v are missing %v", numbers, missing)
+	}
+}
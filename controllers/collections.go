@@ -37,3 +37,107 @@ func getInvoiceCollection() *mongo.Collection {
 func getNoteCollection() *mongo.Collection {
 	return database.Collections.Notes
 }
+
+func getDiscountCollection() *mongo.Collection {
+	return database.Collections.Discounts
+}
+
+func getOrderDiscountCollection() *mongo.Collection {
+	return database.Collections.OrderDiscounts
+}
+
+func getPriceRuleCollection() *mongo.Collection {
+	return database.Collections.PriceRules
+}
+
+func getTaxCategoryCollection() *mongo.Collection {
+	return database.Collections.TaxCategories
+}
+
+func getTipCollection() *mongo.Collection {
+	return database.Collections.Tips
+}
+
+func getCounterCollection() *mongo.Collection {
+	return database.Collections.Counters
+}
+
+func getPaymentCollection() *mongo.Collection {
+	return database.Collections.Payments
+}
+
+func getRefundCollection() *mongo.Collection {
+	return database.Collections.Refunds
+}
+
+func getCreditNoteCollection() *mongo.Collection {
+	return database.Collections.CreditNotes
+}
+
+func getPaymentIntentCollection() *mongo.Collection {
+	return database.Collections.PaymentIntents
+}
+
+func getPrinterCollection() *mongo.Collection {
+	return database.Collections.Printers
+}
+
+func getHouseAccountCollection() *mongo.Collection {
+	return database.Collections.HouseAccounts
+}
+
+func getBusinessDayCollection() *mongo.Collection {
+	return database.Collections.BusinessDays
+}
+
+func getDrawerSessionCollection() *mongo.Collection {
+	return database.Collections.DrawerSessions
+}
+
+func getAreaCollection() *mongo.Collection {
+	return database.Collections.Areas
+}
+
+func getSectionCollection() *mongo.Collection {
+	return database.Collections.Sections
+}
+
+func getReservationCollection() *mongo.Collection {
+	return database.Collections.Reservations
+}
+
+func getWaitlistCollection() *mongo.Collection {
+	return database.Collections.Waitlist
+}
+
+func getTableGroupCollection() *mongo.Collection {
+	return database.Collections.TableGroups
+}
+
+func getGuestOrderCollection() *mongo.Collection {
+	return database.Collections.GuestOrders
+}
+
+func getShiftTemplateCollection() *mongo.Collection {
+	return database.Collections.ShiftTemplates
+}
+
+func getShiftCollection() *mongo.Collection {
+	return database.Collections.Shifts
+}
+
+func getTimeEntryCollection() *mongo.Collection {
+	return database.Collections.TimeEntries
+}
+
+func getIngredientCollection() *mongo.Collection {
+	return database.Collections.Ingredients
+}
+
+func getRecipeCollection() *mongo.Collection {
+	return database.Collections.Recipes
+}
+
+func getStockMovementCollection() *mongo.Collection {
+	return database.Collections.StockMovements
+}
@@ -0,0 +1,400 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var discountValidate = validator.New()
+
+// GetDiscounts returns all discounts
+func GetDiscounts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var discounts []models.Discount
+		cursor, err := getDiscountCollection().Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing discounts"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &discounts); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, discounts)
+	}
+}
+
+// GetDiscountByID returns a single discount by ID
+func GetDiscountByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		discountId := c.Param("discount_id")
+		var discount models.Discount
+
+		err := getDiscountCollection().FindOne(ctx, bson.M{"discount_id": discountId}).Decode(&discount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the discount"})
+			return
+		}
+
+		c.JSON(http.StatusOK, discount)
+	}
+}
+
+// CreateDiscount creates a new discount or coupon code
+func CreateDiscount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var discount models.Discount
+		if err := c.BindJSON(&discount); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := discountValidate.Struct(discount)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if *discount.Scope == "ITEM" && discount.FoodID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "food_id is required for ITEM discounts"})
+			return
+		}
+
+		if *discount.Scope == "CATEGORY" && discount.Category == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "category is required for CATEGORY discounts"})
+			return
+		}
+
+		if *discount.Type == "BUY_X_GET_Y" && *discount.Scope == "ORDER" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "BUY_X_GET_Y discounts must target an item or category"})
+			return
+		}
+
+		if *discount.Type != "BUY_X_GET_Y" && discount.Value == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "value is required"})
+			return
+		}
+
+		if *discount.Type == "PERCENTAGE" && *discount.Value > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a percentage discount cannot be more than 100"})
+			return
+		}
+
+		if discount.FoodID != nil {
+			var food models.Food
+			err := getFoodCollection().FindOne(ctx, bson.M{"food_id": discount.FoodID}).Decode(&food)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+				return
+			}
+		}
+
+		// Coupon codes are matched case-insensitively and must be unique
+		if discount.CouponCode != nil {
+			code := strings.ToUpper(strings.TrimSpace(*discount.CouponCode))
+			discount.CouponCode = &code
+
+			count, err := getDiscountCollection().CountDocuments(ctx, bson.M{"coupon_code": code})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for coupon code"})
+				return
+			}
+
+			if count > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "coupon code already exists"})
+				return
+			}
+		}
+
+		if discount.Active == nil {
+			active := true
+			discount.Active = &active
+		}
+
+		discount.UsageCount = 0
+		discount.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		discount.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		discount.ID = primitive.NewObjectID()
+		discount.DiscountID = discount.ID.Hex()
+
+		result, insertErr := getDiscountCollection().InsertOne(ctx, discount)
+		if mongo.IsDuplicateKeyError(insertErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": "coupon code already exists"})
+			return
+		}
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "discount was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateDiscount updates an existing discount
+func UpdateDiscount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var discount models.Discount
+		discountId := c.Param("discount_id")
+
+		if err := c.BindJSON(&discount); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var current models.Discount
+		err := getDiscountCollection().FindOne(ctx, bson.M{"discount_id": discountId}).Decode(&current)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "discount was not found"})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if discount.Name != nil {
+			validationErr := discountValidate.StructPartial(discount, "Name")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "name", Value: discount.Name})
+		}
+
+		if discount.Value != nil {
+			validationErr := discountValidate.StructPartial(discount, "Value")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			if *current.Type == "PERCENTAGE" && *discount.Value > 100 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "a percentage discount cannot be more than 100"})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "value", Value: discount.Value})
+		}
+
+		if discount.UsageLimit != nil {
+			validationErr := discountValidate.StructPartial(discount, "UsageLimit")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "usage_limit", Value: discount.UsageLimit})
+		}
+
+		if discount.ValidFrom != nil {
+			updateObj = append(updateObj, bson.E{Key: "valid_from", Value: discount.ValidFrom})
+		}
+
+		if discount.ValidTo != nil {
+			updateObj = append(updateObj, bson.E{Key: "valid_to", Value: discount.ValidTo})
+		}
+
+		if discount.Active != nil {
+			updateObj = append(updateObj, bson.E{Key: "active", Value: discount.Active})
+		}
+
+		discount.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: discount.UpdatedAt})
+
+		filter := bson.M{"discount_id": discountId}
+
+		result, err := getDiscountCollection().UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "discount update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetOrderDiscounts returns the discounts applied to an order
+func GetOrderDiscounts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var orderDiscounts []models.OrderDiscount
+		cursor, err := getOrderDiscountCollection().Find(ctx, bson.M{"order_id": orderId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching order discounts"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &orderDiscounts); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, orderDiscounts)
+	}
+}
+
+// ApplyOrderDiscount applies a discount or coupon code to an order and
+// returns the updated order totals
+func ApplyOrderDiscount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var request struct {
+			DiscountID *string `json:"discount_id"`
+			CouponCode *string `json:"coupon_code"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if request.DiscountID == nil && request.CouponCode == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "discount_id or coupon_code is required"})
+			return
+		}
+
+		var order models.Order
+		err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		// The invoice is a snapshot of the order, so invoiced orders are final
+		if order.Status != nil && *order.Status != "OPEN" {
+			c.JSON(http.StatusConflict, gin.H{"error": "order is not open"})
+			return
+		}
+
+		if err := checkOrderOpen(ctx, order); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		count, err := getInvoiceCollection().CountDocuments(ctx, bson.M{"order_id": orderId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for invoice"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "order has already been invoiced"})
+			return
+		}
+
+		filter := bson.M{"discount_id": request.DiscountID}
+		if request.CouponCode != nil {
+			filter = bson.M{"coupon_code": strings.ToUpper(strings.TrimSpace(*request.CouponCode))}
+		}
+
+		var discount models.Discount
+		err = getDiscountCollection().FindOne(ctx, filter).Decode(&discount)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "discount was not found"})
+			return
+		}
+
+		// Discounts with a coupon code can only be applied by entering the code
+		if discount.CouponCode != nil && request.CouponCode == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "this discount requires a coupon code"})
+			return
+		}
+
+		if !helpers.DiscountIsActive(discount, time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "discount is not valid at this time"})
+			return
+		}
+
+		count, err = getOrderDiscountCollection().CountDocuments(ctx, bson.M{"order_id": orderId, "discount_id": discount.DiscountID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking order discounts"})
+			return
+		}
+
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "discount was already applied to this order"})
+			return
+		}
+
+		// Claim a use atomically so concurrent orders cannot exceed the limit
+		usageFilter := bson.M{
+			"discount_id": discount.DiscountID,
+			"$or": []bson.M{
+				{"usage_limit": nil},
+				{"$expr": bson.M{"$lt": []string{"$usage_count", "$usage_limit"}}},
+			},
+		}
+		usageResult, err := getDiscountCollection().UpdateOne(ctx, usageFilter, bson.M{"$inc": bson.M{"usage_count": 1}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while applying the discount"})
+			return
+		}
+
+		if usageResult.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "coupon usage limit has been reached"})
+			return
+		}
+
+		var orderDiscount models.OrderDiscount
+		orderDiscount.OrderID = orderId
+		orderDiscount.DiscountID = discount.DiscountID
+		orderDiscount.CouponCode = discount.CouponCode
+		orderDiscount.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		orderDiscount.ID = primitive.NewObjectID()
+		orderDiscount.OrderDiscountID = orderDiscount.ID.Hex()
+
+		_, insertErr := getOrderDiscountCollection().InsertOne(ctx, orderDiscount)
+		if insertErr != nil {
+			// Give the claimed use back
+			getDiscountCollection().UpdateOne(ctx, bson.M{"discount_id": discount.DiscountID}, bson.M{"$inc": bson.M{"usage_count": -1}})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "discount was not applied"})
+			return
+		}
+
+		totals, err := calculateOrderTotals(ctx, orderId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while calculating order totals"})
+			return
+		}
+
+		c.JSON(http.StatusOK, totals)
+	}
+}
+
+// GetAllDiscounts returns all discounts
+func GetAllDiscounts() gin.HandlerFunc {
+	return GetDiscounts()
+}
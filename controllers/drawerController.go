@@ -0,0 +1,346 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var drawerValidate = validator.New()
+
+var (
+	errDrawerClosed    = errors.New("cash drawer is not open")
+	errNoDrawerOpen    = errors.New("no cash drawer is open")
+	errDrawerAmbiguous = errors.New("several cash drawers are open; give drawer_session_id")
+)
+
+// cashDrawerFor returns the drawer a cash payment or refund goes through.
+// A requested drawer must be open; without one, the only open drawer is
+// used. Cash is never taken or paid out without a drawer to count it in.
+func cashDrawerFor(ctx context.Context, requested *string) (*string, error) {
+	if requested != nil {
+		count, err := getDrawerSessionCollection().CountDocuments(ctx, bson.M{"drawer_session_id": requested, "status": "OPEN"})
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, errDrawerClosed
+		}
+		return requested, nil
+	}
+
+	var drawers []models.DrawerSession
+	if err := findAll(ctx, getDrawerSessionCollection(), bson.M{"status": "OPEN"}, &drawers); err != nil {
+		return nil, err
+	}
+	switch len(drawers) {
+	case 0:
+		return nil, errNoDrawerOpen
+	case 1:
+		return &drawers[0].DrawerSessionID, nil
+	default:
+		return nil, errDrawerAmbiguous
+	}
+}
+
+// drawerCash returns the cash taken and refunded through a drawer
+func drawerCash(ctx context.Context, drawerSessionId string) (float64, float64, error) {
+	var payments []models.Payment
+	if err := findAll(ctx, getPaymentCollection(), bson.M{"drawer_session_id": drawerSessionId, "method": "CASH"}, &payments); err != nil {
+		return 0, 0, err
+	}
+
+	var sales float64
+	for _, payment := range payments {
+		if payment.Amount != nil {
+			sales += *payment.Amount
+		}
+	}
+
+	var refunds []models.Refund
+	if err := findAll(ctx, getRefundCollection(), bson.M{"drawer_session_id": drawerSessionId, "method": "CASH", "status": refundCounted}, &refunds); err != nil {
+		return 0, 0, err
+	}
+
+	var refunded float64
+	for _, refund := range refunds {
+		if refund.Amount != nil {
+			refunded += *refund.Amount
+		}
+	}
+
+	return helpers.RoundMoney(sales), helpers.RoundMoney(refunded), nil
+}
+
+// expectedCash works out what should be in a drawer: the float, plus cash
+// sales and cash paid in, less cash refunds and cash paid out
+func expectedCash(drawer models.DrawerSession, sales float64, refunds float64) float64 {
+	expected := *drawer.OpeningFloat + sales - refunds
+	for _, movement := range drawer.Movements {
+		if *movement.Type == "IN" {
+			expected += *movement.Amount
+		} else {
+			expected -= *movement.Amount
+		}
+	}
+	return helpers.RoundMoney(expected)
+}
+
+// countDrawer records the cash counted in a drawer against what should be
+// there. A positive variance means the drawer is over, a negative one that
+// it is short.
+func countDrawer(drawer models.DrawerSession, sales float64, refunds float64, counted float64) models.DrawerSession {
+	counted = helpers.RoundMoney(counted)
+
+	drawer.CashSales = sales
+	drawer.CashRefunds = refunds
+	drawer.Expected = expectedCash(drawer, sales, refunds)
+	drawer.Counted = &counted
+	drawer.Variance = helpers.RoundMoney(counted - drawer.Expected)
+	return drawer
+}
+
+// GetDrawerSessions returns all drawer sessions, optionally filtered by
+// status (?status=open)
+func GetDrawerSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if status := c.Query("status"); status != "" {
+			filter["status"] = strings.ToUpper(status)
+		}
+
+		var drawers []models.DrawerSession
+		cursor, err := getDrawerSessionCollection().Find(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing drawer sessions"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &drawers); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, drawers)
+	}
+}
+
+// GetDrawerSessionByID returns a single drawer session with its expected
+// cash so far
+func GetDrawerSessionByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		drawerSessionId := c.Param("drawer_session_id")
+		var drawer models.DrawerSession
+
+		err := getDrawerSessionCollection().FindOne(ctx, bson.M{"drawer_session_id": drawerSessionId}).Decode(&drawer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the drawer session"})
+			return
+		}
+
+		if drawer.Status == "OPEN" {
+			sales, refunds, err := drawerCash(ctx, drawer.DrawerSessionID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while totalling the drawer"})
+				return
+			}
+			drawer.CashSales = sales
+			drawer.CashRefunds = refunds
+			drawer.Expected = expectedCash(drawer, sales, refunds)
+		}
+
+		c.JSON(http.StatusOK, drawer)
+	}
+}
+
+// OpenDrawerSession opens a cash drawer with its starting float in the
+// current business day
+func OpenDrawerSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var drawer models.DrawerSession
+		if err := c.BindJSON(&drawer); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := drawerValidate.Struct(drawer)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		count, err := getDrawerSessionCollection().CountDocuments(ctx, bson.M{"name": drawer.Name, "status": "OPEN"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for drawer session"})
+			return
+		}
+
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "this drawer is already open"})
+			return
+		}
+
+		day, err := currentBusinessDay(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while opening the business day"})
+			return
+		}
+
+		drawer.BusinessDayID = day.BusinessDayID
+		drawer.Status = "OPEN"
+		drawer.Movements = []models.CashMovement{}
+		drawer.Counted = nil
+		drawer.OpenedBy = c.GetString("uid")
+		drawer.OpenedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		drawer.ID = primitive.NewObjectID()
+		drawer.DrawerSessionID = drawer.ID.Hex()
+
+		_, insertErr := getDrawerSessionCollection().InsertOne(ctx, drawer)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "drawer session was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, drawer)
+	}
+}
+
+// AddCashMovement records cash paid into or out of an open drawer
+func AddCashMovement() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		drawerSessionId := c.Param("drawer_session_id")
+
+		var movement models.CashMovement
+		if err := c.BindJSON(&movement); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := drawerValidate.Struct(movement)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		amount := helpers.RoundMoney(*movement.Amount)
+		movement.Amount = &amount
+		movement.By = c.GetString("uid")
+		movement.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		result, err := getDrawerSessionCollection().UpdateOne(ctx,
+			bson.M{"drawer_session_id": drawerSessionId, "status": "OPEN"},
+			bson.M{"$push": bson.M{"movements": movement}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cash movement was not recorded"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": errDrawerClosed.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, movement)
+	}
+}
+
+// CloseDrawerSession records the counted cash of a drawer and closes it,
+// returning the expected amount and the variance
+func CloseDrawerSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		drawerSessionId := c.Param("drawer_session_id")
+
+		var request struct {
+			Counted *float64 `json:"counted" validate:"required,gte=0"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := drawerValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var drawer models.DrawerSession
+		err := getDrawerSessionCollection().FindOne(ctx, bson.M{"drawer_session_id": drawerSessionId}).Decode(&drawer)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "drawer session was not found"})
+			return
+		}
+
+		if drawer.Status != "OPEN" {
+			c.JSON(http.StatusConflict, gin.H{"error": errDrawerClosed.Error()})
+			return
+		}
+
+		sales, refunds, err := drawerCash(ctx, drawer.DrawerSessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while totalling the drawer"})
+			return
+		}
+
+		closedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		drawer = countDrawer(drawer, sales, refunds, *request.Counted)
+		drawer.Status = "CLOSED"
+		drawer.ClosedBy = c.GetString("uid")
+		drawer.ClosedAt = &closedAt
+
+		// Guarding on the movement count catches a movement recorded
+		// while the drawer was being counted
+		result, err := getDrawerSessionCollection().UpdateOne(ctx,
+			bson.M{"drawer_session_id": drawerSessionId, "status": "OPEN", "movements": bson.M{"$size": len(drawer.Movements)}},
+			bson.M{"$set": bson.M{
+				"status":       drawer.Status,
+				"cash_sales":   drawer.CashSales,
+				"cash_refunds": drawer.CashRefunds,
+				"expected":     drawer.Expected,
+				"counted":      drawer.Counted,
+				"variance":     drawer.Variance,
+				"closed_by":    drawer.ClosedBy,
+				"closed_at":    drawer.ClosedAt,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "drawer session update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "drawer changed while it was counted, please retry"})
+			return
+		}
+
+		c.JSON(http.StatusOK, drawer)
+	}
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+func TestCountDrawer(t *testing.T) {
+	in, out := "IN", "OUT"
+	money := func(amount float64) *float64 { return &amount }
+
+	tests := []struct {
+		name      string
+		float     float64
+		movements []models.CashMovement
+		sales     float64
+		refunds   float64
+		counted   float64
+		expected  float64
+		variance  float64
+	}{
+		{"balanced", 100, nil, 250.5, 10, 340.5, 340.5, 0},
+		{"over", 100, nil, 50, 0, 155, 150, 5},
+		{"short", 100, nil, 50, 0, 149.99, 150, -0.01},
+		{
+			name:  "cash paid in and out",
+			float: 100,
+			movements: []models.CashMovement{
+				{Type: &in, Amount: money(20)},
+				{Type: &out, Amount: money(35.5)},
+			},
+			sales:    80,
+			refunds:  4.5,
+			counted:  160,
+			expected: 160,
+			variance: 0,
+		},
+		{"counted rounded to the cent", 0, nil, 0.1, 0, 0.104, 0.1, 0},
+		{"empty drawer", 50, nil, 0, 0, 0, 50, -50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drawer := models.DrawerSession{OpeningFloat: &tt.float, Movements: tt.movements}
+
+			got := countDrawer(drawer, tt.sales, tt.refunds, tt.counted)
+			if got.Expected != tt.expected {
+				t.Errorf("expected = %v, want %v", got.Expected, tt.expected)
+			}
+			if got.Variance != tt.variance {
+				t.Errorf("variance = %v, want %v", got.Variance, tt.variance)
+			}
+			if got.CashSales != tt.sales || got.CashRefunds != tt.refunds {
+				t.Errorf("cash sales and refunds = %v and %v, want %v and %v", got.CashSales, got.CashRefunds, tt.sales, tt.refunds)
+			}
+			if got.Counted == nil {
+				t.Fatal("counted was not recorded")
+			}
+			if want := helpers.RoundMoney(tt.counted); *got.Counted != want {
+				t.Errorf("counted = %v, want %v", *got.Counted, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errAreaNotFound     = errors.New("area was not found")
+	errSectionNotFound  = errors.New("section was not found")
+	errSectionArea      = errors.New("section belongs to another area")
+	errTableNumberTaken = errors.New("table number is already used in this area")
+)
+
+// placementStatus maps a table placement error to its HTTP status
+func placementStatus(err error) int {
+	switch {
+	case errors.Is(err, errAreaNotFound), errors.Is(err, errSectionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errSectionArea):
+		return http.StatusBadRequest
+	case errors.Is(err, errTableNumberTaken):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// checkTableArea checks that a table's area and section exist and that the
+// section is in the table's area. A table given only a section takes the
+// section's area.
+func checkTableArea(ctx context.Context, table *models.Table) error {
+	if table.SectionID != nil {
+		var section models.Section
+		err := getSectionCollection().FindOne(ctx, bson.M{"section_id": table.SectionID}).Decode(&section)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return errSectionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if table.AreaID == nil {
+			table.AreaID = section.AreaID
+		} else if section.AreaID == nil || *section.AreaID != *table.AreaID {
+			return errSectionArea
+		}
+	}
+
+	if table.AreaID != nil {
+		count, err := getAreaCollection().CountDocuments(ctx, bson.M{"area_id": table.AreaID})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return errAreaNotFound
+		}
+	}
+	return nil
+}
+
+// checkTablePlacement checks a table's area and section and that its
+// number is not used by another table in the same area
+func checkTablePlacement(ctx context.Context, table *models.Table) error {
+	if err := checkTableArea(ctx, table); err != nil {
+		return err
+	}
+
+	if table.TableNumber == nil {
+		return nil
+	}
+
+	count, err := getTableCollection().CountDocuments(ctx, bson.M{
+		"table_number": table.TableNumber,
+		"area_id":      table.AreaID,
+		"table_id":     bson.M{"$ne": table.TableID},
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errTableNumberTaken
+	}
+	return nil
+}
+
+// mergeTableLayout copies the layout fields set on update onto table. An
+// empty area_id or section_id takes the table out of its area or section.
+func mergeTableLayout(table *models.Table, update models.Table) {
+	if update.TableNumber != nil {
+		table.TableNumber = update.TableNumber
+	}
+	if update.NumberOfGuests != nil {
+		table.NumberOfGuests = update.NumberOfGuests
+	}
+	if update.AreaID != nil {
+		table.AreaID = update.AreaID
+		if *update.AreaID == "" {
+			table.AreaID = nil
+			table.SectionID = nil
+		}
+	}
+	if update.SectionID != nil {
+		table.SectionID = update.SectionID
+		if *update.SectionID == "" {
+			table.SectionID = nil
+		}
+	}
+	if update.Shape != nil {
+		table.Shape = update.Shape
+	}
+	if update.X != nil {
+		table.X = update.X
+	}
+	if update.Y != nil {
+		table.Y = update.Y
+	}
+	if update.Width != nil {
+		table.Width = update.Width
+	}
+	if update.Height != nil {
+		table.Height = update.Height
+	}
+	if update.Rotation != nil {
+		table.Rotation = update.Rotation
+	}
+}
+
+// layoutUpdate is the $set of a table's number, seats and layout fields
+func layoutUpdate(table models.Table) bson.M {
+	return bson.M{
+		"table_number":     table.TableNumber,
+		"number_of_guests": table.NumberOfGuests,
+		"area_id":          table.AreaID,
+		"section_id":       table.SectionID,
+		"shape":            table.Shape,
+		"x":                table.X,
+		"y":                table.Y,
+		"width":            table.Width,
+		"height":           table.Height,
+		"rotation":         table.Rotation,
+		"updated_at":       table.UpdatedAt,
+	}
+}
+
+// floorArea is one area of the floor plan with its sections and tables
+type floorArea struct {
+	models.Area `bson:",inline"`
+	Sections    []models.Section `json:"sections"`
+	Tables      []models.Table   `json:"tables"`
+}
+
+// loadFloorPlan returns every area with its sections and tables, and the
+// tables not placed in any area
+func loadFloorPlan(ctx context.Context) ([]floorArea, []models.Table, error) {
+	var areas []models.Area
+	cursor, err := getAreaCollection().Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "sort_order", Value: 1}, {Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &areas); err != nil {
+		return nil, nil, err
+	}
+
+	var sections []models.Section
+	if err := findAll(ctx, getSectionCollection(), bson.M{}, &sections); err != nil {
+		return nil, nil, err
+	}
+
+	var tables []models.Table
+	if err := findAll(ctx, getTableCollection(), bson.M{}, &tables); err != nil {
+		return nil, nil, err
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		if tables[i].TableNumber == nil || tables[j].TableNumber == nil {
+			return tables[j].TableNumber == nil && tables[i].TableNumber != nil
+		}
+		return *tables[i].TableNumber < *tables[j].TableNumber
+	})
+
+	plan := make([]floorArea, len(areas))
+	index := map[string]int{}
+	for i, area := range areas {
+		plan[i] = floorArea{Area: area, Sections: []models.Section{}, Tables: []models.Table{}}
+		index[area.AreaID] = i
+	}
+
+	for _, section := range sections {
+		if section.AreaID == nil {
+			continue
+		}
+		if i, ok := index[*section.AreaID]; ok {
+			plan[i].Sections = append(plan[i].Sections, section)
+		}
+	}
+
+	unplaced := []models.Table{}
+	for _, table := range tables {
+		if table.AreaID != nil {
+			if i, ok := index[*table.AreaID]; ok {
+				plan[i].Tables = append(plan[i].Tables, table)
+				continue
+			}
+		}
+		unplaced = append(unplaced, table)
+	}
+
+	return plan, unplaced, nil
+}
+
+// GetFloorPlan returns the full floor plan: areas with their sections and
+// tables, and the tables not yet placed in an area
+func GetFloorPlan() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		plan, unplaced, err := loadFloorPlan(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while loading the floor plan"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"areas":    plan,
+			"unplaced": unplaced,
+		})
+	}
+}
+
+// SaveFloorPlan saves the layout of many tables at once: area, section,
+// number, seats, shape, position and size. Every table is checked before
+// any is saved, and table numbers must stay unique within each area.
+func SaveFloorPlan() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Tables []models.Table `json:"tables" validate:"required,min=1"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := tableValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var tables []models.Table
+		if err := findAll(ctx, getTableCollection(), bson.M{}, &tables); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing tables"})
+			return
+		}
+
+		current := map[string]*models.Table{}
+		for i := range tables {
+			current[tables[i].TableID] = &tables[i]
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		changed := map[string]bool{}
+		for _, update := range request.Tables {
+			table, ok := current[update.TableID]
+			if !ok {
+				c.JSON(http.StatusNotFound, gin.H{"error": "table was not found", "table_id": update.TableID})
+				return
+			}
+
+			mergeTableLayout(table, update)
+			table.UpdatedAt = updatedAt
+
+			if validationErr := tableValidate.Struct(*table); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error(), "table_id": table.TableID})
+				return
+			}
+
+			if err := checkTableArea(ctx, table); err != nil {
+				c.JSON(placementStatus(err), gin.H{"error": err.Error(), "table_id": table.TableID})
+				return
+			}
+			changed[table.TableID] = true
+		}
+
+		// Numbers are checked against the whole floor as it will be saved
+		numbers := map[string]string{}
+		for _, table := range tables {
+			if table.TableNumber == nil {
+				continue
+			}
+			area := ""
+			if table.AreaID != nil {
+				area = *table.AreaID
+			}
+			key := fmt.Sprintf("%s/%d", area, *table.TableNumber)
+			if other, ok := numbers[key]; ok {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":     errTableNumberTaken.Error(),
+					"table_ids": []string{other, table.TableID},
+				})
+				return
+			}
+			numbers[key] = table.TableID
+		}
+
+		var writes []mongo.WriteModel
+		for tableId := range changed {
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"table_id": tableId}).
+				SetUpdate(bson.M{"$set": layoutUpdate(*current[tableId])}))
+		}
+
+		if _, err := getTableCollection().BulkWrite(ctx, writes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "floor plan was not saved"})
+			return
+		}
+
+		plan, unplaced, err := loadFloorPlan(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while loading the floor plan"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"areas":    plan,
+			"unplaced": unplaced,
+		})
+	}
+}
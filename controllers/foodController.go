@@ -18,7 +18,8 @@ import (
 
 var foodValidate = validator.New()
 
-// GetFoods returns all foods with pagination
+// GetFoods returns all foods with pagination, optionally only those that
+// can be ordered (?available=true) or are sold out (?available=false)
 func GetFoods() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -38,7 +39,14 @@ func GetFoods() gin.HandlerFunc {
 		startIndex := (page - 1) * recordPerPage
 
 		// Aggregation pipeline
-		matchStage := bson.D{{Key: "$match", Value: bson.D{{}}}}
+		match := bson.D{}
+		switch c.Query("available") {
+		case "true":
+			match = bson.D{{Key: "available", Value: bson.M{"$ne": false}}}
+		case "false":
+			match = bson.D{{Key: "available", Value: false}}
+		}
+		matchStage := bson.D{{Key: "$match", Value: match}}
 		groupStage := bson.D{{Key: "$group", Value: bson.D{
 			{Key: "_id", Value: nil},
 			{Key: "total_count", Value: bson.D{{Key: "$sum", Value: 1}}},
@@ -120,6 +128,21 @@ func CreateFood() gin.HandlerFunc {
 			return
 		}
 
+		if food.TaxCategoryID != nil {
+			var taxCategory models.TaxCategory
+			err := getTaxCategoryCollection().FindOne(ctx, bson.M{"tax_category_id": food.TaxCategoryID}).Decode(&taxCategory)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "tax category was not found"})
+				return
+			}
+		}
+
+		// A food starts on sale unless it has no portions
+		if food.Available == nil {
+			available := food.RemainingCount == nil || *food.RemainingCount > 0
+			food.Available = &available
+		}
+
 		food.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.ID = primitive.NewObjectID()
@@ -177,6 +200,29 @@ func UpdateFood() gin.HandlerFunc {
 			updateObj = append(updateObj, bson.E{Key: "menu_id", Value: food.MenuID})
 		}
 
+		if food.TaxCategoryID != nil {
+			var taxCategory models.TaxCategory
+			err := getTaxCategoryCollection().FindOne(ctx, bson.M{"tax_category_id": food.TaxCategoryID}).Decode(&taxCategory)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "tax category was not found"})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "tax_category_id", Value: food.TaxCategoryID})
+		}
+
+		if food.Station != nil {
+			updateObj = append(updateObj, bson.E{Key: "station", Value: food.Station})
+		}
+
+		if food.Cost != nil {
+			validationErr := foodValidate.StructPartial(food, "Cost")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "cost", Value: food.Cost})
+		}
+
 		food.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: food.UpdatedAt})
 
@@ -0,0 +1,657 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	qrcode "github.com/skip2/go-qrcode"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var guestOrderValidate = validator.New()
+
+// maxPendingGuestOrders is how many guest orders a table may have waiting
+// for approval at once
+const maxPendingGuestOrders = 3
+
+// guestOrderingEnabled reports whether table QR codes can be signed and
+// checked, answering 503 when GUEST_TOKEN_SECRET is not set
+func guestOrderingEnabled(c *gin.Context) bool {
+	if helpers.GuestTokenSecret() == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "guest ordering is not set up"})
+		return false
+	}
+	return true
+}
+
+// guestTable returns the table of the QR code token in ?token=. Codes of
+// a table whose QR version was rotated are refused.
+func guestTable(ctx context.Context, c *gin.Context) (models.Table, bool) {
+	var table models.Table
+
+	if !guestOrderingEnabled(c) {
+		return table, false
+	}
+
+	claims, err := helpers.ValidateTableToken(c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "table code is invalid"})
+		return table, false
+	}
+
+	err = getTableCollection().FindOne(ctx, bson.M{"table_id": claims.TableID}).Decode(&table)
+	if err != nil || !claims.Matches(table.TableID, table.QRVersion) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "table code is no longer valid, please ask a server"})
+		return table, false
+	}
+	return table, true
+}
+
+// menuActive reports whether a menu is being served at a time
+func menuActive(menu models.Menu, at time.Time) bool {
+	if menu.StartDate != nil && at.Before(*menu.StartDate) {
+		return false
+	}
+	if menu.EndDate != nil && at.After(*menu.EndDate) {
+		return false
+	}
+	return true
+}
+
+// activeMenuIds returns the IDs of the menus being served now
+func activeMenuIds(ctx context.Context, now time.Time) (map[string]models.Menu, error) {
+	var menus []models.Menu
+	if err := findAll(ctx, getMenuCollection(), bson.M{}, &menus); err != nil {
+		return nil, err
+	}
+
+	active := map[string]models.Menu{}
+	for _, menu := range menus {
+		if menuActive(menu, now) {
+			active[menu.MenuID] = menu
+		}
+	}
+	return active, nil
+}
+
+// GetTableQRCode returns a PNG QR code for a table that opens the guest
+// menu with the table's token. ?size= sets the width in pixels.
+func GetTableQRCode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !guestOrderingEnabled(c) {
+			return
+		}
+
+		tableId := c.Param("table_id")
+
+		size := 256
+		if v := c.Query("size"); v != "" {
+			var err error
+			size, err = strconv.Atoi(v)
+			if err != nil || size < 128 || size > 1024 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "size must be between 128 and 1024"})
+				return
+			}
+		}
+
+		var table models.Table
+		err := getTableCollection().FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+
+		token, err := helpers.GenerateTableToken(table.TableID, table.QRVersion)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "table code was not signed"})
+			return
+		}
+
+		link, err := url.Parse(helpers.GuestOrderURL())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "GUEST_ORDER_URL is not a valid address"})
+			return
+		}
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+
+		png, err := qrcode.Encode(link.String(), qrcode.Medium, size)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "QR code was not generated"})
+			return
+		}
+
+		name := table.TableID
+		if table.TableNumber != nil {
+			name = strconv.Itoa(*table.TableNumber)
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"table-%s.png\"", name))
+		c.Data(http.StatusOK, "image/png", png)
+	}
+}
+
+// RotateTableQRCode retires a table's printed QR codes, for example when
+// one has been taken away. New codes must be printed afterwards.
+func RotateTableQRCode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tableId := c.Param("table_id")
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		var table models.Table
+		err := getTableCollection().FindOneAndUpdate(ctx,
+			bson.M{"table_id": tableId},
+			bson.M{"$inc": bson.M{"qr_version": 1}, "$set": bson.M{"updated_at": updatedAt}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&table)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"table_id": table.TableID, "qr_version": table.QRVersion})
+	}
+}
+
+// guestFood is a food as shown to guests
+type guestFood struct {
+	FoodID    string  `json:"food_id"`
+	Name      *string `json:"name"`
+	Price     float64 `json:"price"`
+	FoodImage *string `json:"food_image"`
+}
+
+// guestMenu is a menu as shown to guests
+type guestMenu struct {
+	MenuID   string      `json:"menu_id"`
+	Name     string      `json:"name"`
+	Category string      `json:"category"`
+	Foods    []guestFood `json:"foods"`
+}
+
+// GetGuestMenu returns the menus being served now with their current
+// prices, leaving out sold out foods, for the guest at the table of the QR
+// code token
+func GetGuestMenu() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		table, ok := guestTable(ctx, c)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		active, err := activeMenuIds(ctx, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing menus"})
+			return
+		}
+
+		menuIds := make([]string, 0, len(active))
+		for menuId := range active {
+			menuIds = append(menuIds, menuId)
+		}
+
+		var foods []models.Food
+		err = findAll(ctx, getFoodCollection(), bson.M{"menu_id": bson.M{"$in": menuIds}, "available": bson.M{"$ne": false}}, &foods)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing foods"})
+			return
+		}
+
+		byMenu := map[string][]guestFood{}
+		for _, food := range foods {
+			price, _, err := currentFoodPrice(ctx, food, now)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while pricing the menu"})
+				return
+			}
+			byMenu[*food.MenuID] = append(byMenu[*food.MenuID], guestFood{
+				FoodID:    food.FoodID,
+				Name:      food.Name,
+				Price:     price,
+				FoodImage: food.FoodImage,
+			})
+		}
+
+		menus := []guestMenu{}
+		for menuId, menu := range active {
+			if len(byMenu[menuId]) == 0 {
+				continue
+			}
+			menus = append(menus, guestMenu{
+				MenuID:   menuId,
+				Name:     menu.Name,
+				Category: menu.Category,
+				Foods:    byMenu[menuId],
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"table_number": table.TableNumber,
+			"menus":        menus,
+		})
+	}
+}
+
+// CreateGuestOrder places a guest's order from the table of the QR code
+// token. It waits for staff approval before it reaches the kitchen.
+func CreateGuestOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		table, ok := guestTable(ctx, c)
+		if !ok {
+			return
+		}
+
+		var guestOrder models.GuestOrder
+		if err := c.BindJSON(&guestOrder); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := guestOrderValidate.Struct(guestOrder)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		count, err := getGuestOrderCollection().CountDocuments(ctx, bson.M{"table_id": table.TableID, "status": "PENDING"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for guest orders"})
+			return
+		}
+
+		if count >= maxPendingGuestOrders {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "your earlier orders are still waiting for a server"})
+			return
+		}
+
+		now := time.Now()
+		active, err := activeMenuIds(ctx, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing menus"})
+			return
+		}
+
+		// Guests get the name and price they saw on the menu
+		for i, item := range guestOrder.Items {
+			var food models.Food
+			err := getFoodCollection().FindOne(ctx, bson.M{"food_id": item.FoodID}).Decode(&food)
+			if err != nil || food.MenuID == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "food is not on the menu", "food_id": item.FoodID})
+				return
+			}
+			if _, ok := active[*food.MenuID]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "food is not on the menu", "food_id": item.FoodID})
+				return
+			}
+			if !foodAvailable(food) {
+				c.JSON(http.StatusConflict, gin.H{"error": errFoodSoldOut.Error(), "food_id": item.FoodID})
+				return
+			}
+
+			price, _, err := currentFoodPrice(ctx, food, now)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while pricing the food item"})
+				return
+			}
+			guestOrder.Items[i].Name = food.Name
+			guestOrder.Items[i].UnitPrice = price
+		}
+
+		guestOrder.TableID = table.TableID
+		guestOrder.TableNumber = table.TableNumber
+		guestOrder.Status = "PENDING"
+		guestOrder.OrderID = nil
+		guestOrder.ReviewedBy = ""
+		guestOrder.ReviewedAt = nil
+		guestOrder.RejectReason = nil
+		guestOrder.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		guestOrder.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		guestOrder.ID = primitive.NewObjectID()
+		guestOrder.GuestOrderID = guestOrder.ID.Hex()
+
+		_, insertErr := getGuestOrderCollection().InsertOne(ctx, guestOrder)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order was not placed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, guestOrder)
+	}
+}
+
+// GetGuestOrders returns the guest orders waiting for approval, oldest
+// first, or those of another status (?status=APPROVED|REJECTED)
+func GetGuestOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		status := c.DefaultQuery("status", "PENDING")
+		opt := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+		var guestOrders []models.GuestOrder
+		cursor, err := getGuestOrderCollection().Find(ctx, bson.M{"status": status}, opt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing guest orders"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &guestOrders); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, guestOrders)
+	}
+}
+
+// tableOpenOrder returns the open dine-in check of the party at a table,
+// including a check on the table's group
+func tableOpenOrder(ctx context.Context, table models.Table) (models.Order, error) {
+	filter := bson.M{"table_id": table.TableID, "order_type": "DINE_IN", "status": "OPEN"}
+	if table.TableGroupID != nil {
+		filter = bson.M{
+			"$or":        bson.A{bson.M{"table_id": table.TableID}, bson.M{"table_group_id": table.TableGroupID}},
+			"order_type": "DINE_IN",
+			"status":     "OPEN",
+		}
+	}
+
+	var order models.Order
+	err := getOrderCollection().FindOne(ctx, filter,
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})).Decode(&order)
+	return order, err
+}
+
+// ApproveGuestOrder sends a guest order to the kitchen: its items are added
+// to the open check at the table, or a new check is opened for the party
+func ApproveGuestOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		guestOrderId := c.Param("guest_order_id")
+		reviewedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		// Claiming the guest order first keeps it from being approved twice
+		var guestOrder models.GuestOrder
+		err := getGuestOrderCollection().FindOneAndUpdate(ctx,
+			bson.M{"guest_order_id": guestOrderId, "status": "PENDING"},
+			bson.M{"$set": bson.M{
+				"status":      "APPROVED",
+				"reviewed_by": c.GetString("uid"),
+				"reviewed_at": reviewedAt,
+				"updated_at":  reviewedAt,
+			}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&guestOrder)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "guest order is not waiting for approval"})
+			return
+		}
+
+		unclaim := func() {
+			getGuestOrderCollection().UpdateOne(ctx,
+				bson.M{"guest_order_id": guestOrderId},
+				bson.M{"$set": bson.M{"status": "PENDING", "order_id": nil, "reviewed_by": "", "reviewed_at": nil}},
+			)
+		}
+
+		var table models.Table
+		err = getTableCollection().FindOne(ctx, bson.M{"table_id": guestOrder.TableID}).Decode(&table)
+		if err != nil {
+			unclaim()
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+
+		// Nothing reaches the kitchen unless every item can be made
+		if err := checkGuestItems(ctx, guestOrder.Items); err != nil {
+			unclaim()
+			c.JSON(orderItemStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// A check opened here is voided again if the approval fails, and
+		// the tables it seated are put back as they were
+		opened := false
+		var seatable []models.Table
+		discard := func(order models.Order) {
+			if opened {
+				discardGuestCheck(ctx, order, seatable, c.GetString("uid"))
+			}
+		}
+
+		order, err := tableOpenOrder(ctx, table)
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			orderType := "DINE_IN"
+			order = models.Order{
+				TableID:        &table.TableID,
+				OrderType:      &orderType,
+				NumberOfGuests: guestOrder.NumberOfGuests,
+				TableGroupID:   table.TableGroupID,
+				CreatedBy:      c.GetString("uid"),
+			}
+			order.OrderDate = reviewedAt
+
+			for _, table := range orderTables(ctx, order) {
+				if status := tableStatus(table); status == "AVAILABLE" || status == "RESERVED" {
+					seatable = append(seatable, table)
+				}
+			}
+
+			if _, err := openOrder(ctx, &order, table); err != nil {
+				unclaim()
+				if errors.Is(err, errTableDirty) || errors.Is(err, errTableChanged) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			opened = true
+		case err != nil:
+			unclaim()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the order"})
+			return
+		default:
+			if err := checkOrderOpen(ctx, order); err != nil {
+				unclaim()
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		guestOrder.OrderID = &order.OrderID
+		result, err := getGuestOrderCollection().UpdateOne(ctx,
+			bson.M{"guest_order_id": guestOrderId, "status": "APPROVED"},
+			bson.M{"$set": bson.M{"order_id": order.OrderID}},
+		)
+		if err != nil || result.MatchedCount == 0 {
+			discard(order)
+			unclaim()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "guest order update failed"})
+			return
+		}
+
+		// Portions can still run out between the check and the claim; the
+		// guest order is then taken back whole. Guests pay the price they
+		// were shown, even if a price rule has ended since.
+		var added []models.OrderItem
+		for _, item := range guestOrder.Items {
+			unitPrice := item.UnitPrice
+			orderItem := models.OrderItem{FoodID: item.FoodID, Quantity: item.Quantity, UnitPrice: &unitPrice}
+			if _, err := addOrderItem(ctx, order, &orderItem); err != nil {
+				undoGuestItems(ctx, added, c.GetString("uid"))
+				discard(order)
+				unclaim()
+				c.JSON(orderItemStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			added = append(added, orderItem)
+		}
+
+		c.JSON(http.StatusOK, guestOrder)
+	}
+}
+
+// checkGuestItems makes sure every food of a guest order can still be
+// ordered in the quantity asked for, before anything is added to a check
+func checkGuestItems(ctx context.Context, items []models.GuestOrderItem) error {
+	quantities := map[string]int{}
+	for _, item := range items {
+		quantities[*item.FoodID] += *item.Quantity
+	}
+
+	for foodId, quantity := range quantities {
+		var food models.Food
+		if err := getFoodCollection().FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food); err != nil {
+			return errors.New("food item was not found")
+		}
+		if err := checkClaim(food, quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undoGuestItems voids the items already added for a guest order that
+// could not be approved in full, giving back their stock and portions
+func undoGuestItems(ctx context.Context, items []models.OrderItem, by string) {
+	voidedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	reason := "guest order could not be approved"
+
+	for _, item := range items {
+		result, err := getOrderItemCollection().UpdateOne(ctx,
+			bson.M{"order_item_id": item.OrderItemID, "voided": bson.M{"$ne": true}},
+			bson.M{"$set": bson.M{
+				"voided":      true,
+				"void_reason": reason,
+				"voided_by":   by,
+				"voided_at":   voidedAt,
+				"updated_at":  voidedAt,
+			}},
+		)
+		if err != nil {
+			log.Println("guest order item was not voided:", err)
+			continue
+		}
+		if result.MatchedCount == 0 {
+			continue
+		}
+
+		if err := restoreStock(ctx, item, "voided: "+reason, by); err != nil {
+			log.Println("stock was not restored:", err)
+		}
+		releasePortions(ctx, *item.FoodID, *item.Quantity)
+	}
+}
+
+// discardGuestCheck voids a check opened for a guest order that could not
+// be approved, so no empty check is left on the table, and puts the tables
+// it seated back to the status they had
+func discardGuestCheck(ctx context.Context, order models.Order, tables []models.Table, by string) {
+	voidedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	_, err := getOrderCollection().UpdateOne(ctx,
+		bson.M{"order_id": order.OrderID, "status": "OPEN"},
+		bson.M{"$set": bson.M{
+			"status":      "VOID",
+			"void_reason": "guest order could not be approved",
+			"voided_by":   by,
+			"voided_at":   voidedAt,
+			"updated_at":  voidedAt,
+		}},
+	)
+	if err != nil {
+		log.Println("guest order check was not voided:", err)
+		return
+	}
+
+	for _, table := range tables {
+		var current models.Table
+		if err := getTableCollection().FindOne(ctx, bson.M{"table_id": table.TableID}).Decode(&current); err != nil {
+			continue
+		}
+		if status := tableStatus(current); status != "SEATED" && status != "ORDERING" {
+			continue
+		}
+		if err := setTableStatus(ctx, current, tableStatus(table)); err != nil {
+			log.Println("table was not put back:", err)
+		}
+	}
+}
+
+// RejectGuestOrder turns down a guest order, with an optional reason
+func RejectGuestOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		guestOrderId := c.Param("guest_order_id")
+
+		var request struct {
+			Reason *string `json:"reason" validate:"omitempty,max=300"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := guestOrderValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		reviewedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		result, err := getGuestOrderCollection().UpdateOne(ctx,
+			bson.M{"guest_order_id": guestOrderId, "status": "PENDING"},
+			bson.M{"$set": bson.M{
+				"status":        "REJECTED",
+				"reject_reason": request.Reason,
+				"reviewed_by":   c.GetString("uid"),
+				"reviewed_at":   reviewedAt,
+				"updated_at":    reviewedAt,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "guest order update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "guest order is not waiting for approval"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var houseAccountValidate = validator.New()
+
+// accountBalance returns what a house account owes on unpaid invoices
+func accountBalance(ctx context.Context, accountId string) (float64, error) {
+	cursor, err := getInvoiceCollection().Find(ctx, bson.M{"account_id": accountId, "payment_status": bson.M{"$in": unpaidStatuses}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var invoices []models.Invoice
+	if err = cursor.All(ctx, &invoices); err != nil {
+		return 0, err
+	}
+
+	var owed float64
+	for _, invoice := range invoices {
+		owed += balanceDue(invoice)
+	}
+	return helpers.RoundMoney(owed), nil
+}
+
+// GetHouseAccounts returns all house accounts
+func GetHouseAccounts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var accounts []models.HouseAccount
+		cursor, err := getHouseAccountCollection().Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing house accounts"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &accounts); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, accounts)
+	}
+}
+
+// GetHouseAccountByID returns a single house account by ID
+func GetHouseAccountByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		accountId := c.Param("account_id")
+		var account models.HouseAccount
+
+		err := getHouseAccountCollection().FindOne(ctx, bson.M{"account_id": accountId}).Decode(&account)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the house account"})
+			return
+		}
+
+		c.JSON(http.StatusOK, account)
+	}
+}
+
+// CreateHouseAccount creates a new house account
+func CreateHouseAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var account models.HouseAccount
+		if err := c.BindJSON(&account); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := houseAccountValidate.Struct(account)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		account.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		account.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		account.ID = primitive.NewObjectID()
+		account.AccountID = account.ID.Hex()
+
+		result, insertErr := getHouseAccountCollection().InsertOne(ctx, account)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "house account was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateHouseAccount updates an existing house account
+func UpdateHouseAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var account models.HouseAccount
+		accountId := c.Param("account_id")
+
+		if err := c.BindJSON(&account); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := houseAccountValidate.StructPartial(account, "Email", "CreditLimit")
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if account.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: account.Name})
+		}
+
+		if account.Email != nil {
+			updateObj = append(updateObj, bson.E{Key: "email", Value: account.Email})
+		}
+
+		if account.Phone != nil {
+			updateObj = append(updateObj, bson.E{Key: "phone", Value: account.Phone})
+		}
+
+		if account.CreditLimit != nil {
+			updateObj = append(updateObj, bson.E{Key: "credit_limit", Value: account.CreditLimit})
+		}
+
+		account.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: account.UpdatedAt})
+
+		filter := bson.M{"account_id": accountId}
+
+		result, err := getHouseAccountCollection().UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "house account update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetHouseAccountInvoices returns the invoices charged to a house account
+func GetHouseAccountInvoices() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		accountId := c.Param("account_id")
+
+		var invoices []models.Invoice
+		cursor, err := getInvoiceCollection().Find(ctx, bson.M{"account_id": accountId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing invoices"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &invoices); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, invoices)
+	}
+}
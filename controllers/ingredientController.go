@@ -0,0 +1,371 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/events"
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ingredientValidate = validator.New()
+
+var errIngredientNotFound = errors.New("ingredient was not found")
+
+// moveStock changes an ingredient's stock by the movement's quantity and
+// records the movement in the ledger with the stock it left. Stock may go
+// below zero; crossing the low-stock threshold is logged and broadcast, and
+// running out sells out the foods that use the ingredient.
+func moveStock(ctx context.Context, movement *models.StockMovement) error {
+	change := helpers.RoundQuantity(*movement.Quantity)
+	movement.Quantity = &change
+
+	var ingredient models.Ingredient
+	err := getIngredientCollection().FindOneAndUpdate(ctx,
+		bson.M{"ingredient_id": movement.IngredientID},
+		bson.M{"$inc": bson.M{"stock": change}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&ingredient)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return errIngredientNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	movement.StockAfter = helpers.RoundQuantity(ingredient.Stock)
+	movement.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	movement.ID = primitive.NewObjectID()
+	movement.StockMovementID = movement.ID.Hex()
+
+	if _, err := getStockMovementCollection().InsertOne(ctx, movement); err != nil {
+		return err
+	}
+
+	before := movement.StockAfter - change
+	if threshold := ingredient.LowStockThreshold; threshold != nil {
+		if movement.StockAfter <= *threshold && before > *threshold {
+			log.Printf("low stock: %s is at %v %s", *ingredient.Name, movement.StockAfter, *ingredient.Unit)
+			events.Publish("ingredient.low_stock", ingredient)
+		}
+	}
+
+	// Running out sells out every food that needs the ingredient, and
+	// restocking brings them back
+	if movement.StockAfter <= 0 && before > 0 {
+		soldOutByIngredient(ctx, ingredient)
+	}
+	if movement.StockAfter > 0 && before <= 0 {
+		backInStock(ctx, ingredient)
+	}
+	return nil
+}
+
+// GetIngredients returns all ingredients by name, or only those at or below
+// their low-stock threshold (?low_stock=true)
+func GetIngredients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if c.Query("low_stock") == "true" {
+			filter = bson.M{
+				"low_stock_threshold": bson.M{"$ne": nil},
+				"$expr":               bson.M{"$lte": bson.A{"$stock", "$low_stock_threshold"}},
+			}
+		}
+
+		var ingredients []models.Ingredient
+		cursor, err := getIngredientCollection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing ingredients"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &ingredients); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, ingredients)
+	}
+}
+
+// GetIngredient returns a single ingredient
+func GetIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		ingredientId := c.Param("ingredient_id")
+
+		var ingredient models.Ingredient
+		err := getIngredientCollection().FindOne(ctx, bson.M{"ingredient_id": ingredientId}).Decode(&ingredient)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ingredient was not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, ingredient)
+	}
+}
+
+// CreateIngredient creates a new ingredient. Any opening stock is recorded
+// as an adjustment in the stock ledger.
+func CreateIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var ingredient models.Ingredient
+		if err := c.BindJSON(&ingredient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := ingredientValidate.Struct(ingredient)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if ingredient.Stock < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "opening stock cannot be negative"})
+			return
+		}
+
+		count, err := getIngredientCollection().CountDocuments(ctx, bson.M{"name": ingredient.Name})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for the ingredient"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "an ingredient with this name already exists"})
+			return
+		}
+
+		openingStock := ingredient.Stock
+		ingredient.Stock = 0
+		ingredient.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		ingredient.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		ingredient.ID = primitive.NewObjectID()
+		ingredient.IngredientID = ingredient.ID.Hex()
+
+		_, insertErr := getIngredientCollection().InsertOne(ctx, ingredient)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ingredient was not created"})
+			return
+		}
+
+		if openingStock > 0 {
+			movementType, reason := "ADJUSTMENT", "opening stock"
+			movement := models.StockMovement{
+				IngredientID: ingredient.IngredientID,
+				Type:         &movementType,
+				Quantity:     &openingStock,
+				Reason:       &reason,
+				By:           c.GetString("uid"),
+			}
+			if err := moveStock(ctx, &movement); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "opening stock was not recorded"})
+				return
+			}
+			ingredient.Stock = movement.StockAfter
+		}
+
+		c.JSON(http.StatusOK, ingredient)
+	}
+}
+
+// UpdateIngredient updates an ingredient's name, low-stock threshold and
+// unit cost. Its unit is fixed because recipes are written in it, and its
+// stock only changes through stock movements.
+func UpdateIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		ingredientId := c.Param("ingredient_id")
+
+		var update models.Ingredient
+		if err := c.BindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var ingredient models.Ingredient
+		err := getIngredientCollection().FindOne(ctx, bson.M{"ingredient_id": ingredientId}).Decode(&ingredient)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ingredient was not found"})
+			return
+		}
+
+		if update.Unit != nil && *update.Unit != *ingredient.Unit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "an ingredient's unit cannot be changed"})
+			return
+		}
+
+		if update.Name != nil {
+			ingredient.Name = update.Name
+		}
+		if update.LowStockThreshold != nil {
+			ingredient.LowStockThreshold = update.LowStockThreshold
+		}
+		if update.UnitCost != nil {
+			ingredient.UnitCost = update.UnitCost
+		}
+
+		validationErr := ingredientValidate.Struct(ingredient)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		ingredient.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		var updateObj primitive.D
+		updateObj = append(updateObj, bson.E{Key: "name", Value: ingredient.Name})
+		updateObj = append(updateObj, bson.E{Key: "low_stock_threshold", Value: ingredient.LowStockThreshold})
+		updateObj = append(updateObj, bson.E{Key: "unit_cost", Value: ingredient.UnitCost})
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: ingredient.UpdatedAt})
+
+		_, err = getIngredientCollection().UpdateOne(ctx,
+			bson.M{"ingredient_id": ingredientId},
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ingredient update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, ingredient)
+	}
+}
+
+// stockMovementRequest is a stock movement entered by staff. PURCHASE and
+// WASTE take the amount received or thrown away, ADJUSTMENT a signed
+// correction and COUNT the level found in a stock count.
+type stockMovementRequest struct {
+	Type     *string  `json:"type" validate:"required,eq=PURCHASE|eq=WASTE|eq=ADJUSTMENT|eq=COUNT"`
+	Quantity *float64 `json:"quantity" validate:"required"`
+	Reason   *string  `json:"reason" validate:"omitempty,max=200"`
+}
+
+// CreateStockMovement records a purchase, waste, adjustment or stock count
+// of an ingredient and updates its stock
+func CreateStockMovement() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		ingredientId := c.Param("ingredient_id")
+
+		var request stockMovementRequest
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := ingredientValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var ingredient models.Ingredient
+		err := getIngredientCollection().FindOne(ctx, bson.M{"ingredient_id": ingredientId}).Decode(&ingredient)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ingredient was not found"})
+			return
+		}
+
+		change := *request.Quantity
+		switch *request.Type {
+		case "PURCHASE", "WASTE":
+			if change <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must be greater than zero"})
+				return
+			}
+			if *request.Type == "WASTE" {
+				change = -change
+			}
+		case "ADJUSTMENT":
+			if change == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "an adjustment cannot be zero"})
+				return
+			}
+		case "COUNT":
+			if change < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "a counted quantity cannot be negative"})
+				return
+			}
+			change = *request.Quantity - ingredient.Stock
+		}
+
+		movement := models.StockMovement{
+			IngredientID: ingredientId,
+			Type:         request.Type,
+			Quantity:     &change,
+			Reason:       request.Reason,
+			By:           c.GetString("uid"),
+		}
+		if err := moveStock(ctx, &movement); err != nil {
+			if errors.Is(err, errIngredientNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "stock movement was not recorded"})
+			return
+		}
+
+		c.JSON(http.StatusOK, movement)
+	}
+}
+
+// GetStockMovements returns the stock ledger for a date range (?from=,
+// ?to=, default the last 7 days), newest first, optionally for one
+// ingredient (?ingredient_id=) or movement type (?type=)
+func GetStockMovements() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+
+		filter := bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}
+		if ingredientId := c.Query("ingredient_id"); ingredientId != "" {
+			filter["ingredient_id"] = ingredientId
+		}
+		if movementType := c.Query("type"); movementType != "" {
+			filter["type"] = movementType
+		}
+
+		var movements []models.StockMovement
+		cursor, err := getStockMovementCollection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing stock movements"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &movements); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, movements)
+	}
+}
@@ -2,28 +2,41 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/ali-adel-nour/restaurant-management/database"
+	"github.com/ali-adel-nour/restaurant-management/helpers"
 	"github.com/ali-adel-nour/restaurant-management/models"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var invoiceValidate = validator.New()
 
-// GetInvoices returns all invoices
+// GetInvoices returns all invoices, optionally filtered by payment status
+// (?status=overdue) or house account (?account_id=)
 func GetInvoices() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
 
+		filter := bson.M{}
+		if status := c.Query("status"); status != "" {
+			filter["payment_status"] = strings.ToUpper(status)
+		}
+		if accountId := c.Query("account_id"); accountId != "" {
+			filter["account_id"] = accountId
+		}
+
 		var invoices []models.Invoice
-		cursor, err := getInvoiceCollection().Find(ctx, bson.M{})
+		cursor, err := getInvoiceCollection().Find(ctx, filter)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing invoices"})
 			return
@@ -83,6 +96,91 @@ func CreateInvoice() gin.HandlerFunc {
 			return
 		}
 
+		if order.Status != nil && *order.Status == "VOID" {
+			c.JSON(http.StatusConflict, gin.H{"error": "void orders cannot be invoiced"})
+			return
+		}
+
+		count, err := getInvoiceCollection().CountDocuments(ctx, bson.M{"order_id": invoice.OrderID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for invoice"})
+			return
+		}
+
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "order has already been invoiced"})
+			return
+		}
+
+		var account models.HouseAccount
+		if invoice.AccountID != nil {
+			err := getHouseAccountCollection().FindOne(ctx, bson.M{"account_id": invoice.AccountID}).Decode(&account)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "house account was not found"})
+				return
+			}
+		}
+
+		lines, err := snapshotInvoiceLines(ctx, invoice.OrderID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching order items"})
+			return
+		}
+		invoice.Lines = lines
+
+		// Record the order totals, including discount, tax and service charge, on the invoice
+		totals, err := calculateOrderTotals(ctx, invoice.OrderID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while calculating order totals"})
+			return
+		}
+
+		invoice.SubTotal = totals.SubTotal
+		invoice.Discounts = totals.Discounts
+		invoice.DiscountTotal = totals.DiscountTotal
+		invoice.TaxInclusive = totals.TaxInclusive
+		invoice.Taxes = totals.Taxes
+		invoice.TaxTotal = totals.TaxTotal
+		invoice.Covers = totals.Covers
+		invoice.ServerID = order.ServerID
+		invoice.ServiceRate = totals.ServiceRate
+		invoice.ServiceCharge = totals.ServiceCharge
+		invoice.Total = totals.Total
+		invoice.TipTotal = 0
+
+		// Only the baseline statuses can be given; the others follow from
+		// payments and refunds
+		if *invoice.PaymentStatus != "PENDING" && *invoice.PaymentStatus != "PAID" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "payment_status must be PENDING or PAID"})
+			return
+		}
+
+		// Refunds and reminders are only recorded by their own endpoints
+		invoice.RefundedTotal = 0
+		invoice.ReminderCount = 0
+		invoice.LastReminder = nil
+
+		// Invoices created as PAID were settled outside the payments API;
+		// an EXTERNAL payment is recorded for them below so they can be
+		// refunded like any other
+		invoice.AmountPaid = 0
+		if *invoice.PaymentStatus == "PAID" {
+			invoice.AmountPaid = invoice.Total
+		}
+		invoice.BalanceDue = helpers.RoundMoney(invoice.Total - invoice.AmountPaid)
+
+		if account.CreditLimit != nil && invoice.BalanceDue > 0 {
+			owed, err := accountBalance(ctx, account.AccountID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking the house account balance"})
+				return
+			}
+			if helpers.RoundMoney(owed+invoice.BalanceDue) > *account.CreditLimit {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("invoice would exceed the house account credit limit of %.2f", *account.CreditLimit)})
+				return
+			}
+		}
+
 		invoice.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.ID = primitive.NewObjectID()
@@ -94,12 +192,70 @@ func CreateInvoice() gin.HandlerFunc {
 			invoice.PaymentDue = &paymentDue
 		}
 
+		// Number the invoice last so a failed insert can hand the number back
+		invoice.FiscalYear = helpers.FiscalYear(invoice.CreatedAt)
+		counter := fmt.Sprintf("invoice-%d", invoice.FiscalYear)
+		seq, err := database.NextSequence(ctx, counter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while numbering the invoice"})
+			return
+		}
+		invoice.InvoiceNumber = fmt.Sprintf("INV-%d-%06d", invoice.FiscalYear, seq)
+
+		// The unique index on order_id catches a concurrent invoice for the
+		// same order that got past the check above
 		result, insertErr := getInvoiceCollection().InsertOne(ctx, invoice)
 		if insertErr != nil {
+			if released, _ := database.ReleaseSequence(ctx, counter, seq); !released {
+				log.Printf("invoice number %s was skipped: %v", invoice.InvoiceNumber, insertErr)
+			}
+			if mongo.IsDuplicateKeyError(insertErr) && strings.Contains(insertErr.Error(), "order_id_unique") {
+				c.JSON(http.StatusConflict, gin.H{"error": "order has already been invoiced"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "invoice was not created"})
 			return
 		}
 
+		if invoice.AmountPaid > 0 {
+			method := "EXTERNAL"
+			reference := "settled outside the payments API"
+			payment := models.Payment{
+				InvoiceID:  invoice.InvoiceID,
+				Method:     &method,
+				Amount:     &invoice.AmountPaid,
+				Reference:  &reference,
+				ReceivedBy: c.GetString("uid"),
+				CreatedAt:  invoice.CreatedAt,
+			}
+			payment.ID = primitive.NewObjectID()
+			payment.PaymentID = payment.ID.Hex()
+
+			// Without its payment the invoice could never be refunded, so
+			// it is taken back
+			if _, err := getPaymentCollection().InsertOne(ctx, payment); err != nil {
+				getInvoiceCollection().DeleteOne(ctx, bson.M{"invoice_id": invoice.InvoiceID})
+				if released, _ := database.ReleaseSequence(ctx, counter, seq); !released {
+					log.Printf("invoice number %s was skipped: %v", invoice.InvoiceNumber, err)
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "payment of the invoice was not recorded"})
+				return
+			}
+		}
+
+		// Invoicing settles the check, so the order is closed
+		getOrderCollection().UpdateOne(ctx,
+			bson.M{"order_id": invoice.OrderID},
+			bson.M{"$set": bson.M{"status": "CLOSED", "updated_at": invoice.UpdatedAt}},
+		)
+
+		// Charging a house account settles up just like a payment, and so
+		// does an invoice created as PAID or with nothing left to pay
+		if invoice.AccountID != nil || invoice.BalanceDue <= 0 || *invoice.PaymentStatus == "PAID" {
+			releaseTable(ctx, invoice.OrderID)
+		}
+		closeOrderGroup(ctx, order)
+
 		c.JSON(http.StatusOK, result)
 	}
 }
@@ -118,34 +274,62 @@ func UpdateInvoice() gin.HandlerFunc {
 			return
 		}
 
+		var current models.Invoice
+		err := getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&current)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		if current.PaymentStatus != nil && *current.PaymentStatus != "PENDING" && *current.PaymentStatus != "PARTIALLY_PAID" && *current.PaymentStatus != "OVERDUE" {
+			c.JSON(http.StatusConflict, gin.H{"error": "paid invoices cannot be changed, issue a credit note instead"})
+			return
+		}
+
 		var updateObj primitive.D
 
 		if invoice.PaymentMethod != nil {
 			updateObj = append(updateObj, bson.E{Key: "payment_method", Value: invoice.PaymentMethod})
 		}
 
+		// Money only moves through the payment and refund endpoints, so the
+		// status can only be put back to unpaid or marked overdue here
 		if invoice.PaymentStatus != nil {
-			updateObj = append(updateObj, bson.E{Key: "payment_status", Value: invoice.PaymentStatus})
+			status := *invoice.PaymentStatus
+			if status != "PENDING" && status != "OVERDUE" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "payment_status can only be set to PENDING or OVERDUE, record payments and refunds instead"})
+				return
+			}
+			if status == "PENDING" && current.AmountPaid > 0 {
+				status = "PARTIALLY_PAID"
+			}
+			updateObj = append(updateObj, bson.E{Key: "payment_status", Value: status})
 		}
 
 		if invoice.PaymentDue != nil {
 			updateObj = append(updateObj, bson.E{Key: "payment_due", Value: invoice.PaymentDue})
+
+			// Moving the due date of an overdue invoice into the future
+			// takes it out of OVERDUE
+			if invoice.PaymentStatus == nil && current.PaymentStatus != nil && *current.PaymentStatus == "OVERDUE" && invoice.PaymentDue.After(time.Now()) {
+				status := "PENDING"
+				if current.AmountPaid > 0 {
+					status = "PARTIALLY_PAID"
+				}
+				updateObj = append(updateObj, bson.E{Key: "payment_status", Value: status})
+			}
 		}
 
 		invoice.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: invoice.UpdatedAt})
 
-		upsert := true
-		filter := bson.M{"invoice_id": invoiceId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
+		// The status guard closes the race with a concurrent payment
+		filter := bson.M{"invoice_id": invoiceId, "payment_status": bson.M{"$in": unpaidStatuses}}
 
 		result, err := getInvoiceCollection().UpdateOne(
 			ctx,
 			filter,
 			bson.D{{Key: "$set", Value: updateObj}},
-			&opt,
 		)
 
 		if err != nil {
@@ -153,10 +337,92 @@ func UpdateInvoice() gin.HandlerFunc {
 			return
 		}
 
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "paid invoices cannot be changed, issue a credit note instead"})
+			return
+		}
+
 		c.JSON(http.StatusOK, result)
 	}
 }
 
+// snapshotInvoiceLines copies the order items of an order, with food
+// names and tax categories, so the invoice keeps its meaning if foods or
+// items change later
+func snapshotInvoiceLines(ctx context.Context, orderId string) ([]models.InvoiceLine, error) {
+	var orderItems []models.OrderItem
+	cursor, err := getOrderItemCollection().Find(ctx, bson.M{"order_id": orderId, "voided": bson.M{"$ne": true}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &orderItems); err != nil {
+		return nil, err
+	}
+
+	lines := []models.InvoiceLine{}
+	for _, item := range orderItems {
+		if item.FoodID == nil || item.Quantity == nil || item.UnitPrice == nil {
+			continue
+		}
+
+		line := models.InvoiceLine{
+			OrderItemID: item.OrderItemID,
+			FoodID:      *item.FoodID,
+			Quantity:    *item.Quantity,
+			UnitPrice:   *item.UnitPrice,
+			LineTotal:   helpers.RoundMoney(float64(*item.Quantity) * *item.UnitPrice),
+			PriceRule:   item.PriceRule,
+		}
+
+		var food models.Food
+		if err := getFoodCollection().FindOne(ctx, bson.M{"food_id": item.FoodID}).Decode(&food); err == nil {
+			if food.Name != nil {
+				line.Name = *food.Name
+			}
+			line.TaxCategoryID = food.TaxCategoryID
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// GetInvoiceDetail returns an invoice with its lines and tax breakdown
+func GetInvoiceDetail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+		var invoice models.Invoice
+
+		err := getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		// Invoices created before line snapshots fall back to the live order
+		lines := invoice.Lines
+		if lines == nil {
+			lines, err = snapshotInvoiceLines(ctx, invoice.OrderID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching order items"})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"invoice": invoice,
+			"lines":   lines,
+			"taxes":   invoice.Taxes,
+		})
+	}
+}
+
 // GetAllInvoices returns all invoices
 func GetAllInvoices() gin.HandlerFunc {
 	return GetInvoices()
@@ -0,0 +1,304 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// laborRow is the hours and labor cost of one staff member or one day,
+// against the net sales of the same staff member's checks or of the day
+type laborRow struct {
+	UserID            string   `json:"user_id,omitempty"`
+	Name              string   `json:"name,omitempty"`
+	Date              string   `json:"date,omitempty"`
+	HourlyRate        *float64 `json:"hourly_rate,omitempty"`
+	ScheduledHours    float64  `json:"scheduled_hours"`
+	WorkedHours       float64  `json:"worked_hours"`
+	RegularHours      float64  `json:"regular_hours"`
+	OvertimeHours     float64  `json:"overtime_hours"`
+	BreakHours        float64  `json:"break_hours"`
+	RegularCost       float64  `json:"regular_cost"`
+	OvertimeCost      float64  `json:"overtime_cost"`
+	LaborCost         float64  `json:"labor_cost"`
+	NetSales          float64  `json:"net_sales"`
+	LaborPercent      *float64 `json:"labor_percent"`
+	SalesPerLaborHour float64  `json:"sales_per_labor_hour"`
+	OpenEntries       int      `json:"open_entries"`
+}
+
+// addDay adds one staff member's day of work paid at rate
+func (r *laborRow) addDay(day helpers.WorkedDay, breakHours float64, rate float64, rule helpers.OvertimeRule) {
+	r.WorkedHours += day.Hours
+	r.RegularHours += day.Regular
+	r.OvertimeHours += day.Overtime
+	r.BreakHours += breakHours
+	r.RegularCost += day.Regular * rate
+	r.OvertimeCost += day.Overtime * rate * rule.Multiplier
+}
+
+// finish rounds the totals and works out labor against sales
+func (r *laborRow) finish() {
+	r.LaborCost = helpers.RoundMoney(r.RegularCost + r.OvertimeCost)
+	r.ScheduledHours = helpers.RoundMoney(r.ScheduledHours)
+	r.WorkedHours = helpers.RoundMoney(r.WorkedHours)
+	r.RegularHours = helpers.RoundMoney(r.RegularHours)
+	r.OvertimeHours = helpers.RoundMoney(r.OvertimeHours)
+	r.BreakHours = helpers.RoundMoney(r.BreakHours)
+	r.RegularCost = helpers.RoundMoney(r.RegularCost)
+	r.OvertimeCost = helpers.RoundMoney(r.OvertimeCost)
+	r.NetSales = helpers.RoundMoney(r.NetSales)
+
+	r.LaborPercent = nil
+	if r.NetSales > 0 {
+		percent := helpers.RoundMoney(r.LaborCost / r.NetSales * 100)
+		r.LaborPercent = &percent
+	}
+	r.SalesPerLaborHour = 0
+	if r.WorkedHours > 0 {
+		r.SalesPerLaborHour = helpers.RoundMoney(r.NetSales / r.WorkedHours)
+	}
+}
+
+func (r laborRow) record() []string {
+	rate, percent := "", ""
+	if r.HourlyRate != nil {
+		rate = formatMoney(*r.HourlyRate)
+	}
+	if r.LaborPercent != nil {
+		percent = formatMoney(*r.LaborPercent)
+	}
+	return []string{
+		r.UserID,
+		r.Name,
+		rate,
+		formatMoney(r.ScheduledHours),
+		formatMoney(r.WorkedHours),
+		formatMoney(r.RegularHours),
+		formatMoney(r.OvertimeHours),
+		formatMoney(r.BreakHours),
+		formatMoney(r.RegularCost),
+		formatMoney(r.OvertimeCost),
+		formatMoney(r.LaborCost),
+		formatMoney(r.NetSales),
+		percent,
+		strconv.Itoa(r.OpenEntries),
+	}
+}
+
+var laborHeader = []string{
+	"user_id", "name", "hourly_rate", "scheduled_hours", "worked_hours", "regular_hours", "overtime_hours",
+	"break_hours", "regular_cost", "overtime_cost", "labor_cost", "net_sales", "labor_percent", "open_entries",
+}
+
+// staffDay is what one staff member worked on one local day
+type staffDay struct {
+	hours      float64
+	breakHours float64
+	pay        float64
+}
+
+// GetLaborReport returns hours worked, overtime and labor cost per staff
+// member and per day of a date range, against net sales. Work counts on
+// the day it was clocked in, and time still on the clock counts up to now.
+func GetLaborReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		// The report shows pay rates and wages
+		if !userHasRole(ctx, c.GetString("uid"), "ADMIN", "MANAGER") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only managers can see the labor report"})
+			return
+		}
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+		location, _ := reportLocation(c)
+		format, ok := reportFormat(c)
+		if !ok {
+			return
+		}
+
+		// Weekly overtime needs the hours of the whole first week
+		weekStart := helpers.WeekStart(from, location)
+		firstDay := from.In(location).Format("2006-01-02")
+
+		var entries []models.TimeEntry
+		err := findAll(ctx, getTimeEntryCollection(), bson.M{"clock_in": bson.M{"$gte": weekStart, "$lt": to}}, &entries)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing time entries"})
+			return
+		}
+
+		var shifts []models.Shift
+		err = findAll(ctx, getShiftCollection(), bson.M{"status": "SCHEDULED", "starts_at": bson.M{"$gte": from, "$lt": to}}, &shifts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing shifts"})
+			return
+		}
+
+		var invoices []models.Invoice
+		err = findAll(ctx, getInvoiceCollection(), bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}, &invoices)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing invoices"})
+			return
+		}
+
+		rule := helpers.OvertimeRules()
+		now := time.Now()
+
+		staffRows := map[string]*laborRow{}
+		staffRow := func(userId string) *laborRow {
+			row, ok := staffRows[userId]
+			if !ok {
+				row = &laborRow{UserID: userId}
+				staffRows[userId] = row
+			}
+			return row
+		}
+		dayRows := map[string]*laborRow{}
+		dayRow := func(date string) *laborRow {
+			row, ok := dayRows[date]
+			if !ok {
+				row = &laborRow{Date: date}
+				dayRows[date] = row
+			}
+			return row
+		}
+
+		worked := map[string]map[string]*staffDay{}
+		for _, entry := range entries {
+			workedMinutes, breakMinutes := entryMinutes(entry, now)
+			date := entry.ClockIn.In(location).Format("2006-01-02")
+
+			days, ok := worked[entry.UserID]
+			if !ok {
+				days = map[string]*staffDay{}
+				worked[entry.UserID] = days
+			}
+			day, ok := days[date]
+			if !ok {
+				day = &staffDay{}
+				days[date] = day
+			}
+			day.hours += workedMinutes / 60
+			day.breakHours += breakMinutes / 60
+			day.pay += workedMinutes / 60 * entry.HourlyRate
+
+			if entry.Status == "OPEN" && date >= firstDay {
+				staffRow(entry.UserID).OpenEntries++
+			}
+		}
+
+		for userId, days := range worked {
+			split := []helpers.WorkedDay{}
+			for date, day := range days {
+				midnight, _ := time.ParseInLocation("2006-01-02", date, location)
+				split = append(split, helpers.WorkedDay{Date: midnight, Hours: day.hours})
+			}
+			helpers.SplitOvertime(split, rule)
+
+			for _, workedDay := range split {
+				date := workedDay.Date.Format("2006-01-02")
+				if date < firstDay {
+					continue
+				}
+				day := days[date]
+				rate := 0.0
+				if day.hours > 0 {
+					rate = day.pay / day.hours
+				}
+				staffRow(userId).addDay(workedDay, day.breakHours, rate, rule)
+				dayRow(date).addDay(workedDay, day.breakHours, rate, rule)
+			}
+		}
+
+		for _, shift := range shifts {
+			hours := scheduledHours(shift)
+			staffRow(*shift.UserID).ScheduledHours += hours
+			dayRow(shift.StartsAt.In(location).Format("2006-01-02")).ScheduledHours += hours
+		}
+
+		var totals laborRow
+		for _, invoice := range invoices {
+			sales := invoiceNetSales(invoice)
+			totals.NetSales += sales
+			dayRow(invoice.CreatedAt.In(location).Format("2006-01-02")).NetSales += sales
+			if invoice.ServerID != nil {
+				if row, ok := staffRows[*invoice.ServerID]; ok {
+					row.NetSales += sales
+				}
+			}
+		}
+
+		ids := make([]string, 0, len(staffRows))
+		for userId := range staffRows {
+			ids = append(ids, userId)
+		}
+
+		var users []models.User
+		if err := findAll(ctx, getUserCollection(), bson.M{"user_id": bson.M{"$in": ids}}, &users); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing staff"})
+			return
+		}
+		names := serverNames(ctx, ids)
+		for _, user := range users {
+			staffRows[user.UserID].HourlyRate = user.HourlyRate
+		}
+
+		byStaff := []laborRow{}
+		for _, row := range staffRows {
+			row.Name = names[row.UserID]
+			row.finish()
+			byStaff = append(byStaff, *row)
+		}
+		sort.Slice(byStaff, func(i, j int) bool { return byStaff[i].Name < byStaff[j].Name })
+
+		byDay := []laborRow{}
+		for _, row := range dayRows {
+			totals.ScheduledHours += row.ScheduledHours
+			totals.WorkedHours += row.WorkedHours
+			totals.RegularHours += row.RegularHours
+			totals.OvertimeHours += row.OvertimeHours
+			totals.BreakHours += row.BreakHours
+			totals.RegularCost += row.RegularCost
+			totals.OvertimeCost += row.OvertimeCost
+			row.finish()
+			byDay = append(byDay, *row)
+		}
+		sort.Slice(byDay, func(i, j int) bool { return byDay[i].Date < byDay[j].Date })
+		for _, row := range byStaff {
+			totals.OpenEntries += row.OpenEntries
+		}
+		totals.finish()
+
+		if format == "csv" {
+			records := [][]string{}
+			for _, row := range byStaff {
+				records = append(records, row.record())
+			}
+			writeCSV(c, "labor", laborHeader, records)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":     from.In(location),
+			"to":       to.In(location),
+			"timezone": location.String(),
+			"overtime": rule,
+			"totals":   totals,
+			"staff":    byStaff,
+			"by_day":   byDay,
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// popularityFactor is the share of an even menu mix a food must reach to
+// count as popular, the usual 70% rule of menu engineering
+const popularityFactor = 0.7
+
+// foodSales is the units sold and revenue of one food
+type foodSales struct {
+	FoodID  string  `bson:"_id"`
+	Units   int     `bson:"units"`
+	Revenue float64 `bson:"revenue"`
+}
+
+// menuEngineeringItem is one food placed in the popularity and
+// profitability matrix
+type menuEngineeringItem struct {
+	FoodID         string   `json:"food_id"`
+	Name           string   `json:"name"`
+	Price          float64  `json:"price"`
+	Cost           *float64 `json:"cost"`
+	UnitsSold      int      `json:"units_sold"`
+	MenuMixPercent float64  `json:"menu_mix_percent"`
+	Revenue        float64  `json:"revenue"`
+	UnitMargin     *float64 `json:"unit_margin"`
+	TotalMargin    *float64 `json:"total_margin"`
+	Popularity     string   `json:"popularity"`
+	Profitability  string   `json:"profitability"`
+	Classification string   `json:"classification"`
+}
+
+// menuEngineering is the matrix of one menu
+type menuEngineering struct {
+	MenuID              string                `json:"menu_id"`
+	Name                string                `json:"name"`
+	Category            string                `json:"category"`
+	UnitsSold           int                   `json:"units_sold"`
+	PopularityThreshold float64               `json:"popularity_threshold"`
+	AverageMargin       float64               `json:"average_margin"`
+	Items               []menuEngineeringItem `json:"items"`
+}
+
+// soldFoods returns the units sold and revenue of each food from the order
+// items entered in [from, to), leaving out voided items and void orders
+func soldFoods(ctx context.Context, from time.Time, to time.Time) (map[string]foodSales, error) {
+	matchStage := bson.D{{Key: "$match", Value: bson.D{
+		{Key: "created_at", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: to}}},
+		{Key: "voided", Value: bson.D{{Key: "$ne", Value: true}}},
+	}}}
+	lookupStage := bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: getOrderCollection().Name()},
+		{Key: "localField", Value: "order_id"},
+		{Key: "foreignField", Value: "order_id"},
+		{Key: "as", Value: "order"},
+	}}}
+	orderStage := bson.D{{Key: "$match", Value: bson.D{{Key: "order.status", Value: bson.D{{Key: "$ne", Value: "VOID"}}}}}}
+	groupStage := bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: "$food_id"},
+		{Key: "units", Value: bson.D{{Key: "$sum", Value: "$quantity"}}},
+		{Key: "revenue", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$multiply", Value: bson.A{"$quantity", "$unit_price"}}}}}},
+	}}}
+
+	cursor, err := getOrderItemCollection().Aggregate(ctx, mongo.Pipeline{matchStage, lookupStage, orderStage, groupStage})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []foodSales
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	sales := map[string]foodSales{}
+	for _, row := range rows {
+		sales[row.FoodID] = row
+	}
+	return sales, nil
+}
+
+// engineerMenu classifies the foods of a menu. A food is popular when its
+// share of units sold reaches 70% of an even share, and profitable when its
+// unit margin reaches the menu's weighted average margin. Foods without a
+// cost are listed but not classified.
+func engineerMenu(menu models.Menu, foods []models.Food, sales map[string]foodSales) menuEngineering {
+	report := menuEngineering{
+		MenuID:   menu.MenuID,
+		Name:     menu.Name,
+		Category: menu.Category,
+		Items:    []menuEngineeringItem{},
+	}
+
+	var costedUnits int
+	var costedMargin float64
+	for _, food := range foods {
+		sold := sales[food.FoodID]
+		item := menuEngineeringItem{
+			FoodID:    food.FoodID,
+			Cost:      food.Cost,
+			UnitsSold: sold.Units,
+			Revenue:   helpers.RoundMoney(sold.Revenue),
+		}
+		if food.Name != nil {
+			item.Name = *food.Name
+		}
+		if food.Price != nil {
+			item.Price = *food.Price
+		}
+
+		// Margins use the average price actually charged, so price rules
+		// and manual prices count; unsold foods use the menu price
+		if food.Cost != nil {
+			price := item.Price
+			if sold.Units > 0 {
+				price = sold.Revenue / float64(sold.Units)
+			}
+			unitMargin := helpers.RoundMoney(price - *food.Cost)
+			totalMargin := helpers.RoundMoney(unitMargin * float64(sold.Units))
+			item.UnitMargin = &unitMargin
+			item.TotalMargin = &totalMargin
+
+			costedUnits += sold.Units
+			costedMargin += totalMargin
+		}
+
+		report.UnitsSold += sold.Units
+		report.Items = append(report.Items, item)
+	}
+
+	if len(report.Items) > 0 {
+		report.PopularityThreshold = helpers.RoundMoney(100 / float64(len(report.Items)) * popularityFactor)
+	}
+	if costedUnits > 0 {
+		report.AverageMargin = helpers.RoundMoney(costedMargin / float64(costedUnits))
+	}
+
+	for i := range report.Items {
+		item := &report.Items[i]
+		if report.UnitsSold > 0 {
+			item.MenuMixPercent = helpers.RoundMoney(float64(item.UnitsSold) / float64(report.UnitsSold) * 100)
+		}
+
+		item.Popularity = "LOW"
+		if report.UnitsSold > 0 && item.MenuMixPercent >= report.PopularityThreshold {
+			item.Popularity = "HIGH"
+		}
+
+		if item.UnitMargin == nil {
+			continue
+		}
+
+		item.Profitability = "LOW"
+		if *item.UnitMargin >= report.AverageMargin {
+			item.Profitability = "HIGH"
+		}
+
+		switch {
+		case item.Popularity == "HIGH" && item.Profitability == "HIGH":
+			item.Classification = "STAR"
+		case item.Popularity == "HIGH":
+			item.Classification = "PLOWHORSE"
+		case item.Profitability == "HIGH":
+			item.Classification = "PUZZLE"
+		default:
+			item.Classification = "DOG"
+		}
+	}
+
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		return report.Items[i].UnitsSold > report.Items[j].UnitsSold
+	})
+
+	return report
+}
+
+// GetMenuEngineeringReport classifies each food as a star, plowhorse,
+// puzzle or dog from its units sold and margin in a date range, one matrix
+// per menu (?menu_id= for a single menu)
+func GetMenuEngineeringReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+		location, _ := reportLocation(c)
+		format, ok := reportFormat(c)
+		if !ok {
+			return
+		}
+
+		menuFilter := bson.M{}
+		if menuId := c.Query("menu_id"); menuId != "" {
+			menuFilter["menu_id"] = menuId
+		}
+
+		var menus []models.Menu
+		if err := findAll(ctx, getMenuCollection(), menuFilter, &menus); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing menus"})
+			return
+		}
+
+		if len(menus) == 0 && c.Query("menu_id") != "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+			return
+		}
+
+		sales, err := soldFoods(ctx, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while totalling units sold"})
+			return
+		}
+
+		reports := []menuEngineering{}
+		for _, menu := range menus {
+			var foods []models.Food
+			if err := findAll(ctx, getFoodCollection(), bson.M{"menu_id": menu.MenuID}, &foods); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing foods"})
+				return
+			}
+			reports = append(reports, engineerMenu(menu, foods, sales))
+		}
+
+		if format == "csv" {
+			optional := func(amount *float64) string {
+				if amount == nil {
+					return ""
+				}
+				return formatMoney(*amount)
+			}
+
+			records := [][]string{}
+			for _, menu := range reports {
+				for _, item := range menu.Items {
+					records = append(records, []string{
+						menu.MenuID, menu.Name, item.FoodID, item.Name, formatMoney(item.Price), optional(item.Cost),
+						strconv.Itoa(item.UnitsSold), formatMoney(item.MenuMixPercent), formatMoney(item.Revenue),
+						optional(item.UnitMargin), optional(item.TotalMargin), item.Classification,
+					})
+				}
+			}
+			writeCSV(c, "menu-engineering", []string{
+				"menu_id", "menu", "food_id", "food", "price", "cost", "units_sold", "menu_mix_percent",
+				"revenue", "unit_margin", "total_margin", "classification",
+			}, records)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":     from.In(location),
+			"to":       to.In(location),
+			"timezone": location.String(),
+			"menus":    reports,
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+func TestEngineerMenu(t *testing.T) {
+	star, plowhorse, puzzle, dog, uncosted := "star", "plowhorse", "puzzle", "dog", "uncosted"
+	twenty, ten, thirty, twelve, fifteen := 20.0, 10.0, 30.0, 12.0, 15.0
+	five, eight := 5.0, 8.0
+
+	menu := models.Menu{MenuID: "menu", Name: "Dinner", Category: "MAINS"}
+	foods := []models.Food{
+		{FoodID: star, Name: &star, Price: &twenty, Cost: &five},
+		{FoodID: plowhorse, Name: &plowhorse, Price: &ten, Cost: &eight},
+		{FoodID: puzzle, Name: &puzzle, Price: &thirty, Cost: &five},
+		{FoodID: dog, Name: &dog, Price: &twelve, Cost: &ten},
+		{FoodID: uncosted, Name: &uncosted, Price: &fifteen},
+	}
+	sales := map[string]foodSales{
+		star:      {FoodID: star, Units: 40, Revenue: 800},
+		plowhorse: {FoodID: plowhorse, Units: 40, Revenue: 400},
+		puzzle:    {FoodID: puzzle, Units: 5, Revenue: 150},
+		dog:       {FoodID: dog, Units: 5, Revenue: 60},
+		uncosted:  {FoodID: uncosted, Units: 10, Revenue: 150},
+	}
+
+	report := engineerMenu(menu, foods, sales)
+
+	if report.UnitsSold != 100 {
+		t.Errorf("units sold = %d, want 100", report.UnitsSold)
+	}
+	if report.PopularityThreshold != 14 {
+		t.Errorf("popularity threshold = %v, want 14", report.PopularityThreshold)
+	}
+	// (15*40 + 2*40 + 25*5 + 2*5) / 90 costed units
+	if report.AverageMargin != 9.06 {
+		t.Errorf("average margin = %v, want 9.06", report.AverageMargin)
+	}
+
+	tests := []struct {
+		foodId         string
+		menuMix        float64
+		unitMargin     float64
+		costed         bool
+		popularity     string
+		classification string
+	}{
+		{star, 40, 15, true, "HIGH", "STAR"},
+		{plowhorse, 40, 2, true, "HIGH", "PLOWHORSE"},
+		{puzzle, 5, 25, true, "LOW", "PUZZLE"},
+		{dog, 5, 2, true, "LOW", "DOG"},
+		{uncosted, 10, 0, false, "LOW", ""},
+	}
+
+	if len(report.Items) != len(tests) {
+		t.Fatalf("got %d items, want %d", len(report.Items), len(tests))
+	}
+	items := map[string]menuEngineeringItem{}
+	for _, item := range report.Items {
+		items[item.FoodID] = item
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.foodId, func(t *testing.T) {
+			item, ok := items[tt.foodId]
+			if !ok {
+				t.Fatalf("%s is missing from the report", tt.foodId)
+			}
+			if item.MenuMixPercent != tt.menuMix {
+				t.Errorf("menu mix = %v, want %v", item.MenuMixPercent, tt.menuMix)
+			}
+			if (item.UnitMargin != nil) != tt.costed || (item.UnitMargin != nil && *item.UnitMargin != tt.unitMargin) {
+				t.Errorf("unit margin = %v, want %v", item.UnitMargin, tt.unitMargin)
+			}
+			if item.Popularity != tt.popularity {
+				t.Errorf("popularity = %s, want %s", item.Popularity, tt.popularity)
+			}
+			if item.Classification != tt.classification {
+				t.Errorf("classification = %q, want %q", item.Classification, tt.classification)
+			}
+		})
+	}
+}
+
+func TestEngineerMenuWithoutSales(t *testing.T) {
+	soup := "soup"
+	price, cost := 6.0, 2.0
+	foods := []models.Food{{FoodID: soup, Name: &soup, Price: &price, Cost: &cost}}
+
+	report := engineerMenu(models.Menu{MenuID: "menu"}, foods, map[string]foodSales{})
+
+	item := report.Items[0]
+	if item.Popularity != "LOW" || item.MenuMixPercent != 0 {
+		t.Errorf("unsold food = %+v, want LOW popularity and no menu mix", item)
+	}
+	if item.UnitMargin == nil || *item.UnitMargin != 4 {
+		t.Errorf("unit margin = %v, want the menu price less cost", item.UnitMargin)
+	}
+}
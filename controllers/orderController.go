@@ -2,6 +2,8 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -11,7 +13,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var orderValidate = validator.New()
@@ -57,6 +59,203 @@ func GetOrderByID() gin.HandlerFunc {
 	}
 }
 
+var errTableDirty = errors.New("table must be cleaned before guests are seated")
+
+// openOrder opens an order in the current business day and assigns its
+// server. Dine-in guests are seated when their order is opened unless the
+// order says otherwise; another check for the party already at the table
+// leaves it as it is.
+func openOrder(ctx context.Context, order *models.Order, table models.Table) (*mongo.InsertOneResult, error) {
+	day, err := currentBusinessDay(ctx)
+	if err != nil {
+		return nil, errors.New("error occurred while opening the business day")
+	}
+
+	status := "OPEN"
+	order.Status = &status
+	order.BusinessDayID = day.BusinessDayID
+	order.UnseatedAt = nil
+	order.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	order.ID = primitive.NewObjectID()
+	order.OrderID = order.ID.Hex()
+
+	if err := assignServer(ctx, order, table); err != nil {
+		return nil, err
+	}
+
+	if *order.OrderType == "DINE_IN" {
+		if order.SeatedAt == nil {
+			seatedAt := order.CreatedAt
+			order.SeatedAt = &seatedAt
+		}
+
+		// A group's party sits at all of its tables
+		tables := []models.Table{table}
+		if order.TableGroupID != nil {
+			grouped, err := groupTables(ctx, *order.TableGroupID)
+			if err != nil {
+				return nil, errors.New("error occurred while fetching the table group")
+			}
+			tables = grouped
+		}
+
+		for _, table := range tables {
+			if tableStatus(table) == "DIRTY" {
+				return nil, errTableDirty
+			}
+		}
+
+		seated, err := seatTables(ctx, tables)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := getOrderCollection().InsertOne(ctx, order)
+		if err != nil {
+			unseatTables(ctx, seated)
+			return nil, errors.New("order was not created")
+		}
+		return result, nil
+	}
+
+	result, err := getOrderCollection().InsertOne(ctx, order)
+	if err != nil {
+		return nil, errors.New("order was not created")
+	}
+	return result, nil
+}
+
+// seatTables marks the free tables among tables SEATED and returns them as
+// they were before, so they can be put back. Tables already occupied by
+// another check are left as they are. Nothing stays seated on error.
+func seatTables(ctx context.Context, tables []models.Table) ([]models.Table, error) {
+	var seated []models.Table
+	for _, table := range tables {
+		switch tableStatus(table) {
+		case "AVAILABLE", "RESERVED":
+			if err := setTableStatus(ctx, table, "SEATED"); err != nil {
+				unseatTables(ctx, seated)
+				return nil, fmt.Errorf("table was not seated: %w", err)
+			}
+			seated = append(seated, table)
+		}
+	}
+	return seated, nil
+}
+
+// unseatTables puts tables seated by seatTables back to the status they
+// had, unless they have moved on since
+func unseatTables(ctx context.Context, tables []models.Table) {
+	status := "SEATED"
+	for _, table := range tables {
+		from := table
+		from.Status = &status
+		if err := setTableStatus(ctx, from, tableStatus(table)); err != nil {
+			log.Println("table was not put back:", err)
+		}
+	}
+}
+
+// moveOrderTable moves an open order to another table. A dine-in party is
+// seated at the new table, or at all of its group, and the tables it
+// leaves are freed once no other check is open at them: they become
+// AVAILABLE if nothing was ordered there yet and DIRTY otherwise. An order
+// leaving a table group closes the group once its last check has moved.
+func moveOrderTable(ctx context.Context, order models.Order, table models.Table) (models.Order, error) {
+	moved := order
+	moved.TableID = &table.TableID
+	moved.TableGroupID = table.TableGroupID
+
+	dineIn := order.OrderType == nil || *order.OrderType == "DINE_IN"
+
+	var seated []models.Table
+	if dineIn {
+		tables := []models.Table{table}
+		if table.TableGroupID != nil {
+			grouped, err := groupTables(ctx, *table.TableGroupID)
+			if err != nil {
+				return order, errors.New("error occurred while fetching the table group")
+			}
+			tables = grouped
+		}
+
+		for _, table := range tables {
+			if tableStatus(table) == "DIRTY" {
+				return order, errTableDirty
+			}
+		}
+
+		var err error
+		if seated, err = seatTables(ctx, tables); err != nil {
+			return order, err
+		}
+	}
+
+	leaving := orderTables(ctx, order)
+
+	moved.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := getOrderCollection().UpdateOne(ctx,
+		bson.M{"order_id": order.OrderID, "table_id": order.TableID, "status": order.Status},
+		bson.M{"$set": bson.M{
+			"table_id":       moved.TableID,
+			"table_group_id": moved.TableGroupID,
+			"updated_at":     moved.UpdatedAt,
+		}},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		unseatTables(ctx, seated)
+		return order, errors.New("order changed while it was moved, please retry")
+	}
+
+	if dineIn {
+		freeTables(ctx, order.OrderID, leaving, moved)
+	}
+
+	if order.TableGroupID != nil && (moved.TableGroupID == nil || *moved.TableGroupID != *order.TableGroupID) {
+		closeOrderGroup(ctx, order)
+	}
+	return moved, nil
+}
+
+// freeTables frees the tables an order has left for moved, skipping those
+// it still sits at and those another open check is using
+func freeTables(ctx context.Context, orderId string, tables []models.Table, moved models.Order) {
+	staying := map[string]bool{}
+	for _, table := range orderTables(ctx, moved) {
+		staying[table.TableID] = true
+	}
+
+	for _, table := range tables {
+		if staying[table.TableID] {
+			continue
+		}
+
+		open := bson.A{bson.M{"table_id": table.TableID}}
+		if table.TableGroupID != nil {
+			open = append(open, bson.M{"table_group_id": table.TableGroupID})
+		}
+
+		count, err := getOrderCollection().CountDocuments(ctx, bson.M{
+			"$or":        open,
+			"order_type": "DINE_IN",
+			"status":     "OPEN",
+			"order_id":   bson.M{"$ne": orderId},
+		})
+		if err != nil || count > 0 {
+			continue
+		}
+
+		to, ok := vacatedStatus(tableStatus(table), true)
+		if !ok {
+			continue
+		}
+		if err := setTableStatus(ctx, table, to); err != nil {
+			log.Println("table was not freed:", err)
+		}
+	}
+}
+
 // CreateOrder creates a new order
 func CreateOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -71,6 +270,17 @@ func CreateOrder() gin.HandlerFunc {
 			return
 		}
 
+		// An order for a table group is placed at the group's primary table
+		if order.TableGroupID != nil && order.TableID == nil {
+			var group models.TableGroup
+			err := getTableGroupCollection().FindOne(ctx, bson.M{"group_id": order.TableGroupID}).Decode(&group)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "table group was not found"})
+				return
+			}
+			order.TableID = &group.PrimaryTableID
+		}
+
 		validationErr := orderValidate.Struct(order)
 		if validationErr != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
@@ -86,17 +296,63 @@ func CreateOrder() gin.HandlerFunc {
 			}
 		}
 
-		order.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		order.ID = primitive.NewObjectID()
-		order.OrderID = order.ID.Hex()
+		// An order at a grouped table belongs to the group
+		if order.TableGroupID != nil && (table.TableGroupID == nil || *table.TableGroupID != *order.TableGroupID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "table is not in the active table group"})
+			return
+		}
+		order.TableGroupID = table.TableGroupID
+
+		if order.OrderType == nil {
+			orderType := "DINE_IN"
+			order.OrderType = &orderType
+		}
+
+		// An order opened for a reservation seats the party that booked it
+		var reservation models.Reservation
+		if order.ReservationID != nil {
+			if *order.OrderType != "DINE_IN" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "only dine-in orders can seat a reservation"})
+				return
+			}
+
+			err := getReservationCollection().FindOne(ctx, bson.M{"reservation_id": order.ReservationID}).Decode(&reservation)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "reservation was not found"})
+				return
+			}
+
+			if status := *reservation.Status; status != "BOOKED" && status != "CONFIRMED" {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("a %s reservation cannot be seated", status)})
+				return
+			}
 
-		result, insertErr := getOrderCollection().InsertOne(ctx, order)
-		if insertErr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "order was not created"})
+			if order.NumberOfGuests == nil {
+				order.NumberOfGuests = reservation.PartySize
+			}
+		}
+
+		order.CreatedBy = c.GetString("uid")
+		result, err := openOrder(ctx, &order, table)
+		if err != nil {
+			if errors.Is(err, errServerNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, errTableDirty) || errors.Is(err, errTableChanged) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		if order.ReservationID != nil {
+			if err := seatReservation(ctx, reservation, order); err != nil {
+				log.Println("reservation was not marked seated:", err)
+			}
+		}
+
 		c.JSON(http.StatusOK, result)
 	}
 }
@@ -115,32 +371,109 @@ func UpdateOrder() gin.HandlerFunc {
 			return
 		}
 
+		var current models.Order
+		err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&current)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		if err := checkOrderOpen(ctx, current); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		// A void was authorized by a manager and counted on the Z report,
+		// so a voided order stays voided
+		if current.Status != nil && *current.Status == "VOID" {
+			c.JSON(http.StatusConflict, gin.H{"error": "order has been voided"})
+			return
+		}
+
 		var updateObj primitive.D
 
-		if order.TableID != nil {
+		if order.OrderType != nil {
+			validationErr := orderValidate.StructPartial(order, "OrderType")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "order_type", Value: order.OrderType})
+		}
+
+		if order.NumberOfGuests != nil {
+			validationErr := orderValidate.StructPartial(order, "NumberOfGuests")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "number_of_guests", Value: order.NumberOfGuests})
+		}
+
+		if order.Status != nil {
+			validationErr := orderValidate.StructPartial(order, "Status")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+
+			// Voids need a manager and are recorded for the Z report
+			if *order.Status == "VOID" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "orders are voided through POST /orders/:order_id/void"})
+				return
+			}
+
+			// Once invoiced, an order cannot be reopened; the invoice is
+			// refunded instead
+			if *order.Status != "CLOSED" {
+				count, err := getInvoiceCollection().CountDocuments(ctx, bson.M{"order_id": orderId})
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for invoice"})
+					return
+				}
+				if count > 0 {
+					c.JSON(http.StatusConflict, gin.H{"error": "order has already been invoiced"})
+					return
+				}
+			}
+			updateObj = append(updateObj, bson.E{Key: "status", Value: order.Status})
+		}
+
+		// A party moving tables is seated at the new one and frees the old
+		if order.TableID != nil && (current.TableID == nil || *order.TableID != *current.TableID) {
+			if current.Status != nil && *current.Status != "OPEN" {
+				c.JSON(http.StatusConflict, gin.H{"error": "only open orders can move tables"})
+				return
+			}
+
 			var table models.Table
 			err := getTableCollection().FindOne(ctx, bson.M{"table_id": order.TableID}).Decode(&table)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "table was not found"})
+				c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+				return
+			}
+
+			current, err = moveOrderTable(ctx, current, table)
+			if err != nil {
+				if errors.Is(err, errTableDirty) || errors.Is(err, errTableChanged) {
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
-			updateObj = append(updateObj, bson.E{Key: "table_id", Value: order.TableID})
 		}
 
 		order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: order.UpdatedAt})
 
-		upsert := true
-		filter := bson.M{"order_id": orderId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
+		// Guarded so a void made meanwhile is not undone
+		filter := bson.M{"order_id": orderId, "status": bson.M{"$ne": "VOID"}}
 
 		result, err := getOrderCollection().UpdateOne(
 			ctx,
 			filter,
 			bson.D{{Key: "$set", Value: updateObj}},
-			&opt,
 		)
 
 		if err != nil {
@@ -148,10 +481,253 @@ func UpdateOrder() gin.HandlerFunc {
 			return
 		}
 
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "order has been voided"})
+			return
+		}
+
+		// A closed check frees its tables and then its table group
+		if order.Status != nil && *order.Status != "OPEN" {
+			releaseTable(ctx, orderId)
+			closeOrderGroup(ctx, current)
+		}
+
 		c.JSON(http.StatusOK, result)
 	}
 }
 
+// VoidOrder cancels an open order that has not been invoiced. Like item
+// voids it needs manager authorization, and every item still on the order
+// is voided with it so the day's Z report counts them.
+func VoidOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var request struct {
+			Reason          *string `json:"reason" validate:"required"`
+			ManagerEmail    *string `json:"manager_email"`
+			ManagerPassword *string `json:"manager_password"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := orderValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		managerId, ok := authorizeManager(ctx, c, request.ManagerEmail, request.ManagerPassword)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "voids must be authorized by a manager"})
+			return
+		}
+
+		var order models.Order
+		err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		if order.Status != nil && *order.Status != "OPEN" {
+			c.JSON(http.StatusConflict, gin.H{"error": "order is not open"})
+			return
+		}
+
+		if err := checkOrderOpen(ctx, order); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		count, err := getInvoiceCollection().CountDocuments(ctx, bson.M{"order_id": orderId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for invoice"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "order has already been invoiced"})
+			return
+		}
+
+		voidedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		status := "VOID"
+
+		result, err := getOrderCollection().UpdateOne(ctx,
+			bson.M{"order_id": orderId, "status": bson.M{"$in": bson.A{"OPEN", nil}}},
+			bson.M{"$set": bson.M{
+				"status":      status,
+				"void_reason": request.Reason,
+				"voided_by":   managerId,
+				"voided_at":   voidedAt,
+				"updated_at":  voidedAt,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order void failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "order is not open"})
+			return
+		}
+
+		var items []models.OrderItem
+		if err := findAll(ctx, getOrderItemCollection(), bson.M{"order_id": orderId, "voided": bson.M{"$ne": true}}, &items); err != nil {
+			log.Println("items of voided order were not loaded:", err)
+		}
+
+		for _, item := range items {
+			result, err := getOrderItemCollection().UpdateOne(ctx,
+				bson.M{"order_item_id": item.OrderItemID, "voided": bson.M{"$ne": true}},
+				bson.M{"$set": bson.M{
+					"voided":      true,
+					"void_reason": request.Reason,
+					"voided_by":   managerId,
+					"voided_at":   voidedAt,
+					"updated_at":  voidedAt,
+				}},
+			)
+			if err != nil {
+				log.Println("order item was not voided:", err)
+				continue
+			}
+			if result.MatchedCount == 0 {
+				continue
+			}
+
+			if err := restoreStock(ctx, item, "voided: "+*request.Reason, managerId); err != nil {
+				log.Println("stock was not restored:", err)
+			}
+			releasePortions(ctx, *item.FoodID, *item.Quantity)
+		}
+
+		releaseTable(ctx, orderId)
+		closeOrderGroup(ctx, order)
+
+		order.Status = &status
+		order.VoidReason = request.Reason
+		order.VoidedBy = managerId
+		order.VoidedAt = &voidedAt
+		order.UpdatedAt = voidedAt
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// SeatOrder records when the guests of an order sat down, and optionally
+// their party size
+func SeatOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var request struct {
+			NumberOfGuests *int `json:"number_of_guests" validate:"omitempty,min=1"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := orderValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var order models.Order
+		err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		if order.UnseatedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "guests have already left the table"})
+			return
+		}
+
+		seatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.SeatedAt = &seatedAt
+		order.UpdatedAt = seatedAt
+
+		updateObj := bson.M{"seated_at": order.SeatedAt, "updated_at": order.UpdatedAt}
+		if request.NumberOfGuests != nil {
+			order.NumberOfGuests = request.NumberOfGuests
+			updateObj["number_of_guests"] = order.NumberOfGuests
+		}
+
+		result, err := getOrderCollection().UpdateOne(ctx,
+			bson.M{"order_id": orderId, "unseated_at": nil},
+			bson.M{"$set": updateObj},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "guests have already left the table"})
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// UnseatOrder records when the guests of an order left the table
+func UnseatOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var order models.Order
+		err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		if order.SeatedAt == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "guests have not been seated"})
+			return
+		}
+
+		unseatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.UnseatedAt = &unseatedAt
+		order.UpdatedAt = unseatedAt
+
+		result, err := getOrderCollection().UpdateOne(ctx,
+			bson.M{"order_id": orderId, "seated_at": bson.M{"$ne": nil}, "unseated_at": nil},
+			bson.M{"$set": bson.M{"unseated_at": order.UnseatedAt, "updated_at": order.UpdatedAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "guests have already left the table"})
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
 // GetAllOrders returns all orders
 func GetAllOrders() gin.HandlerFunc {
 	return GetOrders()
@@ -2,6 +2,8 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -11,7 +13,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var orderItemValidate = validator.New()
@@ -81,6 +83,75 @@ func GetOrderItemsByOrderID() gin.HandlerFunc {
 	}
 }
 
+// addOrderItem adds an item to an open order at the food's current price,
+// unless the item brings its own, and sends it to the kitchen
+func addOrderItem(ctx context.Context, order models.Order, orderItem *models.OrderItem) (*mongo.InsertOneResult, error) {
+	var food models.Food
+	err := getFoodCollection().FindOne(ctx, bson.M{"food_id": orderItem.FoodID}).Decode(&food)
+	if err != nil {
+		return nil, errors.New("food item was not found")
+	}
+
+	orderItem.OrderID = order.OrderID
+	orderItem.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	orderItem.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	orderItem.ID = primitive.NewObjectID()
+	orderItem.OrderItemID = orderItem.ID.Hex()
+
+	// Set unit price from food price if not provided, applying any
+	// price rule in effect now and recording it on the item
+	if orderItem.UnitPrice == nil {
+		price, rule, err := currentFoodPrice(ctx, food, time.Now())
+		if err != nil {
+			return nil, errors.New("error occurred while pricing the food item")
+		}
+		orderItem.UnitPrice = &price
+		if rule != nil {
+			orderItem.PriceRuleID = &rule.PriceRuleID
+			orderItem.PriceRule = rule.Name
+		}
+	}
+
+	// Portions are claimed last so nothing above can leave them taken
+	if err := claimPortions(ctx, food, *orderItem.Quantity); err != nil {
+		return nil, err
+	}
+
+	result, err := getOrderItemCollection().InsertOne(ctx, orderItem)
+	if err != nil {
+		releasePortions(ctx, food.FoodID, *orderItem.Quantity)
+		return nil, errors.New("order item was not created")
+	}
+
+	// The item is taken even if its ingredients could not be counted out
+	if err := depleteStock(ctx, *orderItem); err != nil {
+		log.Println("stock was not depleted:", err)
+	}
+
+	// The first item sent to the kitchen means the table is ordering
+	if order.OrderType == nil || *order.OrderType == "DINE_IN" {
+		for _, table := range orderTables(ctx, order) {
+			if tableStatus(table) != "SEATED" {
+				continue
+			}
+			if err := setTableStatus(ctx, table, "ORDERING"); err != nil {
+				log.Println("table was not marked ordering:", err)
+			}
+		}
+	}
+
+	// The item is taken either way; a ticket that cannot be printed
+	// can be reprinted from the order
+	_, unrouted, err := printKitchenTickets(ctx, order, []models.OrderItem{*orderItem}, false)
+	if err != nil {
+		log.Println("kitchen ticket was not printed:", err)
+	} else if len(unrouted) > 0 {
+		log.Println("no kitchen printer for stations:", unrouted)
+	}
+
+	return result, nil
+}
+
 // CreateOrderItem creates a new order item
 func CreateOrderItem() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -107,27 +178,19 @@ func CreateOrderItem() gin.HandlerFunc {
 			return
 		}
 
-		// Verify food exists
-		var food models.Food
-		err = getFoodCollection().FindOne(ctx, bson.M{"food_id": orderItem.FoodID}).Decode(&food)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "food item was not found"})
+		if order.Status != nil && *order.Status != "OPEN" {
+			c.JSON(http.StatusConflict, gin.H{"error": "order is not open"})
 			return
 		}
 
-		orderItem.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		orderItem.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		orderItem.ID = primitive.NewObjectID()
-		orderItem.OrderItemID = orderItem.ID.Hex()
-
-		// Set unit price from food price if not provided
-		if orderItem.UnitPrice == nil {
-			orderItem.UnitPrice = food.Price
+		if err := checkOrderOpen(ctx, order); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
 		}
 
-		result, insertErr := getOrderItemCollection().InsertOne(ctx, orderItem)
-		if insertErr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "order item was not created"})
+		result, err := addOrderItem(ctx, order, &orderItem)
+		if err != nil {
+			c.JSON(orderItemStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
@@ -149,47 +212,265 @@ func UpdateOrderItem() gin.HandlerFunc {
 			return
 		}
 
+		var current models.OrderItem
+		err := getOrderItemCollection().FindOne(ctx, bson.M{"order_item_id": orderItemId}).Decode(&current)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
+
+		if current.Voided {
+			c.JSON(http.StatusConflict, gin.H{"error": "order item has been voided"})
+			return
+		}
+
+		// Items of a closed or invoiced check are fixed, so this is
+		// checked before any portions or stock move
+		var order models.Order
+		err = getOrderCollection().FindOne(ctx, bson.M{"order_id": current.OrderID}).Decode(&order)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		if order.Status != nil && *order.Status != "OPEN" {
+			c.JSON(http.StatusConflict, gin.H{"error": "order is not open"})
+			return
+		}
+
+		if err := checkOrderOpen(ctx, order); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		count, err := getInvoiceCollection().CountDocuments(ctx, bson.M{"order_id": order.OrderID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for invoice"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "order has already been invoiced"})
+			return
+		}
+
 		var updateObj primitive.D
 
+		// Quantities are checked before any portions are claimed or
+		// given back
 		if orderItem.Quantity != nil {
+			validationErr := orderItemValidate.StructPartial(orderItem, "Quantity")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
 			updateObj = append(updateObj, bson.E{Key: "quantity", Value: orderItem.Quantity})
 		}
 
+		// A manual price replaces whatever price rule set the original one
 		if orderItem.UnitPrice != nil {
+			validationErr := orderItemValidate.StructPartial(orderItem, "UnitPrice")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
 			updateObj = append(updateObj, bson.E{Key: "unit_price", Value: orderItem.UnitPrice})
+			updateObj = append(updateObj, bson.E{Key: "price_rule_id", Value: nil})
+			updateObj = append(updateObj, bson.E{Key: "price_rule", Value: nil})
 		}
 
+		var food models.Food
 		if orderItem.FoodID != nil {
-			var food models.Food
 			err := getFoodCollection().FindOne(ctx, bson.M{"food_id": orderItem.FoodID}).Decode(&food)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "food item was not found"})
 				return
 			}
+			if !foodAvailable(food) {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s: %s", errFoodSoldOut, *food.Name)})
+				return
+			}
 			updateObj = append(updateObj, bson.E{Key: "food_id", Value: orderItem.FoodID})
 		}
 
 		orderItem.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: orderItem.UpdatedAt})
 
-		upsert := true
-		filter := bson.M{"order_item_id": orderItemId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		// A new quantity or food takes portions before the item changes:
+		// the extra quantity of the same food, or all of it from a new one.
+		// What is no longer needed goes back once the change is saved.
+		claimedFood, claimed := "", 0
+		releaseFood, released := "", 0
+		if orderItem.Quantity != nil || orderItem.FoodID != nil {
+			quantity := *current.Quantity
+			if orderItem.Quantity != nil {
+				quantity = *orderItem.Quantity
+			}
+			foodId := *current.FoodID
+			if orderItem.FoodID != nil {
+				foodId = *orderItem.FoodID
+			}
+
+			claimedFood, claimed, releaseFood, released = portionChanges(current, foodId, quantity)
+
+			if claimed > 0 && food.FoodID == "" {
+				err := getFoodCollection().FindOne(ctx, bson.M{"food_id": claimedFood}).Decode(&food)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "food item was not found"})
+					return
+				}
+			}
+
+			if claimed > 0 {
+				if err := claimPortions(ctx, food, claimed); err != nil {
+					c.JSON(orderItemStatus(err), gin.H{"error": err.Error()})
+					return
+				}
+			}
 		}
 
+		filter := bson.M{"order_item_id": orderItemId}
+
 		result, err := getOrderItemCollection().UpdateOne(
 			ctx,
 			filter,
 			bson.D{{Key: "$set", Value: updateObj}},
-			&opt,
 		)
 
-		if err != nil {
+		if err != nil || result.MatchedCount == 0 {
+			if claimed > 0 {
+				releasePortions(ctx, claimedFood, claimed)
+			}
+			if err == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "order item update failed"})
 			return
 		}
 
+		if released > 0 {
+			releasePortions(ctx, releaseFood, released)
+		}
+
+		// A new quantity or food uses different stock; only the difference
+		// moves, so one retry is safe
+		if orderItem.Quantity != nil || orderItem.FoodID != nil {
+			var updated models.OrderItem
+			if getOrderItemCollection().FindOne(ctx, filter).Decode(&updated) == nil {
+				err := resyncStock(ctx, updated, "order item changed", c.GetString("uid"))
+				if err != nil {
+					err = resyncStock(ctx, updated, "order item changed", c.GetString("uid"))
+				}
+				if err != nil {
+					log.Println("stock was not resynced:", err)
+				}
+			}
+		}
+
 		c.JSON(http.StatusOK, result)
 	}
 }
+
+// VoidOrderItem takes an item off an open order. Voids need manager
+// authorization and keep the item, with who voided it and why, for the
+// day's Z report.
+func VoidOrderItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderItemId := c.Param("orderItem_id")
+
+		var request struct {
+			Reason          *string `json:"reason" validate:"required"`
+			ManagerEmail    *string `json:"manager_email"`
+			ManagerPassword *string `json:"manager_password"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := orderItemValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		managerId, ok := authorizeManager(ctx, c, request.ManagerEmail, request.ManagerPassword)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "voids must be authorized by a manager"})
+			return
+		}
+
+		var orderItem models.OrderItem
+		err := getOrderItemCollection().FindOne(ctx, bson.M{"order_item_id": orderItemId}).Decode(&orderItem)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
+
+		var order models.Order
+		err = getOrderCollection().FindOne(ctx, bson.M{"order_id": orderItem.OrderID}).Decode(&order)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		if order.Status != nil && *order.Status != "OPEN" {
+			c.JSON(http.StatusConflict, gin.H{"error": "order is not open"})
+			return
+		}
+
+		if err := checkOrderOpen(ctx, order); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		count, err := getInvoiceCollection().CountDocuments(ctx, bson.M{"order_id": order.OrderID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for invoice"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "order has already been invoiced"})
+			return
+		}
+
+		voidedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		result, err := getOrderItemCollection().UpdateOne(ctx,
+			bson.M{"order_item_id": orderItemId, "voided": bson.M{"$ne": true}},
+			bson.M{"$set": bson.M{
+				"voided":      true,
+				"void_reason": request.Reason,
+				"voided_by":   managerId,
+				"voided_at":   voidedAt,
+				"updated_at":  voidedAt,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order item void failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "order item has already been voided"})
+			return
+		}
+
+		if err := restoreStock(ctx, orderItem, "voided: "+*request.Reason, managerId); err != nil {
+			log.Println("stock was not restored:", err)
+		}
+		releasePortions(ctx, *orderItem.FoodID, *orderItem.Quantity)
+
+		orderItem.Voided = true
+		orderItem.VoidReason = request.Reason
+		orderItem.VoidedBy = managerId
+		orderItem.VoidedAt = &voidedAt
+		orderItem.UpdatedAt = voidedAt
+
+		c.JSON(http.StatusOK, orderItem)
+	}
+}
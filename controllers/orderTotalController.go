@@ -0,0 +1,320 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// orderTotals is the priced breakdown of an order
+type orderTotals struct {
+	OrderID       string                `json:"order_id"`
+	SubTotal      float64               `json:"sub_total"`
+	Discounts     []models.DiscountLine `json:"discounts"`
+	DiscountTotal float64               `json:"discount_total"`
+	TaxInclusive  bool                  `json:"tax_inclusive"`
+	Taxes         []models.TaxLine      `json:"taxes"`
+	TaxTotal      float64               `json:"tax_total"`
+	Covers        int                   `json:"covers"`
+	ServiceRate   float64               `json:"service_rate"`
+	ServiceCharge float64               `json:"service_charge"`
+	Total         float64               `json:"total"`
+}
+
+// loadPricedLines loads the order items of an order together with the
+// menu category and tax category of each food so discounts and taxes can
+// be evaluated
+func loadPricedLines(ctx context.Context, orderId string) ([]helpers.PricedLine, error) {
+	var orderItems []models.OrderItem
+	cursor, err := getOrderItemCollection().Find(ctx, bson.M{"order_id": orderId, "voided": bson.M{"$ne": true}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &orderItems); err != nil {
+		return nil, err
+	}
+
+	categories := map[string]string{}
+	taxCategories := map[string]string{}
+	var lines []helpers.PricedLine
+	for _, item := range orderItems {
+		if item.FoodID == nil || item.Quantity == nil || item.UnitPrice == nil {
+			continue
+		}
+
+		category, ok := categories[*item.FoodID]
+		if !ok {
+			var food models.Food
+			if err := getFoodCollection().FindOne(ctx, bson.M{"food_id": item.FoodID}).Decode(&food); err == nil {
+				if food.TaxCategoryID != nil {
+					taxCategories[*item.FoodID] = *food.TaxCategoryID
+				}
+				if food.MenuID != nil {
+					var menu models.Menu
+					menuFilter := bson.M{"$or": []bson.M{
+						{"menu_id": food.MenuID},
+						{"menuid": food.MenuID},
+					}}
+					if err := getMenuCollection().FindOne(ctx, menuFilter).Decode(&menu); err == nil {
+						category = menu.Category
+					}
+				}
+			}
+			categories[*item.FoodID] = category
+		}
+
+		lines = append(lines, helpers.PricedLine{
+			FoodID:        *item.FoodID,
+			Category:      category,
+			TaxCategoryID: taxCategories[*item.FoodID],
+			Quantity:      *item.Quantity,
+			UnitPrice:     *item.UnitPrice,
+		})
+	}
+
+	return lines, nil
+}
+
+// calculateOrderTotals prices an order including every applied discount,
+// tax and service charge. Item and category discounts are taken first, order discounts
+// then apply to what is left. Discounts are spread over the lines they
+// cover so each tax category is charged on its discounted amount.
+func calculateOrderTotals(ctx context.Context, orderId string) (orderTotals, error) {
+	totals := orderTotals{
+		OrderID:      orderId,
+		Discounts:    []models.DiscountLine{},
+		TaxInclusive: helpers.TaxInclusivePricing(),
+		Taxes:        []models.TaxLine{},
+	}
+
+	var order models.Order
+	if err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+		return totals, err
+	}
+
+	lines, err := loadPricedLines(ctx, orderId)
+	if err != nil {
+		return totals, err
+	}
+
+	for _, line := range lines {
+		totals.SubTotal += float64(line.Quantity) * line.UnitPrice
+	}
+	totals.SubTotal = helpers.RoundMoney(totals.SubTotal)
+
+	var applied []models.OrderDiscount
+	cursor, err := getOrderDiscountCollection().Find(ctx, bson.M{"order_id": orderId})
+	if err != nil {
+		return totals, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &applied); err != nil {
+		return totals, err
+	}
+
+	var discounts []models.Discount
+	var coupons []*string
+	for _, od := range applied {
+		var discount models.Discount
+		if err := getDiscountCollection().FindOne(ctx, bson.M{"discount_id": od.DiscountID}).Decode(&discount); err != nil {
+			continue
+		}
+		discounts = append(discounts, discount)
+		coupons = append(coupons, od.CouponCode)
+	}
+
+	lineAmounts, discountLines := spreadDiscounts(lines, discounts, coupons)
+	totals.Discounts = append(totals.Discounts, discountLines...)
+	for _, line := range totals.Discounts {
+		totals.DiscountTotal += line.Amount
+	}
+
+	if totals.DiscountTotal > totals.SubTotal {
+		totals.DiscountTotal = totals.SubTotal
+	}
+	totals.DiscountTotal = helpers.RoundMoney(totals.DiscountTotal)
+
+	taxable, err := taxableLines(ctx, order, lines, lineAmounts)
+	if err != nil {
+		return totals, err
+	}
+	totals.Taxes, totals.TaxTotal = helpers.CalculateTaxes(taxable, totals.TaxInclusive, helpers.TaxRoundPerLine())
+
+	// Covers decide whether the party pays a service charge
+	if order.TableID != nil {
+		var table models.Table
+		err := getTableCollection().FindOne(ctx, bson.M{"table_id": order.TableID}).Decode(&table)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return totals, err
+		}
+		if table.NumberOfGuests != nil {
+			totals.Covers = *table.NumberOfGuests
+		}
+	}
+
+	// The party seated on the order counts over the table's size
+	if order.NumberOfGuests != nil {
+		totals.Covers = *order.NumberOfGuests
+	}
+
+	totals.finish(helpers.ServiceChargeMinGuests(), helpers.ServiceChargeRate())
+	return totals, nil
+}
+
+// spreadDiscounts works out the discount lines of an order and what is
+// left of each line after them. Item and category discounts are taken
+// first, order discounts then apply to what is left. coupons holds the
+// coupon code each discount was applied with.
+func spreadDiscounts(lines []helpers.PricedLine, discounts []models.Discount, coupons []*string) ([]float64, []models.DiscountLine) {
+	lineAmounts := make([]float64, len(lines))
+	for i, line := range lines {
+		lineAmounts[i] = float64(line.Quantity) * line.UnitPrice
+	}
+
+	var itemDiscounts, orderDiscounts []models.DiscountLine
+	var orderLevel []models.Discount
+	var orderCoupons []*string
+	for i, discount := range discounts {
+		if *discount.Scope == "ORDER" {
+			orderLevel = append(orderLevel, discount)
+			orderCoupons = append(orderCoupons, coupons[i])
+			continue
+		}
+
+		amount := helpers.CalculateDiscount(discount, lines)
+		weights := make([]float64, len(lines))
+		for j, line := range lines {
+			if helpers.DiscountApplies(discount, line) {
+				weights[j] = lineAmounts[j]
+			}
+		}
+		for j, share := range helpers.AllocateAmount(amount, weights) {
+			lineAmounts[j] -= share
+		}
+
+		itemDiscounts = append(itemDiscounts, models.DiscountLine{
+			DiscountID: discount.DiscountID,
+			Name:       *discount.Name,
+			CouponCode: coupons[i],
+			Amount:     amount,
+		})
+	}
+
+	for i, discount := range orderLevel {
+		var remaining float64
+		for _, amount := range lineAmounts {
+			remaining += amount
+		}
+
+		amount := helpers.CalculateDiscount(discount, []helpers.PricedLine{{Quantity: 1, UnitPrice: remaining}})
+		for j, share := range helpers.AllocateAmount(amount, append([]float64(nil), lineAmounts...)) {
+			lineAmounts[j] -= share
+		}
+
+		orderDiscounts = append(orderDiscounts, models.DiscountLine{
+			DiscountID: discount.DiscountID,
+			Name:       *discount.Name,
+			CouponCode: orderCoupons[i],
+			Amount:     amount,
+		})
+	}
+
+	return lineAmounts, append(itemDiscounts, orderDiscounts...)
+}
+
+// finish adds the service charge when the party is larger than minGuests
+// and works out the total. The service charge is taken on the discounted
+// amount before tax.
+func (t *orderTotals) finish(minGuests int, serviceRate float64) {
+	t.ServiceRate = 0
+	t.ServiceCharge = 0
+	if t.Covers > minGuests {
+		base := t.SubTotal - t.DiscountTotal
+		if t.TaxInclusive {
+			base -= t.TaxTotal
+		}
+		t.ServiceRate = serviceRate
+		t.ServiceCharge = helpers.RoundMoney(base * t.ServiceRate / 100)
+	}
+
+	t.Total = t.SubTotal - t.DiscountTotal + t.ServiceCharge
+	if !t.TaxInclusive {
+		t.Total += t.TaxTotal
+	}
+	t.Total = helpers.RoundMoney(t.Total)
+}
+
+// taxableLines pairs each discounted line amount with the rate of its tax
+// category, using the takeaway rate for takeaway orders
+func taxableLines(ctx context.Context, order models.Order, lines []helpers.PricedLine, amounts []float64) ([]helpers.TaxableLine, error) {
+	takeaway := order.OrderType != nil && *order.OrderType == "TAKEAWAY"
+	categories := map[string]models.TaxCategory{}
+
+	var taxable []helpers.TaxableLine
+	for i, line := range lines {
+		if line.TaxCategoryID == "" {
+			continue
+		}
+
+		category, ok := categories[line.TaxCategoryID]
+		if !ok {
+			err := getTaxCategoryCollection().FindOne(ctx, bson.M{"tax_category_id": line.TaxCategoryID}).Decode(&category)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return nil, err
+			}
+			categories[line.TaxCategoryID] = category
+		}
+
+		if category.Rate == nil {
+			continue
+		}
+
+		rate := *category.Rate
+		if takeaway && category.TakeawayRate != nil {
+			rate = *category.TakeawayRate
+		}
+
+		taxable = append(taxable, helpers.TaxableLine{
+			TaxCategoryID: category.TaxCategoryID,
+			Name:          *category.Name,
+			Rate:          rate,
+			Amount:        amounts[i],
+		})
+	}
+
+	return taxable, nil
+}
+
+// GetOrderTotal returns the priced breakdown of an order
+func GetOrderTotal() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var order models.Order
+		err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		totals, err := calculateOrderTotals(ctx, orderId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while calculating order totals"})
+			return
+		}
+
+		c.JSON(http.StatusOK, totals)
+	}
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+func TestSpreadDiscounts(t *testing.T) {
+	lines := []helpers.PricedLine{
+		{FoodID: "steak", Category: "MAINS", Quantity: 1, UnitPrice: 30},
+		{FoodID: "wine", Category: "DRINKS", Quantity: 2, UnitPrice: 10},
+	}
+
+	percentage, fixed := "PERCENTAGE", "FIXED"
+	category, order := "CATEGORY", "ORDER"
+	happyHour, welcome, tenth, drinks := "Happy hour", "Welcome", "Tenth off", "DRINKS"
+	half, eight, ten := 50.0, 8.0, 10.0
+	code := "WELCOME"
+
+	halfOffDrinks := models.Discount{DiscountID: "happy-hour", Name: &happyHour, Type: &percentage, Scope: &category, Category: &drinks, Value: &half}
+	eightOff := models.Discount{DiscountID: "welcome", Name: &welcome, Type: &fixed, Scope: &order, Value: &eight}
+	tenthOff := models.Discount{DiscountID: "tenth", Name: &tenth, Type: &percentage, Scope: &order, Value: &ten}
+
+	tests := []struct {
+		name        string
+		discounts   []models.Discount
+		coupons     []*string
+		wantAmounts []float64
+		wantLines   []float64
+	}{
+		{
+			name:        "no discounts",
+			wantAmounts: []float64{30, 20},
+		},
+		{
+			name:        "category discount stays on its lines",
+			discounts:   []models.Discount{halfOffDrinks},
+			coupons:     []*string{nil},
+			wantAmounts: []float64{30, 10},
+			wantLines:   []float64{10},
+		},
+		{
+			name:        "order discount is spread by what is left",
+			discounts:   []models.Discount{eightOff},
+			coupons:     []*string{&code},
+			wantAmounts: []float64{25.2, 16.8},
+			wantLines:   []float64{8},
+		},
+		{
+			name:        "order discounts come after item discounts",
+			discounts:   []models.Discount{tenthOff, halfOffDrinks},
+			coupons:     []*string{nil, nil},
+			wantAmounts: []float64{27, 9},
+			wantLines:   []float64{10, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amounts, discountLines := spreadDiscounts(lines, tt.discounts, tt.coupons)
+			for i, want := range tt.wantAmounts {
+				if helpers.RoundMoney(amounts[i]) != want {
+					t.Errorf("line amounts = %v, want %v", amounts, tt.wantAmounts)
+					break
+				}
+			}
+			if len(discountLines) != len(tt.wantLines) {
+				t.Fatalf("got %d discount lines, want %d", len(discountLines), len(tt.wantLines))
+			}
+			for i, want := range tt.wantLines {
+				if discountLines[i].Amount != want {
+					t.Errorf("discount line %d = %v, want %v", i, discountLines[i].Amount, want)
+				}
+			}
+		})
+	}
+
+	_, discountLines := spreadDiscounts(lines, []models.Discount{eightOff}, []*string{&code})
+	if discountLines[0].CouponCode == nil || *discountLines[0].CouponCode != code {
+		t.Errorf("coupon code = %v, want %s", discountLines[0].CouponCode, code)
+	}
+}
+
+func TestOrderTotalsFinish(t *testing.T) {
+	tests := []struct {
+		name        string
+		totals      orderTotals
+		wantService float64
+		wantTotal   float64
+	}{
+		{
+			name:      "small party, tax added",
+			totals:    orderTotals{SubTotal: 100, DiscountTotal: 10, TaxTotal: 12.6, Covers: 4},
+			wantTotal: 102.6,
+		},
+		{
+			name:        "large party pays the service charge before tax",
+			totals:      orderTotals{SubTotal: 100, DiscountTotal: 10, TaxTotal: 12.6, Covers: 8},
+			wantService: 9,
+			wantTotal:   111.6,
+		},
+		{
+			name:        "tax inclusive prices leave the tax out of the base",
+			totals:      orderTotals{SubTotal: 114, TaxTotal: 14, TaxInclusive: true, Covers: 8},
+			wantService: 10,
+			wantTotal:   124,
+		},
+		{
+			name:      "exactly the minimum party pays none",
+			totals:    orderTotals{SubTotal: 50, Covers: 6},
+			wantTotal: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals := tt.totals
+			totals.finish(6, 10)
+			if totals.ServiceCharge != tt.wantService {
+				t.Errorf("service charge = %v, want %v", totals.ServiceCharge, tt.wantService)
+			}
+			if totals.Total != tt.wantTotal {
+				t.Errorf("total = %v, want %v", totals.Total, tt.wantTotal)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/ali-adel-nour/restaurant-management/notify"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// unpaidStatuses are the payment statuses of invoices with money still owed
+var unpaidStatuses = bson.A{"PENDING", "PARTIALLY_PAID", "OVERDUE"}
+
+// markOverdueInvoices moves unpaid invoices past their due date to OVERDUE
+func markOverdueInvoices(ctx context.Context, now time.Time) (int64, error) {
+	result, err := getInvoiceCollection().UpdateMany(ctx,
+		bson.M{
+			"payment_status": bson.M{"$in": bson.A{"PENDING", "PARTIALLY_PAID"}},
+			"payment_due":    bson.M{"$lt": now},
+		},
+		bson.M{"$set": bson.M{"payment_status": "OVERDUE", "updated_at": now}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// sendReminder notifies a house account about an unpaid invoice and
+// records when it was reminded
+func sendReminder(ctx context.Context, invoice models.Invoice, now time.Time) error {
+	if invoice.AccountID == nil {
+		return fmt.Errorf("invoice %s is not charged to a house account", invoice.InvoiceNumber)
+	}
+
+	var account models.HouseAccount
+	if err := getHouseAccountCollection().FindOne(ctx, bson.M{"account_id": invoice.AccountID}).Decode(&account); err != nil {
+		return err
+	}
+
+	if account.Email == nil || *account.Email == "" {
+		return fmt.Errorf("house account %s has no email address", account.AccountID)
+	}
+
+	due := ""
+	if invoice.PaymentDue != nil {
+		due = invoice.PaymentDue.In(helpers.RestaurantLocation()).Format("2006-01-02")
+	}
+
+	err := notify.Send(ctx, notify.Message{
+		To:      *account.Email,
+		Subject: fmt.Sprintf("Payment reminder for invoice %s", invoice.InvoiceNumber),
+		Body: fmt.Sprintf("Dear %s,\n\ninvoice %s from %s has a balance of %.2f, due on %s.\nPlease arrange payment at your earliest convenience.\n",
+			*account.Name, invoice.InvoiceNumber, helpers.RestaurantName(), balanceDue(invoice), due),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = getInvoiceCollection().UpdateOne(ctx,
+		bson.M{"invoice_id": invoice.InvoiceID},
+		bson.M{
+			"$set": bson.M{"last_reminder": now},
+			"$inc": bson.M{"reminder_count": 1},
+		},
+	)
+	return err
+}
+
+// sendOverdueReminders reminds house accounts of overdue invoices that
+// have not been reminded within the reminder interval
+func sendOverdueReminders(ctx context.Context, now time.Time) (int, error) {
+	cursor, err := getInvoiceCollection().Find(ctx, bson.M{
+		"payment_status": "OVERDUE",
+		"account_id":     bson.M{"$ne": nil},
+		"$or": bson.A{
+			bson.M{"last_reminder": nil},
+			bson.M{"last_reminder": bson.M{"$lt": now.Add(-helpers.ReminderInterval())}},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var invoices []models.Invoice
+	if err = cursor.All(ctx, &invoices); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, invoice := range invoices {
+		if err := sendReminder(ctx, invoice, now); err != nil {
+			log.Println("overdue reminder was not sent:", err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// StartOverdueScheduler checks for overdue invoices and sends reminders
+// every interval until ctx is cancelled
+func StartOverdueScheduler(ctx context.Context, interval time.Duration) {
+	run := func() {
+		runCtx, cancel := context.WithTimeout(ctx, 100*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		marked, err := markOverdueInvoices(runCtx, now)
+		if err != nil {
+			log.Println("error occurred while marking overdue invoices:", err)
+			return
+		}
+
+		sent, err := sendOverdueReminders(runCtx, now)
+		if err != nil {
+			log.Println("error occurred while sending overdue reminders:", err)
+			return
+		}
+
+		if marked > 0 || sent > 0 {
+			log.Printf("marked %d invoices overdue, sent %d reminders", marked, sent)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		run()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+}
+
+// SendInvoiceReminder reminds the house account of an unpaid invoice now,
+// regardless of when it was last reminded
+func SendInvoiceReminder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		var invoice models.Invoice
+		err := getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		if balanceDue(invoice) <= 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "invoice has no balance due"})
+			return
+		}
+
+		if err := sendReminder(ctx, invoice, time.Now()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"reminded": true})
+	}
+}
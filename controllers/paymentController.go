@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var paymentValidate = validator.New()
+
+var (
+	errInvoicePaid     = errors.New("invoice is already paid")
+	errOverpayment     = errors.New("payment exceeds the balance due")
+	errPaymentConflict = errors.New("invoice changed while the payment was recorded, please retry")
+)
+
+// settledStatuses are the payment statuses of an invoice that has been
+// paid in full; refunds made after that do not put a balance back
+var settledStatuses = []string{"PAID", "PARTIALLY_REFUNDED", "REFUNDED"}
+
+// invoiceSettled reports whether an invoice has been paid in full
+func invoiceSettled(invoice models.Invoice) bool {
+	if invoice.PaymentStatus == nil {
+		return false
+	}
+	for _, status := range settledStatuses {
+		if *invoice.PaymentStatus == status {
+			return true
+		}
+	}
+	return false
+}
+
+// owed returns what is left to pay on an invoice that has not been
+// settled: money refunded before then is owed again
+func owed(invoice models.Invoice, amountPaid, refundedTotal float64) float64 {
+	return helpers.RoundMoney(invoice.Total - (amountPaid - refundedTotal))
+}
+
+// balanceDue returns what is left to pay on an invoice
+func balanceDue(invoice models.Invoice) float64 {
+	if invoiceSettled(invoice) {
+		return 0
+	}
+	return owed(invoice, invoice.AmountPaid, invoice.RefundedTotal)
+}
+
+// paymentStatus works out an invoice's payment status from the amounts
+// paid and refunded. While a balance is due the invoice stays unpaid
+// (PENDING, PARTIALLY_PAID or OVERDUE) whatever was refunded; once it is
+// settled it is PAID, PARTIALLY_REFUNDED or REFUNDED.
+func paymentStatus(invoice models.Invoice, amountPaid, refundedTotal float64) string {
+	if !invoiceSettled(invoice) && owed(invoice, amountPaid, refundedTotal) > 0 {
+		switch {
+		case invoice.PaymentStatus != nil && *invoice.PaymentStatus == "OVERDUE":
+			return "OVERDUE"
+		case amountPaid > 0:
+			return "PARTIALLY_PAID"
+		default:
+			return "PENDING"
+		}
+	}
+
+	switch {
+	case refundedTotal <= 0:
+		return "PAID"
+	case refundedTotal >= amountPaid:
+		return "REFUNDED"
+	default:
+		return "PARTIALLY_REFUNDED"
+	}
+}
+
+// applyPayment records a payment against an invoice and updates the
+// invoice's paid amount, balance, status and payment method. Cash may be
+// over-tendered and the difference is returned as change; other tenders
+// cannot exceed the balance. The invoice update is guarded by the amounts
+// already paid and refunded so concurrent payments cannot both succeed on a
+// stale balance and a refund made meanwhile is not left out of the status.
+func applyPayment(ctx context.Context, invoice models.Invoice, payment models.Payment) (models.Payment, error) {
+	if invoice.PaymentStatus != nil && *invoice.PaymentStatus == "PAID" {
+		return payment, errInvoicePaid
+	}
+
+	balance := balanceDue(invoice)
+	if balance <= 0 {
+		return payment, errInvoicePaid
+	}
+
+	method := *payment.Method
+	if method == "CASH" {
+		tendered := payment.Tendered
+		if tendered == nil {
+			tendered = payment.Amount
+		}
+		if tendered == nil {
+			tendered = &balance
+		}
+
+		amount := *tendered
+		if payment.Amount != nil && *payment.Amount < amount {
+			amount = *payment.Amount
+		}
+		if amount > balance {
+			amount = balance
+		}
+
+		payment.Amount = &amount
+		payment.Tendered = tendered
+		payment.ChangeDue = helpers.RoundMoney(*tendered - amount)
+	} else {
+		if payment.Amount == nil {
+			payment.Amount = &balance
+		}
+		if helpers.RoundMoney(*payment.Amount) > balance {
+			return payment, errOverpayment
+		}
+	}
+
+	amountPaid := helpers.RoundMoney(invoice.AmountPaid + *payment.Amount)
+	newBalance := helpers.RoundMoney(balance - *payment.Amount)
+
+	status := paymentStatus(invoice, amountPaid, invoice.RefundedTotal)
+
+	paymentMethod := method
+	if invoice.PaymentMethod != nil && *invoice.PaymentMethod != "" && *invoice.PaymentMethod != method && invoice.AmountPaid > 0 {
+		paymentMethod = "MIXED"
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := getInvoiceCollection().UpdateOne(ctx,
+		bson.M{
+			"invoice_id":     invoice.InvoiceID,
+			"amount_paid":    amountFilter(invoice.AmountPaid),
+			"refunded_total": amountFilter(invoice.RefundedTotal),
+			"payment_status": bson.M{"$ne": "PAID"},
+		},
+		bson.M{"$set": bson.M{
+			"amount_paid":    amountPaid,
+			"balance_due":    newBalance,
+			"payment_status": status,
+			"payment_method": paymentMethod,
+			"updated_at":     updatedAt,
+		}},
+	)
+	if err != nil {
+		return payment, err
+	}
+
+	if result.MatchedCount == 0 {
+		return payment, errPaymentConflict
+	}
+
+	payment.InvoiceID = invoice.InvoiceID
+	payment.CreatedAt = updatedAt
+	payment.ID = primitive.NewObjectID()
+	payment.PaymentID = payment.ID.Hex()
+
+	_, insertErr := getPaymentCollection().InsertOne(ctx, payment)
+	if insertErr != nil {
+		// Put the invoice back the way it was
+		getInvoiceCollection().UpdateOne(ctx,
+			bson.M{"invoice_id": invoice.InvoiceID, "amount_paid": amountPaid},
+			bson.M{"$set": bson.M{
+				"amount_paid":    invoice.AmountPaid,
+				"balance_due":    balance,
+				"payment_status": invoice.PaymentStatus,
+				"payment_method": invoice.PaymentMethod,
+			}},
+		)
+		return payment, insertErr
+	}
+
+	if newBalance <= 0 {
+		releaseTable(ctx, invoice.OrderID)
+	}
+
+	return payment, nil
+}
+
+// GetInvoicePayments returns the payments made against an invoice
+func GetInvoicePayments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		var payments []models.Payment
+		cursor, err := getPaymentCollection().Find(ctx, bson.M{"invoice_id": invoiceId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching payments"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &payments); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, payments)
+	}
+}
+
+// CreatePayment records a payment against an invoice and returns it along
+// with the remaining balance
+func CreatePayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		var payment models.Payment
+		if err := c.BindJSON(&payment); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := paymentValidate.Struct(payment)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var invoice models.Invoice
+		err := getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		// These are worked out or recorded by the server, never taken from
+		// the client
+		payment.ReceivedBy = c.GetString("uid")
+		payment.ChangeDue = 0
+		payment.Refunded = 0
+		payment.IntentID = nil
+
+		if *payment.Method == "CASH" {
+			payment.DrawerSessionID, err = cashDrawerFor(ctx, payment.DrawerSessionID)
+			if err != nil {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+		} else {
+			payment.DrawerSessionID = nil
+		}
+
+		payment, err = applyPayment(ctx, invoice, payment)
+		switch {
+		case errors.Is(err, errInvoicePaid), errors.Is(err, errPaymentConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, errOverpayment):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "payment was not recorded"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"payment":     payment,
+			"change_due":  payment.ChangeDue,
+			"balance_due": helpers.RoundMoney(balanceDue(invoice) - *payment.Amount),
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+func TestPaymentStatus(t *testing.T) {
+	overdue := "OVERDUE"
+	paid := "PAID"
+	partiallyRefunded := "PARTIALLY_REFUNDED"
+
+	tests := []struct {
+		name          string
+		status        *string
+		amountPaid    float64
+		refundedTotal float64
+		want          string
+	}{
+		{"nothing paid", nil, 0, 0, "PENDING"},
+		{"part paid", nil, 40, 0, "PARTIALLY_PAID"},
+		{"part paid and overdue", &overdue, 40, 0, "OVERDUE"},
+		{"part paid and all of it refunded", nil, 40, 40, "PARTIALLY_PAID"},
+		{"overdue and refunded", &overdue, 40, 40, "OVERDUE"},
+		{"paid in full", nil, 100, 0, "PAID"},
+		{"paid the total after a refund", nil, 100, 30, "PARTIALLY_PAID"},
+		{"paid back what was refunded", nil, 130, 30, "PARTIALLY_REFUNDED"},
+		{"refunded after it was paid off", &partiallyRefunded, 100, 30, "PARTIALLY_REFUNDED"},
+		{"paid and all of it refunded", &paid, 100, 100, "REFUNDED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := models.Invoice{Total: 100, PaymentStatus: tt.status}
+			if got := paymentStatus(invoice, tt.amountPaid, tt.refundedTotal); got != tt.want {
+				t.Errorf("paymentStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBalanceDue(t *testing.T) {
+	paid := "PAID"
+	partiallyPaid := "PARTIALLY_PAID"
+	partiallyRefunded := "PARTIALLY_REFUNDED"
+
+	tests := []struct {
+		name          string
+		status        *string
+		amountPaid    float64
+		refundedTotal float64
+		want          float64
+	}{
+		{"nothing paid", nil, 0, 0, 100},
+		{"part paid", &partiallyPaid, 40, 0, 60},
+		{"part paid and part refunded", &partiallyPaid, 40, 15, 75},
+		{"part paid and all of it refunded", &partiallyPaid, 40, 40, 100},
+		{"paid in full", &paid, 100, 0, 0},
+		{"refunded after it was paid off", &partiallyRefunded, 100, 30, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := models.Invoice{Total: 100, PaymentStatus: tt.status, AmountPaid: tt.amountPaid, RefundedTotal: tt.refundedTotal}
+			if got := balanceDue(invoice); got != tt.want {
+				t.Errorf("balanceDue() = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/ali-adel-nour/restaurant-management/payments"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// intentNeedsReconciliation marks an intent whose money was captured but
+// neither recorded on the invoice nor refunded
+const intentNeedsReconciliation = "NEEDS_RECONCILIATION"
+
+// captureAmount returns the amount to capture on an intent: the amount
+// asked for, or all of the authorization when none is given. More than
+// was authorized, or nothing at all, is refused.
+func captureAmount(intent models.PaymentIntent, requested *float64) (float64, error) {
+	if requested == nil {
+		return intent.Amount, nil
+	}
+
+	amount := helpers.RoundMoney(*requested)
+	if amount <= 0 || amount > intent.Amount {
+		return 0, fmt.Errorf("capture must be more than 0 and at most the %.2f authorized", intent.Amount)
+	}
+	return amount, nil
+}
+
+// recordCapture marks an authorized intent captured and records the card
+// payment on its invoice. The status guard makes it safe to call from both
+// the capture endpoint and the provider webhook: only the first call
+// records a payment. When the payment cannot be recorded the capture is
+// reversed, so the provider never holds money the invoice does not show.
+func recordCapture(ctx context.Context, intent models.PaymentIntent, amount float64) (models.PaymentIntent, error) {
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := getPaymentIntentCollection().UpdateOne(ctx,
+		bson.M{"intent_id": intent.IntentID, "status": payments.StatusAuthorized},
+		bson.M{"$set": bson.M{"status": payments.StatusCaptured, "captured": amount, "updated_at": updatedAt}},
+	)
+	if err != nil {
+		return intent, err
+	}
+
+	if result.MatchedCount == 0 {
+		return intent, nil
+	}
+
+	intent.Status = payments.StatusCaptured
+	intent.Captured = amount
+	intent.UpdatedAt = updatedAt
+
+	var invoice models.Invoice
+	if err := getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": intent.InvoiceID}).Decode(&invoice); err != nil {
+		return reverseCapture(ctx, intent, err)
+	}
+
+	method := "CARD"
+	payment := models.Payment{
+		Method:     &method,
+		Amount:     &amount,
+		Reference:  &intent.ProviderRef,
+		IntentID:   &intent.IntentID,
+		ReceivedBy: intent.Provider,
+	}
+
+	payment, err = applyPayment(ctx, invoice, payment)
+	if err != nil {
+		return reverseCapture(ctx, intent, err)
+	}
+
+	intent.PaymentID = &payment.PaymentID
+	_, err = getPaymentIntentCollection().UpdateOne(ctx,
+		bson.M{"intent_id": intent.IntentID},
+		bson.M{"$set": bson.M{"payment_id": payment.PaymentID}},
+	)
+	return intent, err
+}
+
+// reverseCapture refunds a capture whose payment could not be recorded.
+// The intent becomes REFUNDED, or NEEDS_RECONCILIATION when the provider
+// refund fails too. The recording error is always returned.
+func reverseCapture(ctx context.Context, intent models.PaymentIntent, cause error) (models.PaymentIntent, error) {
+	intent.Status = payments.StatusRefunded
+	intent.Message = "captured but not recorded, refunded: " + cause.Error()
+
+	provider, err := payments.Get(intent.Provider)
+	if err == nil {
+		_, err = provider.Refund(ctx, intent.ProviderRef, intent.Captured)
+	}
+	if err != nil {
+		intent.Status = intentNeedsReconciliation
+		intent.Message = "captured but not recorded: " + cause.Error() + "; refund failed: " + err.Error()
+	}
+
+	if intent.Status == payments.StatusRefunded {
+		intent.Refunded = intent.Captured
+	}
+
+	intent.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	if _, err := getPaymentIntentCollection().UpdateOne(ctx,
+		bson.M{"intent_id": intent.IntentID},
+		bson.M{"$set": bson.M{"status": intent.Status, "refunded": intent.Refunded, "message": intent.Message, "updated_at": intent.UpdatedAt}},
+	); err != nil {
+		log.Println("payment intent", intent.IntentID, "needs reconciliation:", intent.Message)
+	}
+	return intent, cause
+}
+
+// CreatePaymentIntent authorizes a card payment for an invoice through a
+// payment provider
+func CreatePaymentIntent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		var request struct {
+			Provider  *string  `json:"provider"`
+			Amount    *float64 `json:"amount"`
+			CardToken string   `json:"card_token"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		providerName := helpers.PaymentProvider()
+		if request.Provider != nil {
+			providerName = *request.Provider
+		}
+
+		provider, err := payments.Get(providerName)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var invoice models.Invoice
+		err = getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		balance := balanceDue(invoice)
+		amount := balance
+		if request.Amount != nil {
+			amount = helpers.RoundMoney(*request.Amount)
+		}
+
+		if amount <= 0 || amount > balance {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errOverpayment.Error()})
+			return
+		}
+
+		result, err := provider.Authorize(ctx, payments.AuthorizeRequest{
+			Amount:    amount,
+			Reference: invoice.InvoiceID,
+			CardToken: request.CardToken,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "payment provider error: " + err.Error()})
+			return
+		}
+
+		var intent models.PaymentIntent
+		intent.InvoiceID = invoice.InvoiceID
+		intent.Provider = provider.Name()
+		intent.ProviderRef = result.ProviderRef
+		intent.Amount = amount
+		intent.Status = result.Status
+		intent.Message = result.Message
+		intent.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		intent.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		intent.ID = primitive.NewObjectID()
+		intent.IntentID = intent.ID.Hex()
+
+		_, insertErr := getPaymentIntentCollection().InsertOne(ctx, intent)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "payment intent was not created"})
+			return
+		}
+
+		if intent.Status == payments.StatusDeclined {
+			c.JSON(http.StatusPaymentRequired, intent)
+			return
+		}
+
+		c.JSON(http.StatusOK, intent)
+	}
+}
+
+// GetPaymentIntentByID returns a single payment intent by ID
+func GetPaymentIntentByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		intentId := c.Param("intent_id")
+		var intent models.PaymentIntent
+
+		err := getPaymentIntentCollection().FindOne(ctx, bson.M{"intent_id": intentId}).Decode(&intent)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the payment intent"})
+			return
+		}
+
+		c.JSON(http.StatusOK, intent)
+	}
+}
+
+// CapturePaymentIntent captures an authorized intent and records the
+// payment on its invoice
+func CapturePaymentIntent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		intentId := c.Param("intent_id")
+
+		var request struct {
+			Amount *float64 `json:"amount"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var intent models.PaymentIntent
+		err := getPaymentIntentCollection().FindOne(ctx, bson.M{"intent_id": intentId}).Decode(&intent)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "payment intent was not found"})
+			return
+		}
+
+		if intent.Status != payments.StatusAuthorized {
+			c.JSON(http.StatusConflict, gin.H{"error": "payment intent is " + intent.Status})
+			return
+		}
+
+		amount, err := captureAmount(intent, request.Amount)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		provider, err := payments.Get(intent.Provider)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, err := provider.Capture(ctx, intent.ProviderRef, amount); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "payment provider error: " + err.Error()})
+			return
+		}
+
+		intent, err = recordCapture(ctx, intent, amount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "payment could not be recorded and the capture is " + intent.Status + ": " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, intent)
+	}
+}
+
+// VoidPaymentIntent releases an authorization that was not captured
+func VoidPaymentIntent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		intentId := c.Param("intent_id")
+
+		var intent models.PaymentIntent
+		err := getPaymentIntentCollection().FindOne(ctx, bson.M{"intent_id": intentId}).Decode(&intent)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "payment intent was not found"})
+			return
+		}
+
+		if intent.Status != payments.StatusAuthorized {
+			c.JSON(http.StatusConflict, gin.H{"error": "payment intent is " + intent.Status})
+			return
+		}
+
+		provider, err := payments.Get(intent.Provider)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, err := provider.Void(ctx, intent.ProviderRef); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "payment provider error: " + err.Error()})
+			return
+		}
+
+		intent.Status = payments.StatusVoided
+		intent.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		_, err = getPaymentIntentCollection().UpdateOne(ctx,
+			bson.M{"intent_id": intentId, "status": payments.StatusAuthorized},
+			bson.M{"$set": bson.M{"status": intent.Status, "updated_at": intent.UpdatedAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "payment intent update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, intent)
+	}
+}
+
+// PaymentWebhook receives provider notifications. The signature in the
+// X-Signature header is verified with HMAC before anything is changed.
+func PaymentWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		provider, err := payments.Get(c.Param("provider"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		payload, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		event, err := provider.VerifyWebhook(payload, c.GetHeader("X-Signature"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		var intent models.PaymentIntent
+		err = getPaymentIntentCollection().FindOne(ctx, bson.M{"provider": provider.Name(), "provider_ref": event.ProviderRef}).Decode(&intent)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "payment intent was not found"})
+			return
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		switch event.Type {
+		case payments.EventCaptured:
+			// A capture event without an amount is for the whole authorization
+			var requested *float64
+			if event.Amount != 0 {
+				requested = &event.Amount
+			}
+			amount, err := captureAmount(intent, requested)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if intent, err := recordCapture(ctx, intent, amount); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "payment could not be recorded and the capture is " + intent.Status + ": " + err.Error()})
+				return
+			}
+		case payments.EventVoided, payments.EventFailed:
+			status := payments.StatusVoided
+			if event.Type == payments.EventFailed {
+				status = payments.StatusDeclined
+			}
+			getPaymentIntentCollection().UpdateOne(ctx,
+				bson.M{"intent_id": intent.IntentID, "status": payments.StatusAuthorized},
+				bson.M{"$set": bson.M{"status": status, "updated_at": updatedAt}},
+			)
+		case payments.EventRefunded:
+			refunded := intent.Captured
+			if event.Amount > 0 {
+				refunded = helpers.RoundMoney(event.Amount)
+			}
+			if err := recordProviderRefund(ctx, intent, refunded); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "refund could not be recorded: " + err.Error()})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"received": true})
+	}
+}
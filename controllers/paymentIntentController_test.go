@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+func TestCaptureAmount(t *testing.T) {
+	amount := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name      string
+		requested *float64
+		want      float64
+		wantErr   bool
+	}{
+		{"whole authorization", nil, 50, false},
+		{"part of it", amount(20), 20, false},
+		{"all of it", amount(50), 50, false},
+		{"rounded to the cent", amount(19.999), 20, false},
+		{"more than authorized", amount(50.01), 0, true},
+		{"nothing", amount(0), 0, true},
+		{"negative", amount(-5), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := captureAmount(models.PaymentIntent{Amount: 50}, tt.requested)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("captureAmount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("captureAmount() = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
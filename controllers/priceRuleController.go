@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var priceRuleValidate = validator.New()
+
+// GetPriceRules returns all price rules
+func GetPriceRules() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var priceRules []models.PriceRule
+		cursor, err := getPriceRuleCollection().Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing price rules"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &priceRules); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, priceRules)
+	}
+}
+
+// GetPriceRuleByID returns a single price rule by ID
+func GetPriceRuleByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		priceRuleId := c.Param("price_rule_id")
+		var priceRule models.PriceRule
+
+		err := getPriceRuleCollection().FindOne(ctx, bson.M{"price_rule_id": priceRuleId}).Decode(&priceRule)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the price rule"})
+			return
+		}
+
+		c.JSON(http.StatusOK, priceRule)
+	}
+}
+
+// CreatePriceRule creates a new time-based price rule
+func CreatePriceRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var priceRule models.PriceRule
+		if err := c.BindJSON(&priceRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := priceRuleValidate.Struct(priceRule)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if (priceRule.Price == nil) == (priceRule.PercentOff == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of price or percent_off is required"})
+			return
+		}
+
+		if priceRule.FoodID != nil {
+			var food models.Food
+			err := getFoodCollection().FindOne(ctx, bson.M{"food_id": priceRule.FoodID}).Decode(&food)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+				return
+			}
+		}
+
+		if priceRule.MenuID != nil {
+			var menu models.Menu
+			err := getMenuCollection().FindOne(ctx, bson.M{"menu_id": priceRule.MenuID}).Decode(&menu)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+				return
+			}
+		}
+
+		if priceRule.Active == nil {
+			active := true
+			priceRule.Active = &active
+		}
+
+		priceRule.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		priceRule.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		priceRule.ID = primitive.NewObjectID()
+		priceRule.PriceRuleID = priceRule.ID.Hex()
+
+		result, insertErr := getPriceRuleCollection().InsertOne(ctx, priceRule)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "price rule was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdatePriceRule updates an existing price rule
+func UpdatePriceRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var priceRule models.PriceRule
+		priceRuleId := c.Param("price_rule_id")
+
+		if err := c.BindJSON(&priceRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := priceRuleValidate.StructPartial(priceRule, "DaysOfWeek", "Price", "PercentOff")
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if priceRule.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: priceRule.Name})
+		}
+
+		if priceRule.DaysOfWeek != nil {
+			updateObj = append(updateObj, bson.E{Key: "days_of_week", Value: priceRule.DaysOfWeek})
+		}
+
+		if priceRule.StartTime != nil {
+			if _, err := time.Parse("15:04", *priceRule.StartTime); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "start_time must be HH:MM"})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "start_time", Value: priceRule.StartTime})
+		}
+
+		if priceRule.EndTime != nil {
+			if _, err := time.Parse("15:04", *priceRule.EndTime); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "end_time must be HH:MM"})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "end_time", Value: priceRule.EndTime})
+		}
+
+		// Setting one kind of override clears the other
+		if priceRule.Price != nil {
+			updateObj = append(updateObj, bson.E{Key: "price", Value: priceRule.Price})
+			updateObj = append(updateObj, bson.E{Key: "percent_off", Value: nil})
+		} else if priceRule.PercentOff != nil {
+			updateObj = append(updateObj, bson.E{Key: "percent_off", Value: priceRule.PercentOff})
+			updateObj = append(updateObj, bson.E{Key: "price", Value: nil})
+		}
+
+		if priceRule.Active != nil {
+			updateObj = append(updateObj, bson.E{Key: "active", Value: priceRule.Active})
+		}
+
+		priceRule.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: priceRule.UpdatedAt})
+
+		filter := bson.M{"price_rule_id": priceRuleId}
+
+		result, err := getPriceRuleCollection().UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "price rule update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// currentFoodPrice resolves the price of a food at the given time from the
+// price rules that could apply to it
+func currentFoodPrice(ctx context.Context, food models.Food, at time.Time) (float64, *models.PriceRule, error) {
+	filter := bson.M{
+		"active": bson.M{"$ne": false},
+		"$or": []bson.M{
+			{"food_id": food.FoodID},
+			{"menu_id": food.MenuID, "food_id": nil},
+			{"menu_id": nil, "food_id": nil},
+		},
+	}
+
+	var priceRules []models.PriceRule
+	cursor, err := getPriceRuleCollection().Find(ctx, filter)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &priceRules); err != nil {
+		return 0, nil, err
+	}
+
+	price, rule := helpers.ResolvePrice(food, priceRules, at)
+	return price, rule, nil
+}
+
+// GetAllPriceRules returns all price rules
+func GetAllPriceRules() gin.HandlerFunc {
+	return GetPriceRules()
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/ali-adel-nour/restaurant-management/printing"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var printerValidate = validator.New()
+
+// printerAddress adds the raw printing port 9100 to an address without one
+func printerAddress(address string) string {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, "9100")
+	}
+	return address
+}
+
+// GetPrinters returns all printers
+func GetPrinters() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var printers []models.Printer
+		cursor, err := getPrinterCollection().Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing printers"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &printers); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, printers)
+	}
+}
+
+// GetPrinterByID returns a single printer by ID
+func GetPrinterByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		printerId := c.Param("printer_id")
+		var printer models.Printer
+
+		err := getPrinterCollection().FindOne(ctx, bson.M{"printer_id": printerId}).Decode(&printer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the printer"})
+			return
+		}
+
+		c.JSON(http.StatusOK, printer)
+	}
+}
+
+// CreatePrinter registers a new printer
+func CreatePrinter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var printer models.Printer
+		if err := c.BindJSON(&printer); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if printer.Address != nil {
+			address := printerAddress(*printer.Address)
+			printer.Address = &address
+		}
+
+		validationErr := printerValidate.Struct(printer)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if printer.Active == nil {
+			active := true
+			printer.Active = &active
+		}
+
+		printer.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		printer.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		printer.ID = primitive.NewObjectID()
+		printer.PrinterID = printer.ID.Hex()
+
+		result, insertErr := getPrinterCollection().InsertOne(ctx, printer)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "printer was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdatePrinter updates an existing printer
+func UpdatePrinter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var printer models.Printer
+		printerId := c.Param("printer_id")
+
+		if err := c.BindJSON(&printer); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if printer.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: printer.Name})
+		}
+
+		if printer.Address != nil {
+			address := printerAddress(*printer.Address)
+			printer.Address = &address
+			validationErr := printerValidate.StructPartial(printer, "Address")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "address", Value: printer.Address})
+		}
+
+		if printer.Kind != nil {
+			validationErr := printerValidate.StructPartial(printer, "Kind")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "kind", Value: printer.Kind})
+		}
+
+		if printer.Station != nil {
+			updateObj = append(updateObj, bson.E{Key: "station", Value: printer.Station})
+		}
+
+		if printer.Active != nil {
+			updateObj = append(updateObj, bson.E{Key: "active", Value: printer.Active})
+		}
+
+		printer.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: printer.UpdatedAt})
+
+		filter := bson.M{"printer_id": printerId}
+
+		result, err := getPrinterCollection().UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "printer update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetPrintJobs returns the most recent print jobs and their status
+func GetPrintJobs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, printing.Jobs())
+	}
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/ali-adel-nour/restaurant-management/receipts"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// renderReceipt loads an invoice with its lines and payments and fills the
+// receipt template with it. It returns the invoice so callers can name files.
+func renderReceipt(ctx context.Context, invoiceId string) (models.Invoice, string, error) {
+	var invoice models.Invoice
+	err := getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+	if err != nil {
+		return invoice, "", err
+	}
+
+	lines := invoice.Lines
+	if lines == nil {
+		lines, err = snapshotInvoiceLines(ctx, invoice.OrderID)
+		if err != nil {
+			return invoice, "", err
+		}
+	}
+
+	var payments []models.Payment
+	cursor, err := getPaymentCollection().Find(ctx, bson.M{"invoice_id": invoiceId})
+	if err != nil {
+		return invoice, "", err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &payments); err != nil {
+		return invoice, "", err
+	}
+
+	templateText := receipts.DefaultTemplate
+	if path := helpers.ReceiptTemplatePath(); path != "" {
+		custom, err := os.ReadFile(path)
+		if err != nil {
+			return invoice, "", err
+		}
+		templateText = string(custom)
+	}
+
+	text, err := receipts.Render(receipts.Receipt{
+		Restaurant: receipts.Restaurant{
+			Name:    helpers.RestaurantName(),
+			Address: helpers.RestaurantAddress(),
+			Phone:   helpers.RestaurantPhone(),
+		},
+		Invoice:   invoice,
+		Lines:     lines,
+		Payments:  payments,
+		Location:  helpers.RestaurantLocation(),
+		PrintedAt: time.Now(),
+	}, templateText)
+
+	return invoice, text, err
+}
+
+// GetInvoiceReceipt returns an invoice as a plain text receipt
+func GetInvoiceReceipt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		_, text, err := renderReceipt(ctx, invoiceId)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while rendering the receipt: " + err.Error()})
+			return
+		}
+
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+	}
+}
+
+// GetInvoicePDF returns an invoice as a PDF document
+func GetInvoicePDF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		invoice, text, err := renderReceipt(ctx, invoiceId)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while rendering the receipt: " + err.Error()})
+			return
+		}
+
+		filename := invoice.InvoiceNumber
+		if filename == "" {
+			filename = invoice.InvoiceID
+		}
+
+		c.Header("Content-Disposition", `inline; filename="`+filename+`.pdf"`)
+		c.Data(http.StatusOK, "application/pdf", receipts.PDF(text))
+	}
+}
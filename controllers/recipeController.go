@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var recipeValidate = validator.New()
+
+// depleteStock takes the ingredients of a new order item out of stock,
+// by its food's recipe. Foods without a recipe use no stock.
+func depleteStock(ctx context.Context, orderItem models.OrderItem) error {
+	var recipe models.Recipe
+	err := getRecipeCollection().FindOne(ctx, bson.M{"food_id": orderItem.FoodID}).Decode(&recipe)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	orderItemId := orderItem.OrderItemID
+	for _, line := range recipe.Lines {
+		movementType := "SALE"
+		change := -*line.Quantity * float64(*orderItem.Quantity)
+		movement := models.StockMovement{
+			IngredientID: *line.IngredientID,
+			Type:         &movementType,
+			Quantity:     &change,
+			OrderID:      &orderItem.OrderID,
+			OrderItemID:  &orderItemId,
+		}
+		if err := moveStock(ctx, &movement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreStock puts back what an order item took out of stock, as recorded
+// in the ledger, so a later change to the recipe does not matter
+func restoreStock(ctx context.Context, orderItem models.OrderItem, reason string, by string) error {
+	var movements []models.StockMovement
+	err := findAll(ctx, getStockMovementCollection(), bson.M{"order_item_id": orderItem.OrderItemID}, &movements)
+	if err != nil {
+		return err
+	}
+
+	used := map[string]float64{}
+	var ingredientIds []string
+	for _, movement := range movements {
+		if _, ok := used[movement.IngredientID]; !ok {
+			ingredientIds = append(ingredientIds, movement.IngredientID)
+		}
+		used[movement.IngredientID] += *movement.Quantity
+	}
+
+	orderItemId := orderItem.OrderItemID
+	for _, ingredientId := range ingredientIds {
+		change := -helpers.RoundQuantity(used[ingredientId])
+		if change == 0 {
+			continue
+		}
+		movementType := "SALE_REVERSAL"
+		movement := models.StockMovement{
+			IngredientID: ingredientId,
+			Type:         &movementType,
+			Quantity:     &change,
+			OrderID:      &orderItem.OrderID,
+			OrderItemID:  &orderItemId,
+			Reason:       &reason,
+			By:           by,
+		}
+		if err := moveStock(ctx, &movement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resyncStock brings what an order item took out of stock in line with its
+// current food and quantity, moving only the difference from the ledger so
+// a retry after a partial failure does not take stock twice. It carries on
+// past a failed ingredient and returns every error.
+func resyncStock(ctx context.Context, orderItem models.OrderItem, reason string, by string) error {
+	needed := map[string]float64{}
+	var ingredientIds []string
+
+	var recipe models.Recipe
+	err := getRecipeCollection().FindOne(ctx, bson.M{"food_id": orderItem.FoodID}).Decode(&recipe)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	for _, line := range recipe.Lines {
+		if _, ok := needed[*line.IngredientID]; !ok {
+			ingredientIds = append(ingredientIds, *line.IngredientID)
+		}
+		needed[*line.IngredientID] -= *line.Quantity * float64(*orderItem.Quantity)
+	}
+
+	var movements []models.StockMovement
+	err = findAll(ctx, getStockMovementCollection(), bson.M{"order_item_id": orderItem.OrderItemID}, &movements)
+	if err != nil {
+		return err
+	}
+	taken := map[string]float64{}
+	for _, movement := range movements {
+		if _, ok := needed[movement.IngredientID]; !ok {
+			needed[movement.IngredientID] = 0
+			ingredientIds = append(ingredientIds, movement.IngredientID)
+		}
+		taken[movement.IngredientID] += *movement.Quantity
+	}
+
+	var errs []error
+	orderItemId := orderItem.OrderItemID
+	for _, ingredientId := range ingredientIds {
+		change := helpers.RoundQuantity(needed[ingredientId] - taken[ingredientId])
+		if change == 0 {
+			continue
+		}
+		movementType := "SALE"
+		if change > 0 {
+			movementType = "SALE_REVERSAL"
+		}
+		movement := models.StockMovement{
+			IngredientID: ingredientId,
+			Type:         &movementType,
+			Quantity:     &change,
+			OrderID:      &orderItem.OrderID,
+			OrderItemID:  &orderItemId,
+			Reason:       &reason,
+			By:           by,
+		}
+		if err := moveStock(ctx, &movement); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetRecipe returns the recipe of a food
+func GetRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		foodId := c.Param("food_id")
+
+		var recipe models.Recipe
+		err := getRecipeCollection().FindOne(ctx, bson.M{"food_id": foodId}).Decode(&recipe)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food has no recipe"})
+			return
+		}
+
+		c.JSON(http.StatusOK, recipe)
+	}
+}
+
+// SetRecipe sets the ingredients used by one portion of a food, replacing
+// any recipe it had. Order items already taken keep the stock they used.
+func SetRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		foodId := c.Param("food_id")
+
+		var recipe models.Recipe
+		if err := c.BindJSON(&recipe); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := recipeValidate.Struct(recipe)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		count, err := getFoodCollection().CountDocuments(ctx, bson.M{"food_id": foodId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking the food item"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
+
+		seen := map[string]bool{}
+		var ingredientIds []string
+		for _, line := range recipe.Lines {
+			if seen[*line.IngredientID] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "an ingredient can only appear once in a recipe"})
+				return
+			}
+			seen[*line.IngredientID] = true
+			ingredientIds = append(ingredientIds, *line.IngredientID)
+		}
+
+		count, err = getIngredientCollection().CountDocuments(ctx, bson.M{"ingredient_id": bson.M{"$in": ingredientIds}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking the ingredients"})
+			return
+		}
+		if int(count) != len(ingredientIds) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ingredient was not found"})
+			return
+		}
+
+		for i := range recipe.Lines {
+			quantity := helpers.RoundQuantity(*recipe.Lines[i].Quantity)
+			recipe.Lines[i].Quantity = &quantity
+		}
+
+		var current models.Recipe
+		err = getRecipeCollection().FindOne(ctx, bson.M{"food_id": foodId}).Decode(&current)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while loading the recipe"})
+			return
+		}
+
+		recipe.FoodID = foodId
+		recipe.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err == nil {
+			recipe.ID = current.ID
+			recipe.RecipeID = current.RecipeID
+			recipe.CreatedAt = current.CreatedAt
+
+			_, err = getRecipeCollection().UpdateOne(ctx,
+				bson.M{"recipe_id": current.RecipeID},
+				bson.M{"$set": bson.M{"lines": recipe.Lines, "updated_at": recipe.UpdatedAt}},
+			)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "recipe update failed"})
+				return
+			}
+		} else {
+			recipe.CreatedAt = recipe.UpdatedAt
+			recipe.ID = primitive.NewObjectID()
+			recipe.RecipeID = recipe.ID.Hex()
+
+			_, insertErr := getRecipeCollection().InsertOne(ctx, recipe)
+			if insertErr != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "recipe was not created"})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, recipe)
+	}
+}
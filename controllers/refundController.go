@@ -0,0 +1,471 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/database"
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/ali-adel-nour/restaurant-management/payments"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var refundValidate = validator.New()
+
+// amountFilter matches a stored amount, treating a missing field as zero
+func amountFilter(amount float64) interface{} {
+	if amount == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return amount
+}
+
+var (
+	errRefundConflict  = errors.New("invoice or payment changed while the refund was recorded, please retry")
+	errProviderRefused = errors.New("payment provider refused the refund")
+	errIntentChanged   = errors.New("payment intent changed while its refund was recorded")
+)
+
+// setIntentRefunded moves the amount refunded on a payment intent from one
+// value to another. The intent is REFUNDED once all of its capture has been
+// refunded and stays CAPTURED after a partial refund.
+func setIntentRefunded(ctx context.Context, intent models.PaymentIntent, from, to float64) error {
+	status := payments.StatusCaptured
+	if to >= intent.Captured {
+		status = payments.StatusRefunded
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := getPaymentIntentCollection().UpdateOne(ctx,
+		bson.M{"intent_id": intent.IntentID, "refunded": amountFilter(from)},
+		bson.M{"$set": bson.M{"refunded": to, "status": status, "updated_at": updatedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errIntentChanged
+	}
+	return nil
+}
+
+// refundThroughProvider refunds part or all of a captured payment intent
+// with the provider that took it. The refund is counted on the intent
+// before the provider is asked, so the provider's webhook for it is seen
+// as already recorded.
+func refundThroughProvider(ctx context.Context, intentId string, amount float64) error {
+	var intent models.PaymentIntent
+	if err := getPaymentIntentCollection().FindOne(ctx, bson.M{"intent_id": intentId}).Decode(&intent); err != nil {
+		return err
+	}
+
+	provider, err := payments.Get(intent.Provider)
+	if err != nil {
+		return err
+	}
+
+	refunded := helpers.RoundMoney(intent.Refunded + amount)
+	if err := setIntentRefunded(ctx, intent, intent.Refunded, refunded); err != nil {
+		return err
+	}
+
+	if _, err = provider.Refund(ctx, intent.ProviderRef, amount); err != nil {
+		if err := setIntentRefunded(ctx, intent, refunded, intent.Refunded); err != nil {
+			log.Println("payment intent", intent.IntentID, "refund was not put back:", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// recordProviderRefund records a refund the provider reports by webhook
+// but that was not made through CreateRefund, such as one issued from the
+// provider's dashboard. refunded is the total the provider has refunded on
+// the charge; only the part not yet counted on the intent is recorded, so
+// repeated webhooks and refunds made here are not recorded twice.
+func recordProviderRefund(ctx context.Context, intent models.PaymentIntent, refunded float64) error {
+	if refunded > intent.Captured {
+		refunded = intent.Captured
+	}
+	if refunded <= intent.Refunded {
+		return nil
+	}
+
+	if intent.PaymentID == nil {
+		return errors.New("payment intent has no recorded payment to refund")
+	}
+
+	var payment models.Payment
+	if err := getPaymentCollection().FindOne(ctx, bson.M{"payment_id": *intent.PaymentID}).Decode(&payment); err != nil {
+		return err
+	}
+
+	var invoice models.Invoice
+	if err := getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": payment.InvoiceID}).Decode(&invoice); err != nil {
+		return err
+	}
+
+	amount := helpers.RoundMoney(refunded - intent.Refunded)
+	if refundable := helpers.RoundMoney(*payment.Amount - payment.Refunded); amount > refundable {
+		amount = refundable
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	// Claim the refund on the intent first; a concurrent delivery of the
+	// same webhook then finds it already counted
+	if err := setIntentRefunded(ctx, intent, intent.Refunded, refunded); err != nil {
+		if errors.Is(err, errIntentChanged) {
+			return nil
+		}
+		return err
+	}
+
+	reason := "refunded at " + intent.Provider
+	refund := models.Refund{
+		PaymentID:    &payment.PaymentID,
+		Amount:       &amount,
+		Reason:       &reason,
+		AuthorizedBy: intent.Provider,
+	}
+
+	if _, _, err := refundPayment(ctx, invoice, payment, refund, false); err != nil {
+		if err := setIntentRefunded(ctx, intent, refunded, intent.Refunded); err != nil {
+			log.Println("payment intent", intent.IntentID, "refund was not put back:", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// refundCounted matches the refunds whose money has moved or may have:
+// all but FAILED ones. Refunds saved before refunds had a status count.
+var refundCounted = bson.M{"$ne": "FAILED"}
+
+// voidRefund marks a refund FAILED and voids its credit note after the
+// refund could not be made. Nothing has been paid out at that point.
+func voidRefund(ctx context.Context, refund models.Refund) {
+	if _, err := getRefundCollection().UpdateOne(ctx,
+		bson.M{"refund_id": refund.RefundID},
+		bson.M{"$set": bson.M{"status": "FAILED"}},
+	); err != nil {
+		log.Println("refund was not marked failed:", refund.RefundID, err)
+	}
+	if _, err := getCreditNoteCollection().UpdateOne(ctx,
+		bson.M{"credit_note_id": refund.CreditNoteID},
+		bson.M{"$set": bson.M{"status": "VOID"}},
+	); err != nil {
+		log.Println("credit note was not voided:", refund.CreditNoteID, err)
+	}
+}
+
+// GetInvoiceRefunds returns the refunds made against an invoice
+func GetInvoiceRefunds() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		var refunds []models.Refund
+		cursor, err := getRefundCollection().Find(ctx, bson.M{"invoice_id": invoiceId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching refunds"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &refunds); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, refunds)
+	}
+}
+
+// refundPayment refunds amount of one payment of an invoice and issues a
+// credit note for it. The refund and credit note are saved as PENDING
+// before any money moves; the invoice and payment are then moved, the
+// provider is asked when throughProvider is set and the payment went
+// through one, and the documents are finalised. Any failure before the
+// money has moved undoes the earlier steps.
+func refundPayment(ctx context.Context, invoice models.Invoice, payment models.Payment, refund models.Refund, throughProvider bool) (models.Refund, models.CreditNote, error) {
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	amount := *refund.Amount
+
+	// Record the refund and its credit note as PENDING before any money
+	// moves, so a refund made at the provider always has its documents
+	refund.ID = primitive.NewObjectID()
+	refund.RefundID = refund.ID.Hex()
+	refund.InvoiceID = invoice.InvoiceID
+	refund.Method = *payment.Method
+	refund.Status = "PENDING"
+	refund.CreatedAt = updatedAt
+
+	var creditNote models.CreditNote
+	creditNote.ID = primitive.NewObjectID()
+	creditNote.CreditNoteID = creditNote.ID.Hex()
+	creditNote.InvoiceID = invoice.InvoiceID
+	creditNote.InvoiceNumber = invoice.InvoiceNumber
+	creditNote.RefundID = refund.RefundID
+	creditNote.Amount = amount
+	creditNote.Reason = *refund.Reason
+	creditNote.Status = "PENDING"
+	creditNote.CreatedAt = updatedAt
+	creditNote.FiscalYear = helpers.FiscalYear(updatedAt)
+
+	counter := fmt.Sprintf("creditNote-%d", creditNote.FiscalYear)
+	seq, err := database.NextSequence(ctx, counter)
+	if err != nil {
+		return refund, creditNote, errors.New("error occurred while numbering the credit note")
+	}
+	creditNote.CreditNoteNumber = fmt.Sprintf("CN-%d-%06d", creditNote.FiscalYear, seq)
+	refund.CreditNoteID = creditNote.CreditNoteID
+
+	if _, insertErr := getCreditNoteCollection().InsertOne(ctx, creditNote); insertErr != nil {
+		database.ReleaseSequence(ctx, counter, seq)
+		return refund, creditNote, errors.New("credit note was not created")
+	}
+
+	if _, insertErr := getRefundCollection().InsertOne(ctx, refund); insertErr != nil {
+		voidRefund(ctx, refund)
+		return refund, creditNote, errors.New("refund was not created")
+	}
+
+	// Move the invoice next; both updates are guarded by the amounts
+	// read above so concurrent refunds cannot exceed what was paid. An
+	// invoice with a balance still due keeps its unpaid status, and the
+	// money refunded is owed on it again.
+	refundedTotal := helpers.RoundMoney(invoice.RefundedTotal + amount)
+	status := paymentStatus(invoice, invoice.AmountPaid, refundedTotal)
+
+	balance := 0.0
+	if !invoiceSettled(invoice) {
+		balance = owed(invoice, invoice.AmountPaid, refundedTotal)
+	}
+
+	invoiceResult, err := getInvoiceCollection().UpdateOne(ctx,
+		bson.M{
+			"invoice_id":     invoice.InvoiceID,
+			"amount_paid":    amountFilter(invoice.AmountPaid),
+			"refunded_total": amountFilter(invoice.RefundedTotal),
+		},
+		bson.M{"$set": bson.M{
+			"refunded_total": refundedTotal,
+			"balance_due":    balance,
+			"payment_status": status,
+			"updated_at":     updatedAt,
+		}},
+	)
+	if err != nil {
+		voidRefund(ctx, refund)
+		return refund, creditNote, errors.New("refund was not recorded")
+	}
+
+	if invoiceResult.MatchedCount == 0 {
+		voidRefund(ctx, refund)
+		return refund, creditNote, errRefundConflict
+	}
+
+	paymentResult, err := getPaymentCollection().UpdateOne(ctx,
+		bson.M{"payment_id": payment.PaymentID, "refunded": amountFilter(payment.Refunded)},
+		bson.M{"$set": bson.M{"refunded": helpers.RoundMoney(payment.Refunded + amount)}},
+	)
+	revertInvoice := func() {
+		getInvoiceCollection().UpdateOne(ctx,
+			bson.M{"invoice_id": invoice.InvoiceID, "refunded_total": refundedTotal},
+			bson.M{"$set": bson.M{
+				"refunded_total": invoice.RefundedTotal,
+				"balance_due":    invoice.BalanceDue,
+				"payment_status": invoice.PaymentStatus,
+			}},
+		)
+	}
+
+	if err != nil || paymentResult.MatchedCount == 0 {
+		revertInvoice()
+		voidRefund(ctx, refund)
+		return refund, creditNote, errRefundConflict
+	}
+
+	// Card payments taken through a provider are refunded there too,
+	// unless the provider reported the refund itself
+	if throughProvider && payment.IntentID != nil {
+		if err := refundThroughProvider(ctx, *payment.IntentID, amount); err != nil {
+			getPaymentCollection().UpdateOne(ctx,
+				bson.M{"payment_id": payment.PaymentID},
+				bson.M{"$set": bson.M{"refunded": payment.Refunded}},
+			)
+			revertInvoice()
+			voidRefund(ctx, refund)
+			return refund, creditNote, fmt.Errorf("%w: %v", errProviderRefused, err)
+		}
+	}
+
+	// The money has moved, so the refund stands even if finalising its
+	// documents fails; they then stay PENDING for reconciliation
+	refund.Status = "COMPLETED"
+	creditNote.Status = "ISSUED"
+	if _, err := getRefundCollection().UpdateOne(ctx,
+		bson.M{"refund_id": refund.RefundID},
+		bson.M{"$set": bson.M{"status": refund.Status}},
+	); err != nil {
+		log.Println("refund was not marked completed:", refund.RefundID, err)
+	}
+	if _, err := getCreditNoteCollection().UpdateOne(ctx,
+		bson.M{"credit_note_id": creditNote.CreditNoteID},
+		bson.M{"$set": bson.M{"status": creditNote.Status}},
+	); err != nil {
+		log.Println("credit note was not marked issued:", creditNote.CreditNoteID, err)
+	}
+
+	return refund, creditNote, nil
+}
+
+// CreateRefund refunds all or part of one payment of an invoice and issues
+// a credit note for it. Refunds need manager authorization.
+func CreateRefund() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		var request struct {
+			models.Refund
+			ManagerEmail    *string `json:"manager_email"`
+			ManagerPassword *string `json:"manager_password"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		refund := request.Refund
+		validationErr := refundValidate.Struct(refund)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		authorizedBy, ok := authorizeManager(ctx, c, request.ManagerEmail, request.ManagerPassword)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "refunds require manager authorization"})
+			return
+		}
+
+		var invoice models.Invoice
+		err := getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		if invoice.PaymentStatus == nil || *invoice.PaymentStatus == "PENDING" || *invoice.PaymentStatus == "REFUNDED" {
+			c.JSON(http.StatusConflict, gin.H{"error": "invoice has no payments left to refund"})
+			return
+		}
+
+		var payment models.Payment
+		err = getPaymentCollection().FindOne(ctx, bson.M{"payment_id": refund.PaymentID, "invoice_id": invoiceId}).Decode(&payment)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "payment was not found on this invoice"})
+			return
+		}
+
+		if *payment.Method == "CASH" {
+			refund.DrawerSessionID, err = cashDrawerFor(ctx, refund.DrawerSessionID)
+			if err != nil {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+		} else {
+			refund.DrawerSessionID = nil
+		}
+
+		refundable := helpers.RoundMoney(*payment.Amount - payment.Refunded)
+		if refund.Amount == nil {
+			refund.Amount = &refundable
+		}
+
+		amount := helpers.RoundMoney(*refund.Amount)
+		if amount <= 0 || amount > refundable {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %.2f can be refunded on this payment", refundable)})
+			return
+		}
+		refund.Amount = &amount
+
+		refund.AuthorizedBy = authorizedBy
+
+		refund, creditNote, err := refundPayment(ctx, invoice, payment, refund, true)
+		switch {
+		case errors.Is(err, errRefundConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, errProviderRefused):
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"refund":      refund,
+			"credit_note": creditNote,
+		})
+	}
+}
+
+// GetCreditNotes returns all credit notes
+func GetCreditNotes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var creditNotes []models.CreditNote
+		cursor, err := getCreditNoteCollection().Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing credit notes"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &creditNotes); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, creditNotes)
+	}
+}
+
+// GetCreditNoteByID returns a single credit note by ID
+func GetCreditNoteByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		creditNoteId := c.Param("credit_note_id")
+		var creditNote models.CreditNote
+
+		err := getCreditNoteCollection().FindOne(ctx, bson.M{"credit_note_id": creditNoteId}).Decode(&creditNote)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the credit note"})
+			return
+		}
+
+		c.JSON(http.StatusOK, creditNote)
+	}
+}
@@ -0,0 +1,409 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/database"
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// reportLocation reads the tz query parameter, an IANA time zone name,
+// and defaults to the restaurant time zone
+func reportLocation(c *gin.Context) (*time.Location, bool) {
+	name := c.Query("tz")
+	if name == "" {
+		return helpers.RestaurantLocation(), true
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tz must be an IANA time zone name such as Europe/London"})
+		return nil, false
+	}
+	return location, true
+}
+
+// parseReportRange reads the from/to query parameters. Both accept RFC3339
+// or YYYY-MM-DD (a whole day in the report time zone) and default to the
+// last 7 days.
+func parseReportRange(c *gin.Context) (time.Time, time.Time, bool) {
+	location, ok := reportLocation(c)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	now := time.Now().In(location)
+	to := now
+	from := now.AddDate(0, 0, -7)
+
+	parse := func(value string, endOfDay bool) (time.Time, bool) {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, true
+		}
+		t, err := time.ParseInLocation("2006-01-02", value, location)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, true
+	}
+
+	if v := c.Query("from"); v != "" {
+		t, ok := parse(v, false)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD"})
+			return from, to, false
+		}
+		from = t
+	}
+
+	if v := c.Query("to"); v != "" {
+		t, ok := parse(v, true)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD"})
+			return from, to, false
+		}
+		to = t
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return from, to, false
+	}
+
+	return from, to, true
+}
+
+// serverNames looks up display names for a set of user IDs
+func serverNames(ctx context.Context, userIds []string) map[string]string {
+	names := map[string]string{}
+
+	cursor, err := getUserCollection().Find(ctx, bson.M{"user_id": bson.M{"$in": userIds}})
+	if err != nil {
+		return names
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return names
+	}
+
+	for _, user := range users {
+		if user.FirstName != nil && user.LastName != nil {
+			names[user.UserID] = *user.FirstName + " " + *user.LastName
+		}
+	}
+	return names
+}
+
+// tipRow is the tips collected by one server; ServerID is nil for tips
+// taken on orders without a server
+type tipRow struct {
+	ServerID *string `bson:"_id"`
+	TipTotal float64 `bson:"tip_total"`
+	TipCount int     `bson:"tip_count"`
+}
+
+// tipPoolShares pools all the tips of rows, including those taken without
+// a server, and shares the pool equally among the servers. The share of
+// each row is returned in order; the row without a server gets none.
+func tipPoolShares(rows []tipRow) (float64, []float64) {
+	var pool float64
+	servers := 0
+	for _, row := range rows {
+		pool += row.TipTotal
+		if row.ServerID != nil {
+			servers++
+		}
+	}
+
+	shares := make([]float64, len(rows))
+	if servers == 0 {
+		return helpers.RoundMoney(pool), shares
+	}
+
+	share := helpers.RoundMoney(pool / float64(servers))
+	for i, row := range rows {
+		if row.ServerID != nil {
+			shares[i] = share
+		}
+	}
+	return helpers.RoundMoney(pool), shares
+}
+
+// GetTipReport returns the tips collected by each server in a date range
+// and each server's equal share of the tip pool
+func GetTipReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+
+		matchStage := bson.D{{Key: "$match", Value: bson.D{
+			{Key: "created_at", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: to}}},
+		}}}
+		groupStage := bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$server_id"},
+			{Key: "tip_total", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+			{Key: "tip_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}}
+		sortStage := bson.D{{Key: "$sort", Value: bson.D{{Key: "tip_total", Value: -1}}}}
+
+		cursor, err := getTipCollection().Aggregate(ctx, mongo.Pipeline{matchStage, groupStage, sortStage})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while building the tip report"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var rows []tipRow
+		if err = cursor.All(ctx, &rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while building the tip report"})
+			return
+		}
+
+		var ids []string
+		for _, row := range rows {
+			if row.ServerID != nil {
+				ids = append(ids, *row.ServerID)
+			}
+		}
+		names := serverNames(ctx, ids)
+
+		pool, shares := tipPoolShares(rows)
+
+		servers := []gin.H{}
+		for i, row := range rows {
+			serverId := ""
+			if row.ServerID != nil {
+				serverId = *row.ServerID
+			}
+			servers = append(servers, gin.H{
+				"server_id":   serverId,
+				"server_name": names[serverId],
+				"tip_total":   helpers.RoundMoney(row.TipTotal),
+				"tip_count":   row.TipCount,
+				"pool_share":  shares[i],
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":    from,
+			"to":      to,
+			"pool":    pool,
+			"servers": servers,
+		})
+	}
+}
+
+// agingRow is the unpaid balance of one house account split by how long
+// it has been past due
+type agingRow struct {
+	AccountID  string  `json:"account_id"`
+	Name       string  `json:"name"`
+	Invoices   int     `json:"invoices"`
+	Current    float64 `json:"current"`
+	Days0To30  float64 `json:"days_0_30"`
+	Days31To60 float64 `json:"days_31_60"`
+	Over60     float64 `json:"over_60"`
+	Total      float64 `json:"total"`
+}
+
+// add puts a balance in the bucket for its days past due
+func (r *agingRow) add(balance float64, daysPastDue int) {
+	switch {
+	case daysPastDue < 0:
+		r.Current += balance
+	case daysPastDue <= 30:
+		r.Days0To30 += balance
+	case daysPastDue <= 60:
+		r.Days31To60 += balance
+	default:
+		r.Over60 += balance
+	}
+	r.Invoices++
+	r.Total += balance
+}
+
+func (r *agingRow) round() {
+	r.Current = helpers.RoundMoney(r.Current)
+	r.Days0To30 = helpers.RoundMoney(r.Days0To30)
+	r.Days31To60 = helpers.RoundMoney(r.Days31To60)
+	r.Over60 = helpers.RoundMoney(r.Over60)
+	r.Total = helpers.RoundMoney(r.Total)
+}
+
+// GetAgingReport returns unpaid invoice balances per house account in
+// aging buckets: not yet due, 0-30, 31-60 and over 60 days past due
+func GetAgingReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var invoices []models.Invoice
+		cursor, err := getInvoiceCollection().Find(ctx, bson.M{"payment_status": bson.M{"$in": unpaidStatuses}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing invoices"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &invoices); err != nil {
+			log.Fatal(err)
+		}
+
+		now := time.Now()
+		rows := map[string]*agingRow{}
+		var totals agingRow
+		for _, invoice := range invoices {
+			balance := balanceDue(invoice)
+			if balance <= 0 {
+				continue
+			}
+
+			daysPastDue := -1
+			if invoice.PaymentDue != nil && now.After(*invoice.PaymentDue) {
+				daysPastDue = int(now.Sub(*invoice.PaymentDue).Hours() / 24)
+			}
+
+			accountId := ""
+			if invoice.AccountID != nil {
+				accountId = *invoice.AccountID
+			}
+
+			row, ok := rows[accountId]
+			if !ok {
+				row = &agingRow{AccountID: accountId, Name: "No account"}
+				rows[accountId] = row
+			}
+			row.add(balance, daysPastDue)
+			totals.add(balance, daysPastDue)
+		}
+
+		var accountIds []string
+		for accountId := range rows {
+			if accountId != "" {
+				accountIds = append(accountIds, accountId)
+			}
+		}
+
+		if len(accountIds) > 0 {
+			var accounts []models.HouseAccount
+			cursor, err := getHouseAccountCollection().Find(ctx, bson.M{"account_id": bson.M{"$in": accountIds}})
+			if err == nil {
+				defer cursor.Close(ctx)
+				if cursor.All(ctx, &accounts) == nil {
+					for _, account := range accounts {
+						if row, ok := rows[account.AccountID]; ok && account.Name != nil {
+							row.Name = *account.Name
+						}
+					}
+				}
+			}
+		}
+
+		report := []agingRow{}
+		for _, row := range rows {
+			row.round()
+			report = append(report, *row)
+		}
+		sort.Slice(report, func(i, j int) bool {
+			return report[i].Total > report[j].Total
+		})
+		totals.round()
+		totals.Name = "Total"
+
+		c.JSON(http.StatusOK, gin.H{
+			"as_of":    now,
+			"accounts": report,
+			"totals":   totals,
+		})
+	}
+}
+
+// missingSequences returns the numbers from 1 to last that are not in taken
+func missingSequences(taken []int64, last int64) []int64 {
+	seen := map[int64]bool{}
+	for _, seq := range taken {
+		seen[seq] = true
+	}
+
+	missing := []int64{}
+	for seq := int64(1); seq <= last; seq++ {
+		if !seen[seq] {
+			missing = append(missing, seq)
+		}
+	}
+	return missing
+}
+
+// GetInvoiceGapReport lists the invoice numbers of a fiscal year
+// (?fiscal_year=, default the current one) that were handed out but belong
+// to no invoice, because the invoice failed to save after another number
+// had been taken
+func GetInvoiceGapReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		fiscalYear := helpers.FiscalYear(time.Now())
+		if v := c.Query("fiscal_year"); v != "" {
+			year, err := strconv.Atoi(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "fiscal_year must be a year such as 2026"})
+				return
+			}
+			fiscalYear = year
+		}
+
+		last, err := database.CurrentSequence(ctx, fmt.Sprintf("invoice-%d", fiscalYear))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while reading the invoice counter"})
+			return
+		}
+
+		var invoices []models.Invoice
+		err = findAll(ctx, getInvoiceCollection(), bson.M{"fiscal_year": fiscalYear, "invoice_number": bson.M{"$gt": ""}}, &invoices)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing invoices"})
+			return
+		}
+
+		taken := make([]int64, 0, len(invoices))
+		for _, invoice := range invoices {
+			var year int
+			var seq int64
+			if _, err := fmt.Sscanf(invoice.InvoiceNumber, "INV-%d-%d", &year, &seq); err == nil {
+				taken = append(taken, seq)
+			}
+		}
+
+		missing := []string{}
+		for _, seq := range missingSequences(taken, last) {
+			missing = append(missing, fmt.Sprintf("INV-%d-%06d", fiscalYear, seq))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"fiscal_year":     fiscalYear,
+			"last_number":     last,
+			"invoices":        len(taken),
+			"missing_numbers": missing,
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAgingRowAdd(t *testing.T) {
+	tests := []struct {
+		name        string
+		daysPastDue int
+		want        agingRow
+	}{
+		{"not yet due", -1, agingRow{Current: 10}},
+		{"due today", 0, agingRow{Days0To30: 10}},
+		{"30 days", 30, agingRow{Days0To30: 10}},
+		{"31 days", 31, agingRow{Days31To60: 10}},
+		{"60 days", 60, agingRow{Days31To60: 10}},
+		{"61 days", 61, agingRow{Over60: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var row agingRow
+			row.add(10, tt.daysPastDue)
+
+			tt.want.Invoices = 1
+			tt.want.Total = 10
+			if row != tt.want {
+				t.Errorf("row = %+v, want %+v", row, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgingRowRound(t *testing.T) {
+	var row agingRow
+	row.add(0.1, 5)
+	row.add(0.2, 10)
+	row.add(1.005, 90)
+	row.round()
+
+	if row.Days0To30 != 0.3 || row.Over60 != 1 || row.Total != 1.31 || row.Invoices != 3 {
+		t.Errorf("row = %+v, want 0.30 in 0-30 days, 1.00 over 60 and 1.31 in total", row)
+	}
+}
+
+func TestMissingSequences(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []int64
+		last  int64
+		want  []int64
+	}{
+		{"no invoices yet", nil, 0, []int64{}},
+		{"no gaps", []int64{1, 2, 3}, 3, []int64{}},
+		{"gap in the middle", []int64{1, 3, 4}, 4, []int64{2}},
+		{"last number skipped", []int64{1, 2}, 3, []int64{3}},
+		{"out of order", []int64{4, 1}, 4, []int64{2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingSequences(tt.taken, tt.last); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingSequences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTipPoolShares(t *testing.T) {
+	ana, ben := "ana", "ben"
+
+	tests := []struct {
+		name   string
+		rows   []tipRow
+		pool   float64
+		shares []float64
+	}{
+		{"no tips", nil, 0, []float64{}},
+		{
+			name:   "servers only",
+			rows:   []tipRow{{ServerID: &ana, TipTotal: 30}, {ServerID: &ben, TipTotal: 10}},
+			pool:   40,
+			shares: []float64{20, 20},
+		},
+		{
+			name:   "tips without a server go to the servers",
+			rows:   []tipRow{{ServerID: &ana, TipTotal: 30}, {TipTotal: 12}, {ServerID: &ben, TipTotal: 10}},
+			pool:   52,
+			shares: []float64{26, 0, 26},
+		},
+		{
+			name:   "no servers",
+			rows:   []tipRow{{TipTotal: 12}},
+			pool:   12,
+			shares: []float64{0},
+		},
+		{
+			name:   "rounded to the cent",
+			rows:   []tipRow{{ServerID: &ana, TipTotal: 10}, {ServerID: &ben, TipTotal: 0.01}},
+			pool:   10.01,
+			shares: []float64{5.01, 5.01},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, shares := tipPoolShares(tt.rows)
+			if pool != tt.pool {
+				t.Errorf("pool = %.2f, want %.2f", pool, tt.pool)
+			}
+			if !reflect.DeepEqual(shares, tt.shares) {
+				t.Errorf("shares = %v, want %v", shares, tt.shares)
+			}
+		})
+	}
+}
@@ -0,0 +1,516 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var reservationValidate = validator.New()
+
+// reservationTransitions lists the statuses each reservation status can be
+// moved to by hand. Reservations are SEATED by creating their order.
+var reservationTransitions = map[string][]string{
+	"BOOKED":    {"CONFIRMED", "CANCELLED", "NO_SHOW"},
+	"CONFIRMED": {"CANCELLED", "NO_SHOW"},
+}
+
+// holdingStatuses are the reservation statuses that hold a table
+var holdingStatuses = []string{"BOOKED", "CONFIRMED", "SEATED"}
+
+var (
+	errTableNotFound    = errors.New("table was not found")
+	errTableTooSmall    = errors.New("table does not seat the party")
+	errTableBooked      = errors.New("table is already booked at this time")
+	errNoTableAvailable = errors.New("no table is available for this party at this time")
+)
+
+// reservationStatus maps a table booking error to its HTTP status
+func reservationStatus(err error) int {
+	switch {
+	case errors.Is(err, errTableNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errTableTooSmall):
+		return http.StatusBadRequest
+	case errors.Is(err, errTableBooked), errors.Is(err, errNoTableAvailable):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// tableSeats returns the number of seats at a table
+func tableSeats(table models.Table) int {
+	if table.NumberOfGuests == nil {
+		return 0
+	}
+	return *table.NumberOfGuests
+}
+
+// bookedTables returns the tables held by a reservation overlapping the
+// time from start to end, leaving out one reservation being changed
+func bookedTables(ctx context.Context, start time.Time, end time.Time, exclude string) (map[string]bool, error) {
+	var reservations []models.Reservation
+	err := findAll(ctx, getReservationCollection(), bson.M{
+		"table_id":       bson.M{"$ne": nil},
+		"status":         bson.M{"$in": holdingStatuses},
+		"reserved_at":    bson.M{"$lt": end},
+		"ends_at":        bson.M{"$gt": start},
+		"reservation_id": bson.M{"$ne": exclude},
+	}, &reservations)
+	if err != nil {
+		return nil, err
+	}
+
+	booked := map[string]bool{}
+	for _, reservation := range reservations {
+		booked[*reservation.TableID] = true
+	}
+	return booked, nil
+}
+
+// availableTables returns the tables that seat a party and are free from
+// start to end, smallest first so large tables stay free for large parties
+func availableTables(ctx context.Context, partySize int, start time.Time, end time.Time, exclude string) ([]models.Table, error) {
+	var tables []models.Table
+	err := findAll(ctx, getTableCollection(), bson.M{"number_of_guests": bson.M{"$gte": partySize}}, &tables)
+	if err != nil {
+		return nil, err
+	}
+
+	booked, err := bookedTables(ctx, start, end, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	free := []models.Table{}
+	for _, table := range tables {
+		if !booked[table.TableID] {
+			free = append(free, table)
+		}
+	}
+
+	sort.SliceStable(free, func(i, j int) bool {
+		if tableSeats(free[i]) != tableSeats(free[j]) {
+			return tableSeats(free[i]) < tableSeats(free[j])
+		}
+		if free[i].TableNumber == nil || free[j].TableNumber == nil {
+			return free[j].TableNumber == nil && free[i].TableNumber != nil
+		}
+		return *free[i].TableNumber < *free[j].TableNumber
+	})
+	return free, nil
+}
+
+// bookTable checks that a reservation's table seats the party and is free
+// for its time, or gives it the smallest free table when it has none
+func bookTable(ctx context.Context, reservation *models.Reservation) error {
+	if reservation.TableID == nil {
+		free, err := availableTables(ctx, *reservation.PartySize, *reservation.ReservedAt, reservation.EndsAt, reservation.ReservationID)
+		if err != nil {
+			return err
+		}
+		if len(free) == 0 {
+			return errNoTableAvailable
+		}
+		reservation.TableID = &free[0].TableID
+		return nil
+	}
+
+	var table models.Table
+	err := getTableCollection().FindOne(ctx, bson.M{"table_id": reservation.TableID}).Decode(&table)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return errTableNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if tableSeats(table) < *reservation.PartySize {
+		return fmt.Errorf("%w: it seats %d", errTableTooSmall, tableSeats(table))
+	}
+
+	booked, err := bookedTables(ctx, *reservation.ReservedAt, reservation.EndsAt, reservation.ReservationID)
+	if err != nil {
+		return err
+	}
+	if booked[table.TableID] {
+		return errTableBooked
+	}
+	return nil
+}
+
+// reservationEnd is when a reservation stops holding its table
+func reservationEnd(reservation models.Reservation) time.Time {
+	return reservation.ReservedAt.Add(time.Duration(*reservation.Duration) * time.Minute)
+}
+
+// GetReservations returns the reservations of one day (?date=YYYY-MM-DD,
+// default today) in time order, optionally of one status (?status=)
+func GetReservations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		location, ok := reportLocation(c)
+		if !ok {
+			return
+		}
+
+		date := c.DefaultQuery("date", time.Now().In(location).Format("2006-01-02"))
+		day, err := time.ParseInLocation("2006-01-02", date, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+			return
+		}
+
+		filter := bson.M{"reserved_at": bson.M{"$gte": day, "$lt": day.AddDate(0, 0, 1)}}
+		if status := c.Query("status"); status != "" {
+			filter["status"] = status
+		}
+
+		opt := options.Find().SetSort(bson.D{{Key: "reserved_at", Value: 1}})
+
+		var reservations []models.Reservation
+		cursor, err := getReservationCollection().Find(ctx, filter, opt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing reservations"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &reservations); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, reservations)
+	}
+}
+
+// GetReservationByID returns a single reservation by ID
+func GetReservationByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		reservationId := c.Param("reservation_id")
+		var reservation models.Reservation
+
+		err := getReservationCollection().FindOne(ctx, bson.M{"reservation_id": reservationId}).Decode(&reservation)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the reservation"})
+			return
+		}
+
+		c.JSON(http.StatusOK, reservation)
+	}
+}
+
+// GetAvailability returns the tables that seat a party (?party_size=) and
+// are free for a booking at a time (?time=, RFC3339) lasting ?duration=
+// minutes
+func GetAvailability() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		start, err := time.Parse(time.RFC3339, c.Query("time"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "time must be RFC3339"})
+			return
+		}
+
+		partySize, err := strconv.Atoi(c.Query("party_size"))
+		if err != nil || partySize < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "party_size must be a positive number"})
+			return
+		}
+
+		duration := helpers.ReservationDuration()
+		if v := c.Query("duration"); v != "" {
+			duration, err = strconv.Atoi(v)
+			if err != nil || duration < 15 || duration > 720 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be between 15 and 720 minutes"})
+				return
+			}
+		}
+		end := start.Add(time.Duration(duration) * time.Minute)
+
+		free, err := availableTables(ctx, partySize, start, end, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while searching for tables"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"time":       start,
+			"ends_at":    end,
+			"party_size": partySize,
+			"duration":   duration,
+			"tables":     free,
+		})
+	}
+}
+
+// CreateReservation books a table for a party. A reservation without a
+// table gets the smallest free table that seats the party.
+func CreateReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var reservation models.Reservation
+		if err := c.BindJSON(&reservation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := reservationValidate.Struct(reservation)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if reservation.Duration == nil {
+			duration := helpers.ReservationDuration()
+			reservation.Duration = &duration
+		}
+		reservation.EndsAt = reservationEnd(reservation)
+
+		if err := bookTable(ctx, &reservation); err != nil {
+			c.JSON(reservationStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		status := "BOOKED"
+		reservation.Status = &status
+		reservation.OrderID = nil
+		reservation.SeatedAt = nil
+		reservation.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		reservation.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		reservation.ID = primitive.NewObjectID()
+		reservation.ReservationID = reservation.ID.Hex()
+
+		result, insertErr := getReservationCollection().InsertOne(ctx, reservation)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "reservation was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateReservation changes a booked or confirmed reservation. A new time,
+// party size or table is checked against the other bookings.
+func UpdateReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var update models.Reservation
+		reservationId := c.Param("reservation_id")
+
+		if err := c.BindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var reservation models.Reservation
+		err := getReservationCollection().FindOne(ctx, bson.M{"reservation_id": reservationId}).Decode(&reservation)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "reservation was not found"})
+			return
+		}
+
+		current := *reservation.Status
+		if current != "BOOKED" && current != "CONFIRMED" {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("a %s reservation cannot be changed", current)})
+			return
+		}
+
+		if update.PartySize != nil {
+			reservation.PartySize = update.PartySize
+		}
+		if update.ReservedAt != nil {
+			reservation.ReservedAt = update.ReservedAt
+		}
+		if update.Duration != nil {
+			reservation.Duration = update.Duration
+		}
+		if update.CustomerName != nil {
+			reservation.CustomerName = update.CustomerName
+		}
+		if update.CustomerPhone != nil {
+			reservation.CustomerPhone = update.CustomerPhone
+		}
+		if update.CustomerEmail != nil {
+			reservation.CustomerEmail = update.CustomerEmail
+		}
+		if update.Notes != nil {
+			reservation.Notes = update.Notes
+		}
+		if update.TableID != nil {
+			reservation.TableID = update.TableID
+		}
+
+		validationErr := reservationValidate.Struct(reservation)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if reservation.Duration == nil {
+			duration := helpers.ReservationDuration()
+			reservation.Duration = &duration
+		}
+		reservation.EndsAt = reservationEnd(reservation)
+
+		if err := bookTable(ctx, &reservation); err != nil {
+			c.JSON(reservationStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		reservation.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		updateObj := primitive.D{
+			{Key: "party_size", Value: reservation.PartySize},
+			{Key: "reserved_at", Value: reservation.ReservedAt},
+			{Key: "duration", Value: reservation.Duration},
+			{Key: "ends_at", Value: reservation.EndsAt},
+			{Key: "customer_name", Value: reservation.CustomerName},
+			{Key: "customer_phone", Value: reservation.CustomerPhone},
+			{Key: "customer_email", Value: reservation.CustomerEmail},
+			{Key: "notes", Value: reservation.Notes},
+			{Key: "table_id", Value: reservation.TableID},
+			{Key: "updated_at", Value: reservation.UpdatedAt},
+		}
+
+		// The status guard keeps a reservation seated meanwhile unchanged
+		filter := bson.M{"reservation_id": reservationId, "status": current}
+
+		result, err := getReservationCollection().UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "reservation update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "reservation status changed, please retry"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateReservationStatus confirms or cancels a reservation or marks the
+// party as a no-show
+func UpdateReservationStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		reservationId := c.Param("reservation_id")
+
+		var request struct {
+			Status *string `json:"status" validate:"required,eq=BOOKED|eq=CONFIRMED|eq=SEATED|eq=NO_SHOW|eq=CANCELLED"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := reservationValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if *request.Status == "SEATED" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reservations are seated by creating their order with reservation_id"})
+			return
+		}
+
+		var reservation models.Reservation
+		err := getReservationCollection().FindOne(ctx, bson.M{"reservation_id": reservationId}).Decode(&reservation)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "reservation was not found"})
+			return
+		}
+
+		from := *reservation.Status
+		allowed := false
+		for _, next := range reservationTransitions[from] {
+			if next == *request.Status {
+				allowed = true
+			}
+		}
+		if !allowed {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   fmt.Sprintf("reservation cannot go from %s to %s", from, *request.Status),
+				"allowed": reservationTransitions[from],
+			})
+			return
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		result, err := getReservationCollection().UpdateOne(ctx,
+			bson.M{"reservation_id": reservationId, "status": from},
+			bson.M{"$set": bson.M{"status": request.Status, "updated_at": updatedAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "reservation update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "reservation status changed, please retry"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// seatReservation marks a reservation seated and links it to the order
+// opened for the party, at the table they were actually seated at
+func seatReservation(ctx context.Context, reservation models.Reservation, order models.Order) error {
+	result, err := getReservationCollection().UpdateOne(ctx,
+		bson.M{"reservation_id": reservation.ReservationID, "status": bson.M{"$in": []string{"BOOKED", "CONFIRMED"}}},
+		bson.M{"$set": bson.M{
+			"status":     "SEATED",
+			"order_id":   order.OrderID,
+			"table_id":   order.TableID,
+			"seated_at":  order.SeatedAt,
+			"updated_at": order.CreatedAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("reservation status changed while seating")
+	}
+	return nil
+}
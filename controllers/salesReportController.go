@@ -0,0 +1,521 @@
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// salesRow is the invoiced sales of one period. Net sales are after
+// discounts and before tax and service charge; refunds are counted in
+// the period they were made, so a closed period never changes.
+type salesRow struct {
+	Key             interface{} `bson:"_id" json:"-"`
+	Period          string      `bson:"-" json:"period,omitempty"`
+	InvoiceCount    int         `bson:"invoice_count" json:"invoice_count"`
+	Covers          int         `bson:"covers" json:"covers"`
+	GrossSales      float64     `bson:"gross_sales" json:"gross_sales"`
+	Discounts       float64     `bson:"discounts" json:"discounts"`
+	NetSales        float64     `bson:"net_sales" json:"net_sales"`
+	Tax             float64     `bson:"tax" json:"tax"`
+	ServiceCharge   float64     `bson:"service_charge" json:"service_charge"`
+	Total           float64     `bson:"total" json:"total"`
+	Refunds         float64     `bson:"refunds" json:"refunds"`
+	NetTotal        float64     `bson:"-" json:"net_total"`
+	Tips            float64     `bson:"tips" json:"tips"`
+	AverageCheck    float64     `bson:"-" json:"average_check"`
+	AveragePerCover float64     `bson:"-" json:"average_per_cover"`
+}
+
+// finish rounds the totals and works out the averages
+func (r *salesRow) finish() {
+	r.GrossSales = helpers.RoundMoney(r.GrossSales)
+	r.Discounts = helpers.RoundMoney(r.Discounts)
+	r.NetSales = helpers.RoundMoney(r.NetSales)
+	r.Tax = helpers.RoundMoney(r.Tax)
+	r.ServiceCharge = helpers.RoundMoney(r.ServiceCharge)
+	r.Total = helpers.RoundMoney(r.Total)
+	r.Refunds = helpers.RoundMoney(r.Refunds)
+	r.NetTotal = helpers.RoundMoney(r.Total - r.Refunds)
+	r.Tips = helpers.RoundMoney(r.Tips)
+
+	r.AverageCheck = 0
+	if r.InvoiceCount > 0 {
+		r.AverageCheck = helpers.RoundMoney(r.Total / float64(r.InvoiceCount))
+	}
+	r.AveragePerCover = 0
+	if r.Covers > 0 {
+		r.AveragePerCover = helpers.RoundMoney(r.NetSales / float64(r.Covers))
+	}
+}
+
+func (r salesRow) record() []string {
+	return []string{
+		r.Period,
+		strconv.Itoa(r.InvoiceCount),
+		strconv.Itoa(r.Covers),
+		formatMoney(r.GrossSales),
+		formatMoney(r.Discounts),
+		formatMoney(r.NetSales),
+		formatMoney(r.Tax),
+		formatMoney(r.ServiceCharge),
+		formatMoney(r.Total),
+		formatMoney(r.Refunds),
+		formatMoney(r.NetTotal),
+		formatMoney(r.Tips),
+		formatMoney(r.AverageCheck),
+		formatMoney(r.AveragePerCover),
+	}
+}
+
+var salesHeader = []string{
+	"period", "invoice_count", "covers", "gross_sales", "discounts", "net_sales", "tax",
+	"service_charge", "total", "refunds", "net_total", "tips", "average_check", "average_per_cover",
+}
+
+// itemSalesRow is the quantity and line sales of one food or menu category
+type itemSalesRow struct {
+	ID           string  `bson:"_id" json:"id"`
+	Name         string  `bson:"name" json:"name"`
+	Quantity     int     `bson:"quantity" json:"quantity"`
+	Sales        float64 `bson:"sales" json:"sales"`
+	SharePercent float64 `bson:"-" json:"share_percent"`
+}
+
+// salesComparison compares one metric with the prior period
+type salesComparison struct {
+	Metric        string   `json:"metric"`
+	Current       float64  `json:"current"`
+	Previous      float64  `json:"previous"`
+	Change        float64  `json:"change"`
+	ChangePercent *float64 `json:"change_percent"`
+}
+
+func compareSales(metric string, current float64, previous float64) salesComparison {
+	comparison := salesComparison{
+		Metric:   metric,
+		Current:  current,
+		Previous: previous,
+		Change:   helpers.RoundMoney(current - previous),
+	}
+	if previous != 0 {
+		percent := helpers.RoundMoney((current - previous) / previous * 100)
+		comparison.ChangePercent = &percent
+	}
+	return comparison
+}
+
+func formatMoney(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// reportFormat reads the format query parameter: json (default) or csv
+func reportFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return "", false
+	}
+	return format, true
+}
+
+// writeCSV sends a report as a CSV attachment
+func writeCSV(c *gin.Context, name string, header []string, records [][]string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(header)
+	writer.WriteAll(records)
+}
+
+// invoicedBetween matches invoices created in [from, to)
+func invoicedBetween(from time.Time, to time.Time) bson.D {
+	return bson.D{{Key: "$match", Value: bson.D{
+		{Key: "created_at", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: to}}},
+	}}}
+}
+
+// aggregateSales totals the invoices created in [from, to) grouped by key;
+// a nil key gives a single row for the whole range
+func aggregateSales(ctx context.Context, from time.Time, to time.Time, key interface{}) ([]salesRow, error) {
+	groupStage := bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: key},
+		{Key: "invoice_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		{Key: "covers", Value: bson.D{{Key: "$sum", Value: "$covers"}}},
+		{Key: "gross_sales", Value: bson.D{{Key: "$sum", Value: "$sub_total"}}},
+		{Key: "discounts", Value: bson.D{{Key: "$sum", Value: "$discount_total"}}},
+		{Key: "net_sales", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$subtract", Value: bson.A{
+			bson.D{{Key: "$subtract", Value: bson.A{"$sub_total", "$discount_total"}}},
+			bson.D{{Key: "$cond", Value: bson.A{"$tax_inclusive", "$tax_total", 0}}},
+		}}}}}},
+		{Key: "tax", Value: bson.D{{Key: "$sum", Value: "$tax_total"}}},
+		{Key: "service_charge", Value: bson.D{{Key: "$sum", Value: "$service_charge"}}},
+		{Key: "total", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+		{Key: "tips", Value: bson.D{{Key: "$sum", Value: "$tip_total"}}},
+	}}}
+	sortStage := bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}}
+
+	cursor, err := getInvoiceCollection().Aggregate(ctx, mongo.Pipeline{invoicedBetween(from, to), groupStage, sortStage})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rows := []salesRow{}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	refunds, err := aggregateRefunds(ctx, from, to, key)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		if amount, ok := refunds[rows[i].Key]; ok {
+			rows[i].Refunds = amount
+			delete(refunds, rows[i].Key)
+		}
+	}
+	for key, amount := range refunds {
+		rows = append(rows, salesRow{Key: key, Refunds: amount})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return salesKeyLess(rows[i].Key, rows[j].Key)
+	})
+
+	for i := range rows {
+		rows[i].finish()
+	}
+	return rows, nil
+}
+
+// salesKeyLess orders period keys: numbers numerically, dates as text
+func salesKeyLess(a interface{}, b interface{}) bool {
+	x, xOk := a.(int32)
+	y, yOk := b.(int32)
+	if xOk && yOk {
+		return x < y
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// aggregateRefunds totals the refunds made in [from, to) by the same key
+// as aggregateSales. Failed refunds are left out.
+func aggregateRefunds(ctx context.Context, from time.Time, to time.Time, key interface{}) (map[interface{}]float64, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.D{
+			{Key: "created_at", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: to}}},
+			{Key: "status", Value: refundCounted},
+		}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: key},
+			{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		}}},
+	}
+
+	cursor, err := getRefundCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []struct {
+		Key    interface{} `bson:"_id"`
+		Amount float64     `bson:"amount"`
+	}
+	if err = cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+
+	refunds := map[interface{}]float64{}
+	for _, total := range totals {
+		refunds[total.Key] = total.Amount
+	}
+	return refunds, nil
+}
+
+// salesTotal totals the invoices created in [from, to) as one row
+func salesTotal(ctx context.Context, from time.Time, to time.Time) (salesRow, error) {
+	rows, err := aggregateSales(ctx, from, to, nil)
+	if err != nil || len(rows) == 0 {
+		return salesRow{}, err
+	}
+	return rows[0], nil
+}
+
+// GetSalesReport returns the sales totals of a date range with the average
+// check and covers, compared with the period of the same length just
+// before it
+func GetSalesReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+		location, _ := reportLocation(c)
+		format, ok := reportFormat(c)
+		if !ok {
+			return
+		}
+
+		previousFrom := from.Add(-to.Sub(from))
+
+		current, err := salesTotal(ctx, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while building the sales report"})
+			return
+		}
+
+		previous, err := salesTotal(ctx, previousFrom, from)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while building the sales report"})
+			return
+		}
+
+		comparison := []salesComparison{
+			compareSales("invoice_count", float64(current.InvoiceCount), float64(previous.InvoiceCount)),
+			compareSales("covers", float64(current.Covers), float64(previous.Covers)),
+			compareSales("net_sales", current.NetSales, previous.NetSales),
+			compareSales("total", current.Total, previous.Total),
+			compareSales("net_total", current.NetTotal, previous.NetTotal),
+			compareSales("average_check", current.AverageCheck, previous.AverageCheck),
+			compareSales("average_per_cover", current.AveragePerCover, previous.AveragePerCover),
+		}
+
+		if format == "csv" {
+			records := [][]string{}
+			for _, row := range comparison {
+				percent := ""
+				if row.ChangePercent != nil {
+					percent = formatMoney(*row.ChangePercent)
+				}
+				records = append(records, []string{
+					row.Metric, formatMoney(row.Current), formatMoney(row.Previous), formatMoney(row.Change), percent,
+				})
+			}
+			writeCSV(c, "sales", []string{"metric", "current", "previous", "change", "change_percent"}, records)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":          from.In(location),
+			"to":            to.In(location),
+			"timezone":      location.String(),
+			"previous_from": previousFrom.In(location),
+			"previous_to":   from.In(location),
+			"current":       current,
+			"previous":      previous,
+			"comparison":    comparison,
+		})
+	}
+}
+
+// GetSalesByPeriod returns sales grouped by day, hour of the day or day of
+// the week in the report time zone (?group=day|hour|weekday)
+func GetSalesByPeriod() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+		location, _ := reportLocation(c)
+		format, ok := reportFormat(c)
+		if !ok {
+			return
+		}
+
+		date := bson.D{{Key: "date", Value: "$created_at"}, {Key: "timezone", Value: location.String()}}
+
+		group := c.DefaultQuery("group", "day")
+		var key bson.D
+		switch group {
+		case "day":
+			key = bson.D{{Key: "$dateToString", Value: append(bson.D{{Key: "format", Value: "%Y-%m-%d"}}, date...)}}
+		case "hour":
+			key = bson.D{{Key: "$hour", Value: date}}
+		case "weekday":
+			key = bson.D{{Key: "$isoDayOfWeek", Value: date}}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group must be day, hour or weekday"})
+			return
+		}
+
+		rows, err := aggregateSales(ctx, from, to, key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while building the sales report"})
+			return
+		}
+
+		for i := range rows {
+			switch value := rows[i].Key.(type) {
+			case string:
+				rows[i].Period = value
+			case int32:
+				if group == "hour" {
+					rows[i].Period = fmt.Sprintf("%02d:00", value)
+				} else {
+					rows[i].Period = time.Weekday(value % 7).String()
+				}
+			}
+		}
+
+		if format == "csv" {
+			records := [][]string{}
+			for _, row := range rows {
+				records = append(records, row.record())
+			}
+			writeCSV(c, "sales-by-"+group, salesHeader, records)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":     from.In(location),
+			"to":       to.In(location),
+			"timezone": location.String(),
+			"group":    group,
+			"rows":     rows,
+		})
+	}
+}
+
+// aggregateItemSales totals the invoice lines of [from, to) by food or by
+// menu category. Line sales are before order discounts and tax.
+func aggregateItemSales(ctx context.Context, from time.Time, to time.Time, byCategory bool) ([]itemSalesRow, error) {
+	pipeline := mongo.Pipeline{
+		invoicedBetween(from, to),
+		bson.D{{Key: "$unwind", Value: "$lines"}},
+	}
+
+	if byCategory {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: getFoodCollection().Name()},
+				{Key: "localField", Value: "lines.food_id"},
+				{Key: "foreignField", Value: "food_id"},
+				{Key: "as", Value: "food"},
+			}}},
+			bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$food"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+			bson.D{{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: getMenuCollection().Name()},
+				{Key: "localField", Value: "food.menu_id"},
+				{Key: "foreignField", Value: "menu_id"},
+				{Key: "as", Value: "menu"},
+			}}},
+			bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$menu"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$menu.category", "UNCATEGORIZED"}}}},
+				{Key: "name", Value: bson.D{{Key: "$first", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$menu.category", "UNCATEGORIZED"}}}}}},
+				{Key: "quantity", Value: bson.D{{Key: "$sum", Value: "$lines.quantity"}}},
+				{Key: "sales", Value: bson.D{{Key: "$sum", Value: "$lines.line_total"}}},
+			}}},
+		)
+	} else {
+		pipeline = append(pipeline, bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$lines.food_id"},
+			{Key: "name", Value: bson.D{{Key: "$last", Value: "$lines.name"}}},
+			{Key: "quantity", Value: bson.D{{Key: "$sum", Value: "$lines.quantity"}}},
+			{Key: "sales", Value: bson.D{{Key: "$sum", Value: "$lines.line_total"}}},
+		}}})
+	}
+
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: "sales", Value: -1}, {Key: "_id", Value: 1}}}})
+
+	cursor, err := getInvoiceCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rows := []itemSalesRow{}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	var total float64
+	for _, row := range rows {
+		total += row.Sales
+	}
+	for i := range rows {
+		rows[i].Sales = helpers.RoundMoney(rows[i].Sales)
+		if total > 0 {
+			rows[i].SharePercent = helpers.RoundMoney(rows[i].Sales / total * 100)
+		}
+	}
+	return rows, nil
+}
+
+// itemSalesReport returns the handler for sales by food or by category
+func itemSalesReport(byCategory bool) gin.HandlerFunc {
+	name := "sales-by-food"
+	if byCategory {
+		name = "sales-by-category"
+	}
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+		location, _ := reportLocation(c)
+		format, ok := reportFormat(c)
+		if !ok {
+			return
+		}
+
+		rows, err := aggregateItemSales(ctx, from, to, byCategory)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while building the sales report"})
+			return
+		}
+
+		if format == "csv" {
+			records := [][]string{}
+			for _, row := range rows {
+				records = append(records, []string{
+					row.ID, row.Name, strconv.Itoa(row.Quantity), formatMoney(row.Sales), formatMoney(row.SharePercent),
+				})
+			}
+			writeCSV(c, name, []string{"id", "name", "quantity", "sales", "share_percent"}, records)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":     from.In(location),
+			"to":       to.In(location),
+			"timezone": location.String(),
+			"rows":     rows,
+		})
+	}
+}
+
+// GetSalesByFood returns the quantity sold and line sales of each food
+func GetSalesByFood() gin.HandlerFunc {
+	return itemSalesReport(false)
+}
+
+// GetSalesByCategory returns the quantity sold and line sales of each menu
+// category
+func GetSalesByCategory() gin.HandlerFunc {
+	return itemSalesReport(true)
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var sectionValidate = validator.New()
+
+// GetSections returns all sections, optionally of one area (?area_id=)
+func GetSections() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if areaId := c.Query("area_id"); areaId != "" {
+			filter["area_id"] = areaId
+		}
+
+		var sections []models.Section
+		cursor, err := getSectionCollection().Find(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing sections"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &sections); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, sections)
+	}
+}
+
+// GetSectionByID returns a single section by ID
+func GetSectionByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		sectionId := c.Param("section_id")
+		var section models.Section
+
+		err := getSectionCollection().FindOne(ctx, bson.M{"section_id": sectionId}).Decode(&section)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the section"})
+			return
+		}
+
+		c.JSON(http.StatusOK, section)
+	}
+}
+
+// CreateSection creates a new section in an area
+func CreateSection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var section models.Section
+		if err := c.BindJSON(&section); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := sectionValidate.Struct(section)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		count, err := getAreaCollection().CountDocuments(ctx, bson.M{"area_id": section.AreaID})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "area was not found"})
+			return
+		}
+
+		if section.ServerID != nil {
+			count, err := getUserCollection().CountDocuments(ctx, bson.M{"user_id": section.ServerID})
+			if err != nil || count == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "server was not found"})
+				return
+			}
+		}
+
+		section.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		section.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		section.ID = primitive.NewObjectID()
+		section.SectionID = section.ID.Hex()
+
+		result, insertErr := getSectionCollection().InsertOne(ctx, section)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "section was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateSection updates an existing section. Setting server_id to an
+// empty string leaves the section without a server.
+func UpdateSection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var section models.Section
+		sectionId := c.Param("section_id")
+
+		if err := c.BindJSON(&section); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := sectionValidate.StructPartial(section, "Color")
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if section.Name != nil {
+			validationErr := sectionValidate.StructPartial(section, "Name")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "name", Value: section.Name})
+		}
+
+		// Tables keep their own area, so a section cannot move to another one
+		if section.AreaID != nil {
+			count, err := getSectionCollection().CountDocuments(ctx, bson.M{"section_id": sectionId, "area_id": section.AreaID})
+			if err != nil || count == 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "a section cannot move to another area"})
+				return
+			}
+		}
+
+		if section.ServerID != nil {
+			if *section.ServerID == "" {
+				updateObj = append(updateObj, bson.E{Key: "server_id", Value: nil})
+			} else {
+				count, err := getUserCollection().CountDocuments(ctx, bson.M{"user_id": section.ServerID})
+				if err != nil || count == 0 {
+					c.JSON(http.StatusNotFound, gin.H{"error": "server was not found"})
+					return
+				}
+				updateObj = append(updateObj, bson.E{Key: "server_id", Value: section.ServerID})
+			}
+		}
+
+		if section.Color != nil {
+			updateObj = append(updateObj, bson.E{Key: "color", Value: section.Color})
+		}
+
+		section.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: section.UpdatedAt})
+
+		filter := bson.M{"section_id": sectionId}
+
+		result, err := getSectionCollection().UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "section update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var serverValidate = validator.New()
+
+var errServerNotFound = errors.New("server was not found")
+
+// checkServer checks that a server is a known user
+func checkServer(ctx context.Context, serverId string) error {
+	count, err := getUserCollection().CountDocuments(ctx, bson.M{"user_id": serverId})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errServerNotFound
+	}
+	return nil
+}
+
+// assignServer gives a new order its server: the one given, or else the
+// server of the table's section, or else the user who opened the order
+func assignServer(ctx context.Context, order *models.Order, table models.Table) error {
+	if order.ServerID != nil {
+		return checkServer(ctx, *order.ServerID)
+	}
+
+	if table.SectionID != nil {
+		var section models.Section
+		err := getSectionCollection().FindOne(ctx, bson.M{"section_id": table.SectionID}).Decode(&section)
+		if err == nil && section.ServerID != nil {
+			order.ServerID = section.ServerID
+			return nil
+		}
+	}
+
+	if order.CreatedBy != "" {
+		createdBy := order.CreatedBy
+		order.ServerID = &createdBy
+	}
+	return nil
+}
+
+// reassignRequest names the server that takes over a check or table
+type reassignRequest struct {
+	ServerID *string `json:"server_id" validate:"required"`
+}
+
+// bindReassign reads and checks a reassign request
+func bindReassign(ctx context.Context, c *gin.Context) (string, bool) {
+	var request reassignRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+
+	validationErr := serverValidate.Struct(request)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		return "", false
+	}
+
+	if err := checkServer(ctx, *request.ServerID); err != nil {
+		if errors.Is(err, errServerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return "", false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking the server"})
+		return "", false
+	}
+	return *request.ServerID, true
+}
+
+// ReassignOrder hands an open check over to another server. Checks that
+// have been invoiced keep the server they were invoiced under.
+func ReassignOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		serverId, ok := bindReassign(ctx, c)
+		if !ok {
+			return
+		}
+
+		var order models.Order
+		err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		if order.Status == nil || *order.Status != "OPEN" {
+			c.JSON(http.StatusConflict, gin.H{"error": "only open orders can be reassigned"})
+			return
+		}
+
+		count, err := getInvoiceCollection().CountDocuments(ctx, bson.M{"order_id": orderId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for invoice"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "order has already been invoiced"})
+			return
+		}
+
+		order.ServerID = &serverId
+		order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		_, err = getOrderCollection().UpdateOne(ctx,
+			bson.M{"order_id": orderId, "status": "OPEN"},
+			bson.M{"$set": bson.M{"server_id": order.ServerID, "updated_at": order.UpdatedAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// ReassignTable hands every open check at a table, or at its table group,
+// over to another server, for example at the end of a server's shift
+func ReassignTable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tableId := c.Param("table_id")
+
+		serverId, ok := bindReassign(ctx, c)
+		if !ok {
+			return
+		}
+
+		var table models.Table
+		err := getTableCollection().FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+
+		filter := bson.M{"table_id": table.TableID, "status": "OPEN"}
+		if table.TableGroupID != nil {
+			filter = bson.M{
+				"$or":    bson.A{bson.M{"table_id": table.TableID}, bson.M{"table_group_id": table.TableGroupID}},
+				"status": "OPEN",
+			}
+		}
+
+		var orders []models.Order
+		if err := findAll(ctx, getOrderCollection(), filter, &orders); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing orders"})
+			return
+		}
+
+		orderIds := make([]string, len(orders))
+		for i, order := range orders {
+			orderIds[i] = order.OrderID
+		}
+
+		// Invoiced checks keep their server
+		var invoices []models.Invoice
+		if err := findAll(ctx, getInvoiceCollection(), bson.M{"order_id": bson.M{"$in": orderIds}}, &invoices); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing invoices"})
+			return
+		}
+		invoiced := map[string]bool{}
+		for _, invoice := range invoices {
+			invoiced[invoice.OrderID] = true
+		}
+
+		reassigned := []string{}
+		for _, orderId := range orderIds {
+			if !invoiced[orderId] {
+				reassigned = append(reassigned, orderId)
+			}
+		}
+
+		if len(reassigned) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "table has no open check to reassign"})
+			return
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		_, err = getOrderCollection().UpdateMany(ctx,
+			bson.M{"order_id": bson.M{"$in": reassigned}, "status": "OPEN"},
+			bson.M{"$set": bson.M{"server_id": serverId, "updated_at": updatedAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"table_id":  table.TableID,
+			"server_id": serverId,
+			"order_ids": reassigned,
+		})
+	}
+}
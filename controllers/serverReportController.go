@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// serverSalesRow is the invoiced sales of one server, in one shift or over
+// the whole report range. A shift is a service period of one local day.
+type serverSalesRow struct {
+	ServerID        string  `json:"server_id"`
+	ServerName      string  `json:"server_name"`
+	Date            string  `json:"date,omitempty"`
+	Period          string  `json:"period,omitempty"`
+	Checks          int     `json:"checks"`
+	Covers          int     `json:"covers"`
+	NetSales        float64 `json:"net_sales"`
+	Total           float64 `json:"total"`
+	Refunds         float64 `json:"refunds"`
+	Tips            float64 `json:"tips"`
+	AverageCheck    float64 `json:"average_check"`
+	AveragePerCover float64 `json:"average_per_cover"`
+}
+
+func (r *serverSalesRow) add(invoice models.Invoice) {
+	r.Checks++
+	r.Covers += invoice.Covers
+	r.NetSales += invoiceNetSales(invoice)
+	r.Total += invoice.Total
+	r.Refunds += invoice.RefundedTotal
+}
+
+// finish rounds the totals and works out the averages
+func (r *serverSalesRow) finish() {
+	r.NetSales = helpers.RoundMoney(r.NetSales)
+	r.Total = helpers.RoundMoney(r.Total)
+	r.Refunds = helpers.RoundMoney(r.Refunds)
+	r.Tips = helpers.RoundMoney(r.Tips)
+	if r.Checks > 0 {
+		r.AverageCheck = helpers.RoundMoney(r.Total / float64(r.Checks))
+	}
+	if r.Covers > 0 {
+		r.AveragePerCover = helpers.RoundMoney(r.NetSales / float64(r.Covers))
+	}
+}
+
+func (r serverSalesRow) record() []string {
+	return []string{
+		r.ServerID,
+		r.ServerName,
+		r.Date,
+		r.Period,
+		strconv.Itoa(r.Checks),
+		strconv.Itoa(r.Covers),
+		formatMoney(r.NetSales),
+		formatMoney(r.Total),
+		formatMoney(r.Refunds),
+		formatMoney(r.Tips),
+		formatMoney(r.AverageCheck),
+		formatMoney(r.AveragePerCover),
+	}
+}
+
+var serverSalesHeader = []string{
+	"server_id", "server_name", "date", "period", "checks", "covers", "net_sales", "total",
+	"refunds", "tips", "average_check", "average_per_cover",
+}
+
+// orderShift returns the local day and service period an order was opened in
+func orderShift(order models.Order, periods []helpers.ServicePeriod, location *time.Location) (string, string) {
+	period := helpers.ServicePeriodAt(periods, order.CreatedAt, location)
+	if period == "" {
+		period = "OTHER"
+	}
+	return order.CreatedAt.In(location).Format("2006-01-02"), period
+}
+
+// loadServerOrders returns the orders of a set of invoices by order ID
+func loadServerOrders(ctx context.Context, invoices []models.Invoice) (map[string]models.Order, error) {
+	orderIds := make([]string, len(invoices))
+	for i, invoice := range invoices {
+		orderIds[i] = invoice.OrderID
+	}
+
+	var orders []models.Order
+	if err := findAll(ctx, getOrderCollection(), bson.M{"order_id": bson.M{"$in": orderIds}}, &orders); err != nil {
+		return nil, err
+	}
+
+	byId := map[string]models.Order{}
+	for _, order := range orders {
+		byId[order.OrderID] = order
+	}
+	return byId, nil
+}
+
+// GetServerReport returns the checks, covers, sales and tips of each server
+// for the invoices of a date range, per shift and in total. Tips go to the
+// server they were credited to, in the shift of the order they were left on.
+func GetServerReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+		location, _ := reportLocation(c)
+		format, ok := reportFormat(c)
+		if !ok {
+			return
+		}
+
+		var invoices []models.Invoice
+		err := findAll(ctx, getInvoiceCollection(), bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}, &invoices)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing invoices"})
+			return
+		}
+
+		orders, err := loadServerOrders(ctx, invoices)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing orders"})
+			return
+		}
+
+		invoiceIds := make([]string, len(invoices))
+		for i, invoice := range invoices {
+			invoiceIds[i] = invoice.InvoiceID
+		}
+
+		var tips []models.Tip
+		if err := findAll(ctx, getTipCollection(), bson.M{"invoice_id": bson.M{"$in": invoiceIds}}, &tips); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing tips"})
+			return
+		}
+
+		periods := helpers.ServicePeriods()
+		shiftRows := map[[3]string]*serverSalesRow{}
+		serverRows := map[string]*serverSalesRow{}
+		row := func(serverId string, order models.Order) (*serverSalesRow, *serverSalesRow) {
+			date, period := orderShift(order, periods, location)
+			key := [3]string{serverId, date, period}
+			shift, ok := shiftRows[key]
+			if !ok {
+				shift = &serverSalesRow{ServerID: serverId, Date: date, Period: period}
+				shiftRows[key] = shift
+			}
+			server, ok := serverRows[serverId]
+			if !ok {
+				server = &serverSalesRow{ServerID: serverId}
+				serverRows[serverId] = server
+			}
+			return shift, server
+		}
+
+		// Invoices from before servers were recorded have no server
+		for _, invoice := range invoices {
+			serverId := ""
+			if invoice.ServerID != nil {
+				serverId = *invoice.ServerID
+			}
+			shift, server := row(serverId, orders[invoice.OrderID])
+			shift.add(invoice)
+			server.add(invoice)
+		}
+
+		for _, tip := range tips {
+			if tip.Amount == nil {
+				continue
+			}
+			serverId := ""
+			if tip.ServerID != nil {
+				serverId = *tip.ServerID
+			}
+			shift, server := row(serverId, orders[tip.OrderID])
+			shift.Tips += *tip.Amount
+			server.Tips += *tip.Amount
+		}
+
+		ids := make([]string, 0, len(serverRows))
+		for serverId := range serverRows {
+			ids = append(ids, serverId)
+		}
+		names := serverNames(ctx, ids)
+
+		// Periods keep their configured order within a day
+		periodOrder := map[string]int{"OTHER": len(periods)}
+		for i, period := range periods {
+			periodOrder[period.Name] = i
+		}
+
+		byShift := []serverSalesRow{}
+		for _, shift := range shiftRows {
+			shift.ServerName = names[shift.ServerID]
+			shift.finish()
+			byShift = append(byShift, *shift)
+		}
+		sort.Slice(byShift, func(i, j int) bool {
+			if byShift[i].Date != byShift[j].Date {
+				return byShift[i].Date < byShift[j].Date
+			}
+			if byShift[i].Period != byShift[j].Period {
+				return periodOrder[byShift[i].Period] < periodOrder[byShift[j].Period]
+			}
+			return byShift[i].NetSales > byShift[j].NetSales
+		})
+
+		byServer := []serverSalesRow{}
+		for _, server := range serverRows {
+			server.ServerName = names[server.ServerID]
+			server.finish()
+			byServer = append(byServer, *server)
+		}
+		sort.Slice(byServer, func(i, j int) bool { return byServer[i].NetSales > byServer[j].NetSales })
+
+		if format == "csv" {
+			records := [][]string{}
+			for _, row := range byShift {
+				records = append(records, row.record())
+			}
+			writeCSV(c, "servers", serverSalesHeader, records)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":     from.In(location),
+			"to":       to.In(location),
+			"timezone": location.String(),
+			"servers":  byServer,
+			"by_shift": byShift,
+		})
+	}
+}
@@ -0,0 +1,539 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var shiftValidate = validator.New()
+
+// maxShiftLength is the longest shift that can be scheduled
+const maxShiftLength = 16 * time.Hour
+
+var (
+	errStaffNotFound         = errors.New("staff member was not found")
+	errShiftTemplateNotFound = errors.New("shift template was not found")
+	errShiftTimes            = errors.New("a shift needs a template and a date, or starts_at and ends_at")
+	errShiftLength           = errors.New("a shift must end after it starts and last at most 16 hours")
+	errShiftOverlap          = errors.New("staff member already has a shift at that time")
+)
+
+// shiftStatus maps a scheduling error to its HTTP status
+func shiftStatus(err error) int {
+	switch {
+	case errors.Is(err, errStaffNotFound), errors.Is(err, errShiftTemplateNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errShiftTimes), errors.Is(err, errShiftLength):
+		return http.StatusBadRequest
+	case errors.Is(err, errShiftOverlap):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// requireManager refuses the request unless the signed-in user is a
+// manager or admin
+func requireManager(ctx context.Context, c *gin.Context) bool {
+	if !userHasRole(ctx, c.GetString("uid"), "ADMIN", "MANAGER") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only managers can change the schedule"})
+		return false
+	}
+	return true
+}
+
+// clockWindow returns the start and end of an "HH:MM" to "HH:MM" window on
+// a local day. A window that ends before it starts runs past midnight.
+func clockWindow(day time.Time, start string, end string) (time.Time, time.Time) {
+	startClock, _ := time.Parse("15:04", start)
+	endClock, _ := time.Parse("15:04", end)
+
+	startsAt := time.Date(day.Year(), day.Month(), day.Day(), startClock.Hour(), startClock.Minute(), 0, 0, day.Location())
+	endsAt := time.Date(day.Year(), day.Month(), day.Day(), endClock.Hour(), endClock.Minute(), 0, 0, day.Location())
+	if !endsAt.After(startsAt) {
+		endsAt = endsAt.AddDate(0, 0, 1)
+	}
+	return startsAt, endsAt
+}
+
+// placeShift works out a shift's times, from its template and date or from
+// its own start and end, and checks the staff member is free
+func placeShift(ctx context.Context, shift *models.Shift) error {
+	location := helpers.RestaurantLocation()
+
+	count, err := getUserCollection().CountDocuments(ctx, bson.M{"user_id": shift.UserID})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errStaffNotFound
+	}
+
+	if shift.ShiftTemplateID != nil && shift.StartsAt == nil {
+		if shift.Date == nil {
+			return errShiftTimes
+		}
+
+		var template models.ShiftTemplate
+		err := getShiftTemplateCollection().FindOne(ctx, bson.M{"shift_template_id": shift.ShiftTemplateID}).Decode(&template)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return errShiftTemplateNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		day, _ := time.ParseInLocation("2006-01-02", *shift.Date, location)
+		startsAt, endsAt := clockWindow(day, *template.StartTime, *template.EndTime)
+		shift.StartsAt = &startsAt
+		shift.EndsAt = &endsAt
+		if shift.Position == nil {
+			shift.Position = template.Position
+		}
+		if shift.BreakMinutes == 0 {
+			shift.BreakMinutes = template.BreakMinutes
+		}
+	}
+
+	if shift.StartsAt == nil || shift.EndsAt == nil {
+		return errShiftTimes
+	}
+
+	length := shift.EndsAt.Sub(*shift.StartsAt)
+	if length <= 0 || length > maxShiftLength || time.Duration(shift.BreakMinutes)*time.Minute >= length {
+		return errShiftLength
+	}
+
+	date := shift.StartsAt.In(location).Format("2006-01-02")
+	shift.Date = &date
+
+	count, err = getShiftCollection().CountDocuments(ctx, bson.M{
+		"user_id":   shift.UserID,
+		"status":    "SCHEDULED",
+		"shift_id":  bson.M{"$ne": shift.ShiftID},
+		"starts_at": bson.M{"$lt": shift.EndsAt},
+		"ends_at":   bson.M{"$gt": shift.StartsAt},
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errShiftOverlap
+	}
+	return nil
+}
+
+// scheduledHours is a shift's length less its planned break
+func scheduledHours(shift models.Shift) float64 {
+	if shift.StartsAt == nil || shift.EndsAt == nil {
+		return 0
+	}
+	return shift.EndsAt.Sub(*shift.StartsAt).Hours() - float64(shift.BreakMinutes)/60
+}
+
+// GetShiftTemplates returns all shift templates
+func GetShiftTemplates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var templates []models.ShiftTemplate
+		cursor, err := getShiftTemplateCollection().Find(ctx, bson.M{},
+			options.Find().SetSort(bson.D{{Key: "start_time", Value: 1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing shift templates"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &templates); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, templates)
+	}
+}
+
+// CreateShiftTemplate creates a new shift template (managers only)
+func CreateShiftTemplate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !requireManager(ctx, c) {
+			return
+		}
+
+		var template models.ShiftTemplate
+		if err := c.BindJSON(&template); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := shiftValidate.Struct(template)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		template.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		template.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		template.ID = primitive.NewObjectID()
+		template.ShiftTemplateID = template.ID.Hex()
+
+		_, insertErr := getShiftTemplateCollection().InsertOne(ctx, template)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "shift template was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, template)
+	}
+}
+
+// UpdateShiftTemplate updates a shift template (managers only). Shifts
+// already scheduled from it keep their times.
+func UpdateShiftTemplate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !requireManager(ctx, c) {
+			return
+		}
+
+		templateId := c.Param("shift_template_id")
+
+		var update models.ShiftTemplate
+		if err := c.BindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var template models.ShiftTemplate
+		err := getShiftTemplateCollection().FindOne(ctx, bson.M{"shift_template_id": templateId}).Decode(&template)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shift template was not found"})
+			return
+		}
+
+		if update.Name != nil {
+			template.Name = update.Name
+		}
+		if update.Position != nil {
+			template.Position = update.Position
+		}
+		if update.StartTime != nil {
+			template.StartTime = update.StartTime
+		}
+		if update.EndTime != nil {
+			template.EndTime = update.EndTime
+		}
+		if update.BreakMinutes != 0 {
+			template.BreakMinutes = update.BreakMinutes
+		}
+
+		validationErr := shiftValidate.Struct(template)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		template.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		var updateObj primitive.D
+		updateObj = append(updateObj, bson.E{Key: "name", Value: template.Name})
+		updateObj = append(updateObj, bson.E{Key: "position", Value: template.Position})
+		updateObj = append(updateObj, bson.E{Key: "start_time", Value: template.StartTime})
+		updateObj = append(updateObj, bson.E{Key: "end_time", Value: template.EndTime})
+		updateObj = append(updateObj, bson.E{Key: "break_minutes", Value: template.BreakMinutes})
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: template.UpdatedAt})
+
+		_, err = getShiftTemplateCollection().UpdateOne(ctx,
+			bson.M{"shift_template_id": templateId},
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "shift template update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, template)
+	}
+}
+
+// staffSchedule is one staff member's shifts in a week
+type staffSchedule struct {
+	UserID         string         `json:"user_id"`
+	Name           string         `json:"name"`
+	Shifts         []models.Shift `json:"shifts"`
+	ScheduledHours float64        `json:"scheduled_hours"`
+}
+
+// GetSchedule returns the scheduled shifts of a week starting on Monday
+// (?week= any date in the week, default this week) for each staff member,
+// or for one (?user_id=)
+func GetSchedule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		location, ok := reportLocation(c)
+		if !ok {
+			return
+		}
+
+		day := time.Now().In(location)
+		if week := c.Query("week"); week != "" {
+			var err error
+			day, err = time.ParseInLocation("2006-01-02", week, location)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "week must be YYYY-MM-DD"})
+				return
+			}
+		}
+		weekStart := helpers.WeekStart(day, location)
+		weekEnd := weekStart.AddDate(0, 0, 7)
+
+		filter := bson.M{"status": "SCHEDULED", "starts_at": bson.M{"$gte": weekStart, "$lt": weekEnd}}
+		if userId := c.Query("user_id"); userId != "" {
+			filter["user_id"] = userId
+		}
+
+		var shifts []models.Shift
+		cursor, err := getShiftCollection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "starts_at", Value: 1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing shifts"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &shifts); err != nil {
+			log.Fatal(err)
+		}
+
+		byStaff := map[string]*staffSchedule{}
+		var ids []string
+		for _, shift := range shifts {
+			schedule, ok := byStaff[*shift.UserID]
+			if !ok {
+				schedule = &staffSchedule{UserID: *shift.UserID, Shifts: []models.Shift{}}
+				byStaff[*shift.UserID] = schedule
+				ids = append(ids, *shift.UserID)
+			}
+			schedule.Shifts = append(schedule.Shifts, shift)
+			schedule.ScheduledHours += scheduledHours(shift)
+		}
+
+		names := serverNames(ctx, ids)
+		staff := []staffSchedule{}
+		var total float64
+		for _, schedule := range byStaff {
+			schedule.Name = names[schedule.UserID]
+			schedule.ScheduledHours = helpers.RoundMoney(schedule.ScheduledHours)
+			total += schedule.ScheduledHours
+			staff = append(staff, *schedule)
+		}
+		sort.Slice(staff, func(i, j int) bool { return staff[i].Name < staff[j].Name })
+
+		c.JSON(http.StatusOK, gin.H{
+			"week_start":      weekStart,
+			"week_end":        weekEnd,
+			"timezone":        location.String(),
+			"scheduled_hours": helpers.RoundMoney(total),
+			"staff":           staff,
+		})
+	}
+}
+
+// GetShift returns a single shift by ID
+func GetShift() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		shiftId := c.Param("shift_id")
+		var shift models.Shift
+
+		err := getShiftCollection().FindOne(ctx, bson.M{"shift_id": shiftId}).Decode(&shift)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the shift"})
+			return
+		}
+
+		c.JSON(http.StatusOK, shift)
+	}
+}
+
+// CreateShift schedules a shift for a staff member (managers only), from a
+// template and a date or by its start and end times
+func CreateShift() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !requireManager(ctx, c) {
+			return
+		}
+
+		var shift models.Shift
+		if err := c.BindJSON(&shift); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := shiftValidate.Struct(shift)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		shift.ShiftID = ""
+		if err := placeShift(ctx, &shift); err != nil {
+			c.JSON(shiftStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		shift.Status = "SCHEDULED"
+		shift.CreatedBy = c.GetString("uid")
+		shift.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		shift.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		shift.ID = primitive.NewObjectID()
+		shift.ShiftID = shift.ID.Hex()
+
+		_, insertErr := getShiftCollection().InsertOne(ctx, shift)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "shift was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, shift)
+	}
+}
+
+// UpdateShift moves a scheduled shift or hands it to someone else
+// (managers only)
+func UpdateShift() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !requireManager(ctx, c) {
+			return
+		}
+
+		shiftId := c.Param("shift_id")
+
+		var update models.Shift
+		if err := c.BindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var shift models.Shift
+		err := getShiftCollection().FindOne(ctx, bson.M{"shift_id": shiftId}).Decode(&shift)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "shift was not found"})
+			return
+		}
+
+		if shift.Status != "SCHEDULED" {
+			c.JSON(http.StatusConflict, gin.H{"error": "a cancelled shift cannot be changed"})
+			return
+		}
+
+		if update.UserID != nil {
+			shift.UserID = update.UserID
+		}
+		if update.Position != nil {
+			shift.Position = update.Position
+		}
+		if update.StartsAt != nil {
+			shift.StartsAt = update.StartsAt
+		}
+		if update.EndsAt != nil {
+			shift.EndsAt = update.EndsAt
+		}
+		if update.BreakMinutes != 0 {
+			shift.BreakMinutes = update.BreakMinutes
+		}
+		if update.Notes != nil {
+			shift.Notes = update.Notes
+		}
+
+		validationErr := shiftValidate.Struct(shift)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if err := placeShift(ctx, &shift); err != nil {
+			c.JSON(shiftStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		shift.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		var updateObj primitive.D
+		updateObj = append(updateObj, bson.E{Key: "user_id", Value: shift.UserID})
+		updateObj = append(updateObj, bson.E{Key: "position", Value: shift.Position})
+		updateObj = append(updateObj, bson.E{Key: "date", Value: shift.Date})
+		updateObj = append(updateObj, bson.E{Key: "starts_at", Value: shift.StartsAt})
+		updateObj = append(updateObj, bson.E{Key: "ends_at", Value: shift.EndsAt})
+		updateObj = append(updateObj, bson.E{Key: "break_minutes", Value: shift.BreakMinutes})
+		updateObj = append(updateObj, bson.E{Key: "notes", Value: shift.Notes})
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: shift.UpdatedAt})
+
+		_, err = getShiftCollection().UpdateOne(ctx,
+			bson.M{"shift_id": shiftId, "status": "SCHEDULED"},
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "shift update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, shift)
+	}
+}
+
+// CancelShift takes a shift off the schedule (managers only)
+func CancelShift() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !requireManager(ctx, c) {
+			return
+		}
+
+		shiftId := c.Param("shift_id")
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		var shift models.Shift
+		err := getShiftCollection().FindOneAndUpdate(ctx,
+			bson.M{"shift_id": shiftId, "status": "SCHEDULED"},
+			bson.M{"$set": bson.M{"status": "CANCELLED", "updated_at": updatedAt}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&shift)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "shift is not scheduled"})
+			return
+		}
+
+		c.JSON(http.StatusOK, shift)
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var tableValidate = validator.New()
@@ -75,8 +74,16 @@ func CreateTable() gin.HandlerFunc {
 			return
 		}
 
+		if err := checkTablePlacement(ctx, &table); err != nil {
+			c.JSON(placementStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		status := "AVAILABLE"
+		table.Status = &status
 		table.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		table.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		table.StatusChangedAt = &table.CreatedAt
 		table.ID = primitive.NewObjectID()
 		table.TableID = table.ID.Hex()
 
@@ -90,44 +97,49 @@ func CreateTable() gin.HandlerFunc {
 	}
 }
 
-// UpdateTable updates an existing table
+// UpdateTable updates an existing table's number, seats and layout. The
+// table's section must be in its area and its number unused in that area.
 func UpdateTable() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
 
-		var table models.Table
+		var update models.Table
 		tableId := c.Param("table_id")
 
-		if err := c.BindJSON(&table); err != nil {
+		if err := c.BindJSON(&update); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		var updateObj primitive.D
+		var table models.Table
+		err := getTableCollection().FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+
+		mergeTableLayout(&table, update)
 
-		if table.NumberOfGuests != nil {
-			updateObj = append(updateObj, bson.E{Key: "number_of_guests", Value: table.NumberOfGuests})
+		validationErr := tableValidate.Struct(table)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
 		}
 
-		if table.TableNumber != nil {
-			updateObj = append(updateObj, bson.E{Key: "table_number", Value: table.TableNumber})
+		if err := checkTablePlacement(ctx, &table); err != nil {
+			c.JSON(placementStatus(err), gin.H{"error": err.Error()})
+			return
 		}
 
 		table.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: table.UpdatedAt})
 
-		upsert := true
 		filter := bson.M{"table_id": tableId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
 
 		result, err := getTableCollection().UpdateOne(
 			ctx,
 			filter,
-			bson.D{{Key: "$set", Value: updateObj}},
-			&opt,
+			bson.M{"$set": layoutUpdate(table)},
 		)
 
 		if err != nil {
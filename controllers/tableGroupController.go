@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tableGroupValidate = validator.New()
+
+// groupTables returns the tables of a table group, primary table first
+func groupTables(ctx context.Context, groupId string) ([]models.Table, error) {
+	var group models.TableGroup
+	if err := getTableGroupCollection().FindOne(ctx, bson.M{"group_id": groupId}).Decode(&group); err != nil {
+		return nil, err
+	}
+
+	var tables []models.Table
+	if err := findAll(ctx, getTableCollection(), bson.M{"table_id": bson.M{"$in": group.TableIDs}}, &tables); err != nil {
+		return nil, err
+	}
+
+	for i, table := range tables {
+		if table.TableID == group.PrimaryTableID {
+			tables[0], tables[i] = tables[i], tables[0]
+		}
+	}
+	return tables, nil
+}
+
+// orderTables returns the tables a dine-in order's party sits at: the
+// tables of its group, or its one table
+func orderTables(ctx context.Context, order models.Order) []models.Table {
+	if order.TableGroupID != nil {
+		tables, err := groupTables(ctx, *order.TableGroupID)
+		if err == nil && len(tables) > 0 {
+			return tables
+		}
+	}
+
+	var table models.Table
+	if order.TableID == nil || getTableCollection().FindOne(ctx, bson.M{"table_id": order.TableID}).Decode(&table) != nil {
+		return nil
+	}
+	return []models.Table{table}
+}
+
+// seatingCapacity returns the seats of a table, or of its whole group when
+// it is pushed together with others
+func seatingCapacity(ctx context.Context, table models.Table) int {
+	if table.TableGroupID != nil {
+		var group models.TableGroup
+		if getTableGroupCollection().FindOne(ctx, bson.M{"group_id": table.TableGroupID}).Decode(&group) == nil {
+			return group.Seats
+		}
+	}
+	return tableSeats(table)
+}
+
+// dissolveGroup ends an active table group and frees its tables to be
+// used on their own again
+func dissolveGroup(ctx context.Context, groupId string) error {
+	dissolvedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := getTableGroupCollection().UpdateOne(ctx,
+		bson.M{"group_id": groupId, "status": "ACTIVE"},
+		bson.M{"$set": bson.M{"status": "DISSOLVED", "dissolved_at": dissolvedAt, "updated_at": dissolvedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("table group is not active")
+	}
+
+	_, err = getTableCollection().UpdateMany(ctx,
+		bson.M{"table_group_id": groupId},
+		bson.M{"$set": bson.M{"table_group_id": nil, "updated_at": dissolvedAt}},
+	)
+	return err
+}
+
+// closeOrderGroup dissolves the table group of an order whose check has
+// been closed, once no other check on the group is open
+func closeOrderGroup(ctx context.Context, order models.Order) {
+	if order.TableGroupID == nil {
+		return
+	}
+
+	count, err := getOrderCollection().CountDocuments(ctx, bson.M{
+		"table_group_id": order.TableGroupID,
+		"status":         "OPEN",
+		"order_id":       bson.M{"$ne": order.OrderID},
+	})
+	if err != nil || count > 0 {
+		return
+	}
+
+	if err := dissolveGroup(ctx, *order.TableGroupID); err != nil {
+		log.Println("table group was not dissolved:", err)
+	}
+}
+
+// GetTableGroups returns the active table groups, or those of another
+// status (?status=DISSOLVED)
+func GetTableGroups() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		status := c.DefaultQuery("status", "ACTIVE")
+
+		var groups []models.TableGroup
+		cursor, err := getTableGroupCollection().Find(ctx, bson.M{"status": status})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing table groups"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &groups); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, groups)
+	}
+}
+
+// GetTableGroup returns a single table group by ID
+func GetTableGroup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		groupId := c.Param("group_id")
+		var group models.TableGroup
+
+		err := getTableGroupCollection().FindOne(ctx, bson.M{"group_id": groupId}).Decode(&group)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the table group"})
+			return
+		}
+
+		c.JSON(http.StatusOK, group)
+	}
+}
+
+// CreateTableGroup pushes free tables of one area together for a large
+// party. The first table is the group's primary table, which its orders
+// are placed at.
+func CreateTableGroup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var group models.TableGroup
+		if err := c.BindJSON(&group); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := tableGroupValidate.Struct(group)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var tables []models.Table
+		if err := findAll(ctx, getTableCollection(), bson.M{"table_id": bson.M{"$in": group.TableIDs}}, &tables); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing tables"})
+			return
+		}
+
+		if len(tables) != len(group.TableIDs) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+
+		byId := map[string]models.Table{}
+		for _, table := range tables {
+			byId[table.TableID] = table
+		}
+
+		var area *string
+		var numbers []string
+		group.Seats = 0
+		for i, tableId := range group.TableIDs {
+			table := byId[tableId]
+
+			if table.TableGroupID != nil {
+				c.JSON(http.StatusConflict, gin.H{"error": "table is already in a group", "table_id": tableId})
+				return
+			}
+
+			if status := tableStatus(table); status != "AVAILABLE" && status != "RESERVED" {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("table is %s", status), "table_id": tableId})
+				return
+			}
+
+			if i == 0 {
+				area = table.AreaID
+			} else if (area == nil) != (table.AreaID == nil) || (area != nil && *area != *table.AreaID) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "grouped tables must be in the same area"})
+				return
+			}
+
+			group.Seats += tableSeats(table)
+			if table.TableNumber != nil {
+				numbers = append(numbers, fmt.Sprintf("%d", *table.TableNumber))
+			}
+		}
+
+		if group.Name == nil || *group.Name == "" {
+			name := strings.Join(numbers, "+")
+			group.Name = &name
+		}
+
+		group.Status = "ACTIVE"
+		group.PrimaryTableID = group.TableIDs[0]
+		group.DissolvedAt = nil
+		group.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		group.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		group.ID = primitive.NewObjectID()
+		group.GroupID = group.ID.Hex()
+
+		_, insertErr := getTableGroupCollection().InsertOne(ctx, group)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "table group was not created"})
+			return
+		}
+
+		// Only tables still outside any group are taken; if another group
+		// took one meanwhile this one is dissolved again
+		result, err := getTableCollection().UpdateMany(ctx,
+			bson.M{"table_id": bson.M{"$in": group.TableIDs}, "table_group_id": nil},
+			bson.M{"$set": bson.M{"table_group_id": group.GroupID, "updated_at": group.UpdatedAt}},
+		)
+		if err != nil || result.ModifiedCount != int64(len(group.TableIDs)) {
+			if err := dissolveGroup(ctx, group.GroupID); err != nil {
+				log.Println("table group was not dissolved:", err)
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "a table was grouped meanwhile, please retry"})
+			return
+		}
+
+		c.JSON(http.StatusOK, group)
+	}
+}
+
+// DissolveTableGroup separates the tables of a group that has no open check
+func DissolveTableGroup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		groupId := c.Param("group_id")
+
+		count, err := getOrderCollection().CountDocuments(ctx, bson.M{"table_group_id": groupId, "status": "OPEN"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for orders"})
+			return
+		}
+
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "table group has an open check"})
+			return
+		}
+
+		if err := dissolveGroup(ctx, groupId); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		var group models.TableGroup
+		err = getTableGroupCollection().FindOne(ctx, bson.M{"group_id": groupId}).Decode(&group)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the table group"})
+			return
+		}
+
+		c.JSON(http.StatusOK, group)
+	}
+}
@@ -0,0 +1,241 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// tableVisit is one party's stay at a table
+type tableVisit struct {
+	TableID   string
+	PartySize int
+	Period    string
+	Dwell     time.Duration
+	Revenue   float64
+}
+
+// turnoverRow totals the visits of one table, party size or service period
+type turnoverRow struct {
+	TableID       string  `json:"table_id,omitempty"`
+	TableNumber   *int    `json:"table_number,omitempty"`
+	PartySize     int     `json:"party_size,omitempty"`
+	Period        string  `json:"period,omitempty"`
+	Seats         int     `json:"seats,omitempty"`
+	Visits        int     `json:"visits"`
+	Covers        int     `json:"covers"`
+	Revenue       float64 `json:"revenue"`
+	AverageDwell  float64 `json:"average_dwell_minutes"`
+	TurnsPerTable float64 `json:"turns_per_table,omitempty"`
+	SeatHours     float64 `json:"seat_hours,omitempty"`
+	RevPASH       float64 `json:"revpash,omitempty"`
+	dwellMinutes  float64
+}
+
+func (r *turnoverRow) add(visit tableVisit) {
+	r.Visits++
+	r.Covers += visit.PartySize
+	r.Revenue += visit.Revenue
+	r.dwellMinutes += visit.Dwell.Minutes()
+}
+
+func (r *turnoverRow) finish() {
+	r.Revenue = helpers.RoundMoney(r.Revenue)
+	if r.Visits > 0 {
+		r.AverageDwell = helpers.RoundMoney(r.dwellMinutes / float64(r.Visits))
+	}
+	if r.SeatHours > 0 {
+		r.SeatHours = helpers.RoundMoney(r.SeatHours)
+		r.RevPASH = helpers.RoundMoney(r.Revenue / r.SeatHours)
+	}
+}
+
+// invoiceNetSales is the revenue of an invoice after discounts and before
+// tax and service charge
+func invoiceNetSales(invoice models.Invoice) float64 {
+	net := invoice.SubTotal - invoice.DiscountTotal
+	if invoice.TaxInclusive {
+		net -= invoice.TaxTotal
+	}
+	return net
+}
+
+// GetTableTurnoverReport returns how long parties stayed at the tables,
+// seated in a date range, by table, party size and service period, with
+// turns per table and revenue per available seat hour (RevPASH)
+func GetTableTurnoverReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+		location, _ := reportLocation(c)
+
+		var tables []models.Table
+		if err := findAll(ctx, getTableCollection(), bson.M{}, &tables); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing tables"})
+			return
+		}
+
+		var orders []models.Order
+		err := findAll(ctx, getOrderCollection(), bson.M{
+			"seated_at":   bson.M{"$gte": from, "$lt": to},
+			"unseated_at": bson.M{"$ne": nil},
+			"status":      bson.M{"$ne": "VOID"},
+		}, &orders)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing orders"})
+			return
+		}
+
+		orderIds := make([]string, len(orders))
+		for i, order := range orders {
+			orderIds[i] = order.OrderID
+		}
+
+		var invoices []models.Invoice
+		if err := findAll(ctx, getInvoiceCollection(), bson.M{"order_id": bson.M{"$in": orderIds}}, &invoices); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing invoices"})
+			return
+		}
+
+		revenue := map[string]float64{}
+		for _, invoice := range invoices {
+			revenue[invoice.OrderID] += invoiceNetSales(invoice)
+		}
+
+		tableRows := map[string]*turnoverRow{}
+		seats := 0
+		for _, table := range tables {
+			row := &turnoverRow{TableID: table.TableID, TableNumber: table.TableNumber}
+			if table.NumberOfGuests != nil {
+				row.Seats = *table.NumberOfGuests
+				seats += row.Seats
+			}
+			tableRows[table.TableID] = row
+		}
+
+		periods := helpers.ServicePeriods()
+		periodRows := map[string]*turnoverRow{}
+		for _, period := range periods {
+			hours := period.Overlap(from, to, location).Hours()
+			periodRows[period.Name] = &turnoverRow{Period: period.Name, SeatHours: hours * float64(seats)}
+		}
+
+		partyRows := map[int]*turnoverRow{}
+		var totals turnoverRow
+		for _, order := range orders {
+			if order.TableID == nil || order.SeatedAt == nil || order.UnseatedAt == nil {
+				continue
+			}
+
+			visit := tableVisit{
+				TableID: *order.TableID,
+				Period:  helpers.ServicePeriodAt(periods, *order.SeatedAt, location),
+				Dwell:   order.UnseatedAt.Sub(*order.SeatedAt),
+				Revenue: revenue[order.OrderID],
+			}
+
+			table, ok := tableRows[visit.TableID]
+			if !ok {
+				table = &turnoverRow{TableID: visit.TableID}
+				tableRows[visit.TableID] = table
+			}
+
+			visit.PartySize = table.Seats
+			if order.NumberOfGuests != nil {
+				visit.PartySize = *order.NumberOfGuests
+			}
+
+			table.add(visit)
+			totals.add(visit)
+
+			party, ok := partyRows[visit.PartySize]
+			if !ok {
+				party = &turnoverRow{PartySize: visit.PartySize}
+				partyRows[visit.PartySize] = party
+			}
+			party.add(visit)
+
+			if visit.Period == "" {
+				visit.Period = "OTHER"
+			}
+			period, ok := periodRows[visit.Period]
+			if !ok {
+				period = &turnoverRow{Period: visit.Period}
+				periodRows[visit.Period] = period
+			}
+			period.add(visit)
+		}
+
+		byTable := []turnoverRow{}
+		for _, row := range tableRows {
+			row.finish()
+			byTable = append(byTable, *row)
+		}
+		sort.Slice(byTable, func(i, j int) bool {
+			if byTable[i].TableNumber == nil || byTable[j].TableNumber == nil {
+				return byTable[j].TableNumber == nil && byTable[i].TableNumber != nil
+			}
+			return *byTable[i].TableNumber < *byTable[j].TableNumber
+		})
+
+		byPartySize := []turnoverRow{}
+		for _, row := range partyRows {
+			row.finish()
+			byPartySize = append(byPartySize, *row)
+		}
+		sort.Slice(byPartySize, func(i, j int) bool { return byPartySize[i].PartySize < byPartySize[j].PartySize })
+
+		// Periods keep their configured order, with visits outside every
+		// period last
+		byPeriod := []turnoverRow{}
+		var periodRevenue float64
+		for _, period := range periods {
+			if row, ok := periodRows[period.Name]; ok {
+				totals.SeatHours += row.SeatHours
+				periodRevenue += row.Revenue
+				if len(tables) > 0 {
+					row.TurnsPerTable = helpers.RoundMoney(float64(row.Visits) / float64(len(tables)))
+				}
+				row.finish()
+				byPeriod = append(byPeriod, *row)
+				delete(periodRows, period.Name)
+			}
+		}
+		if row, ok := periodRows["OTHER"]; ok {
+			row.finish()
+			byPeriod = append(byPeriod, *row)
+		}
+
+		if len(tables) > 0 {
+			totals.TurnsPerTable = helpers.RoundMoney(float64(totals.Visits) / float64(len(tables)))
+		}
+		totals.finish()
+
+		// Only visits inside a service period have seat hours to share
+		if totals.SeatHours > 0 {
+			totals.RevPASH = helpers.RoundMoney(periodRevenue / totals.SeatHours)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":          from.In(location),
+			"to":            to.In(location),
+			"timezone":      location.String(),
+			"seats":         seats,
+			"totals":        totals,
+			"by_table":      byTable,
+			"by_party_size": byPartySize,
+			"by_period":     byPeriod,
+		})
+	}
+}
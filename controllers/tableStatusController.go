@@ -0,0 +1,334 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// tableTransitions lists the statuses each table status can move to
+var tableTransitions = map[string][]string{
+	"AVAILABLE": {"RESERVED", "SEATED", "DIRTY"},
+	"RESERVED":  {"AVAILABLE", "SEATED"},
+	"SEATED":    {"ORDERING", "DIRTY", "AVAILABLE"},
+	"ORDERING":  {"DIRTY"},
+	"DIRTY":     {"AVAILABLE"},
+}
+
+// tableStatuses is the order statuses are shown on the board
+var tableStatuses = []string{"AVAILABLE", "RESERVED", "SEATED", "ORDERING", "DIRTY"}
+
+var errTableChanged = errors.New("table status changed, please retry")
+
+// tableStatus returns the status of a table; tables created before
+// statuses existed are available
+func tableStatus(table models.Table) string {
+	if table.Status == nil || *table.Status == "" {
+		return "AVAILABLE"
+	}
+	return *table.Status
+}
+
+// canMoveTable reports whether a table may go from one status to another
+func canMoveTable(from string, to string) bool {
+	for _, next := range tableTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// vacatedStatus returns the status a table goes to when the last open check
+// at it leaves: dirty once it has been used, or straight back to available
+// when a party that never ordered moves to another table. It reports false
+// for tables that were not occupied.
+func vacatedStatus(status string, moved bool) (string, bool) {
+	switch status {
+	case "SEATED":
+		if moved {
+			return "AVAILABLE", true
+		}
+		return "DIRTY", true
+	case "ORDERING":
+		return "DIRTY", true
+	}
+	return "", false
+}
+
+// setTableStatus moves a table from its current status to another,
+// guarded on the status it was read with
+func setTableStatus(ctx context.Context, table models.Table, to string) error {
+	filter := bson.M{"table_id": table.TableID, "status": table.Status}
+
+	changedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := getTableCollection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"status":            to,
+		"status_changed_at": changedAt,
+		"updated_at":        changedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errTableChanged
+	}
+	return nil
+}
+
+// releaseTable marks the tables of a dine-in order dirty once the party
+// has settled up or the order is voided, unless another check at them is still open
+func releaseTable(ctx context.Context, orderId string) {
+	var order models.Order
+	if err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+		return
+	}
+	if order.TableID == nil || (order.OrderType != nil && *order.OrderType != "DINE_IN") {
+		return
+	}
+
+	tables := orderTables(ctx, order)
+	tableIds := make([]string, len(tables))
+	for i, table := range tables {
+		tableIds[i] = table.TableID
+	}
+
+	open := bson.A{bson.M{"table_id": bson.M{"$in": tableIds}}}
+	if order.TableGroupID != nil {
+		open = append(open, bson.M{"table_group_id": order.TableGroupID})
+	}
+
+	count, err := getOrderCollection().CountDocuments(ctx, bson.M{
+		"$or":        open,
+		"order_type": "DINE_IN",
+		"status":     "OPEN",
+		"order_id":   bson.M{"$ne": orderId},
+	})
+	if err != nil || count > 0 {
+		return
+	}
+
+	for _, table := range tables {
+		to, ok := vacatedStatus(tableStatus(table), false)
+		if !ok {
+			continue
+		}
+		if err := setTableStatus(ctx, table, to); err != nil {
+			log.Println("table was not marked dirty:", err)
+		}
+	}
+}
+
+// UpdateTableStatus changes a table's status by hand. Only the usual
+// transitions are allowed unless override is set with manager
+// authorization.
+func UpdateTableStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tableId := c.Param("table_id")
+
+		var request struct {
+			Status          *string `json:"status" validate:"required,eq=AVAILABLE|eq=RESERVED|eq=SEATED|eq=ORDERING|eq=DIRTY"`
+			Override        bool    `json:"override"`
+			ManagerEmail    *string `json:"manager_email"`
+			ManagerPassword *string `json:"manager_password"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := tableValidate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var table models.Table
+		err := getTableCollection().FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+
+		from := tableStatus(table)
+		if !canMoveTable(from, *request.Status) && from != *request.Status {
+			if !request.Override {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":   fmt.Sprintf("table cannot go from %s to %s", from, *request.Status),
+					"allowed": tableTransitions[from],
+				})
+				return
+			}
+
+			if _, ok := authorizeManager(ctx, c, request.ManagerEmail, request.ManagerPassword); !ok {
+				c.JSON(http.StatusForbidden, gin.H{"error": "overrides must be authorized by a manager"})
+				return
+			}
+		}
+
+		if err := setTableStatus(ctx, table, *request.Status); err != nil {
+			if errors.Is(err, errTableChanged) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "table update failed"})
+			return
+		}
+
+		err = getTableCollection().FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the table"})
+			return
+		}
+
+		c.JSON(http.StatusOK, table)
+	}
+}
+
+// latestTableOrders returns the latest dine-in order at each of the given
+// tables. At an occupied table it is the party sitting there; it may
+// already be invoiced and waiting for payment.
+func latestTableOrders(ctx context.Context, tableIds []string) (map[string]models.Order, error) {
+	matchStage := bson.D{{Key: "$match", Value: bson.D{
+		{Key: "table_id", Value: bson.D{{Key: "$in", Value: tableIds}}},
+		{Key: "order_type", Value: "DINE_IN"},
+		{Key: "status", Value: bson.D{{Key: "$ne", Value: "VOID"}}},
+	}}}
+	sortStage := bson.D{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}}
+	groupStage := bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: "$table_id"},
+		{Key: "order", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+	}}}
+
+	cursor, err := getOrderCollection().Aggregate(ctx, mongo.Pipeline{matchStage, sortStage, groupStage})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var latest []struct {
+		TableID string       `bson:"_id"`
+		Order   models.Order `bson:"order"`
+	}
+	if err = cursor.All(ctx, &latest); err != nil {
+		return nil, err
+	}
+
+	orders := map[string]models.Order{}
+	for _, row := range latest {
+		orders[row.TableID] = row.Order
+	}
+	return orders, nil
+}
+
+// boardTable is one table as shown on the floor board
+type boardTable struct {
+	TableID       string     `json:"table_id"`
+	TableNumber   *int       `json:"table_number"`
+	Seats         int        `json:"seats"`
+	Status        string     `json:"status"`
+	StatusSince   *time.Time `json:"status_since"`
+	OrderID       *string    `json:"order_id"`
+	PartySize     *int       `json:"party_size"`
+	SeatedAt      *time.Time `json:"seated_at"`
+	SeatedMinutes *int       `json:"seated_minutes"`
+	TableGroupID  *string    `json:"table_group_id"`
+}
+
+// GetTableBoard summarizes the floor: every table with its status and the
+// party at it, and the number of tables and seats in each status
+func GetTableBoard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var tables []models.Table
+		if err := findAll(ctx, getTableCollection(), bson.M{}, &tables); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing tables"})
+			return
+		}
+
+		var occupied []string
+		for _, table := range tables {
+			if status := tableStatus(table); status == "SEATED" || status == "ORDERING" {
+				occupied = append(occupied, table.TableID)
+			}
+		}
+
+		parties, err := latestTableOrders(ctx, occupied)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing orders"})
+			return
+		}
+
+		now := time.Now()
+		counts := map[string]int{}
+		seats := map[string]int{}
+		for _, status := range tableStatuses {
+			counts[status] = 0
+			seats[status] = 0
+		}
+
+		guests := 0
+		board := []boardTable{}
+		for _, table := range tables {
+			entry := boardTable{
+				TableID:      table.TableID,
+				TableNumber:  table.TableNumber,
+				Status:       tableStatus(table),
+				StatusSince:  table.StatusChangedAt,
+				TableGroupID: table.TableGroupID,
+			}
+			if table.NumberOfGuests != nil {
+				entry.Seats = *table.NumberOfGuests
+			}
+
+			if order, ok := parties[table.TableID]; ok {
+				orderId := order.OrderID
+				entry.OrderID = &orderId
+				entry.PartySize = order.NumberOfGuests
+				entry.SeatedAt = order.SeatedAt
+				if order.SeatedAt != nil {
+					minutes := int(now.Sub(*order.SeatedAt).Minutes())
+					entry.SeatedMinutes = &minutes
+				}
+				if order.NumberOfGuests != nil {
+					guests += *order.NumberOfGuests
+				} else {
+					guests += entry.Seats
+				}
+			}
+
+			counts[entry.Status]++
+			seats[entry.Status] += entry.Seats
+			board = append(board, entry)
+		}
+
+		sort.Slice(board, func(i, j int) bool {
+			if board[i].TableNumber == nil || board[j].TableNumber == nil {
+				return board[j].TableNumber == nil && board[i].TableNumber != nil
+			}
+			return *board[i].TableNumber < *board[j].TableNumber
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"tables": board,
+			"counts": counts,
+			"seats":  seats,
+			"guests": guests,
+		})
+	}
+}
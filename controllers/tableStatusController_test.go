@@ -0,0 +1,33 @@
+package controller
+
+import "testing"
+
+func TestVacatedStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		moved  bool
+		want   string
+		ok     bool
+	}{
+		{"voided or paid before ordering", "SEATED", false, "DIRTY", true},
+		{"voided or paid after ordering", "ORDERING", false, "DIRTY", true},
+		{"moved before ordering", "SEATED", true, "AVAILABLE", true},
+		{"moved after ordering", "ORDERING", true, "DIRTY", true},
+		{"already dirty", "DIRTY", false, "", false},
+		{"available", "AVAILABLE", false, "", false},
+		{"reserved", "RESERVED", true, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := vacatedStatus(tt.status, tt.moved)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("vacatedStatus(%q, %v) = %q, %v, want %q, %v", tt.status, tt.moved, got, ok, tt.want, tt.ok)
+			}
+			if ok && !canMoveTable(tt.status, got) {
+				t.Errorf("%s cannot move to %s", tt.status, got)
+			}
+		})
+	}
+}
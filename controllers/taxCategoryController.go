@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var taxCategoryValidate = validator.New()
+
+// GetTaxCategories returns all tax categories
+func GetTaxCategories() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var taxCategories []models.TaxCategory
+		cursor, err := getTaxCategoryCollection().Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing tax categories"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &taxCategories); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, taxCategories)
+	}
+}
+
+// GetTaxCategoryByID returns a single tax category by ID
+func GetTaxCategoryByID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		taxCategoryId := c.Param("tax_category_id")
+		var taxCategory models.TaxCategory
+
+		err := getTaxCategoryCollection().FindOne(ctx, bson.M{"tax_category_id": taxCategoryId}).Decode(&taxCategory)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the tax category"})
+			return
+		}
+
+		c.JSON(http.StatusOK, taxCategory)
+	}
+}
+
+// CreateTaxCategory creates a new tax category
+func CreateTaxCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var taxCategory models.TaxCategory
+		if err := c.BindJSON(&taxCategory); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := taxCategoryValidate.Struct(taxCategory)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		count, err := getTaxCategoryCollection().CountDocuments(ctx, bson.M{"name": taxCategory.Name})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking for tax category"})
+			return
+		}
+
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "tax category already exists"})
+			return
+		}
+
+		taxCategory.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		taxCategory.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		taxCategory.ID = primitive.NewObjectID()
+		taxCategory.TaxCategoryID = taxCategory.ID.Hex()
+
+		result, insertErr := getTaxCategoryCollection().InsertOne(ctx, taxCategory)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "tax category was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateTaxCategory updates an existing tax category. Invoices keep the
+// rates they were created with.
+func UpdateTaxCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var taxCategory models.TaxCategory
+		taxCategoryId := c.Param("tax_category_id")
+
+		if err := c.BindJSON(&taxCategory); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := taxCategoryValidate.StructPartial(taxCategory, "Rate", "TakeawayRate")
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if taxCategory.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: taxCategory.Name})
+		}
+
+		if taxCategory.Rate != nil {
+			updateObj = append(updateObj, bson.E{Key: "rate", Value: taxCategory.Rate})
+		}
+
+		if taxCategory.TakeawayRate != nil {
+			updateObj = append(updateObj, bson.E{Key: "takeaway_rate", Value: taxCategory.TakeawayRate})
+		}
+
+		taxCategory.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: taxCategory.UpdatedAt})
+
+		filter := bson.M{"tax_category_id": taxCategoryId}
+
+		result, err := getTaxCategoryCollection().UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "tax category update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetAllTaxCategories returns all tax categories
+func GetAllTaxCategories() gin.HandlerFunc {
+	return GetTaxCategories()
+}
@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/ali-adel-nour/restaurant-management/printing"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// defaultStation is where foods without a station are prepared
+const defaultStation = "KITCHEN"
+
+// activePrinters returns the enabled printers of one kind
+func activePrinters(ctx context.Context, kind string) ([]models.Printer, error) {
+	var printers []models.Printer
+	cursor, err := getPrinterCollection().Find(ctx, bson.M{"kind": kind, "active": bson.M{"$ne": false}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &printers); err != nil {
+		return nil, err
+	}
+	return printers, nil
+}
+
+// printKitchenTickets sends one ticket per station for the given order
+// items to that station's kitchen printers. Printers without a station
+// take the tickets of stations that have no printer of their own. It
+// returns the queued jobs and the stations no printer could take.
+func printKitchenTickets(ctx context.Context, order models.Order, items []models.OrderItem, reprint bool) ([]printing.Job, []string, error) {
+	printers, err := activePrinters(ctx, "KITCHEN")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Group the items by station, keeping the order they were entered in
+	var stations []string
+	tickets := map[string]*printing.KitchenTicket{}
+	for _, item := range items {
+		if item.FoodID == nil || item.Quantity == nil {
+			continue
+		}
+
+		name := *item.FoodID
+		station := defaultStation
+		var food models.Food
+		if err := getFoodCollection().FindOne(ctx, bson.M{"food_id": item.FoodID}).Decode(&food); err == nil {
+			if food.Name != nil {
+				name = *food.Name
+			}
+			if food.Station != nil && *food.Station != "" {
+				station = strings.ToUpper(*food.Station)
+			}
+		}
+
+		ticket, ok := tickets[station]
+		if !ok {
+			ticket = &printing.KitchenTicket{
+				Station:   station,
+				OrderID:   order.OrderID,
+				Reprint:   reprint,
+				CreatedAt: time.Now().In(helpers.RestaurantLocation()),
+			}
+			if order.OrderType != nil {
+				ticket.OrderType = *order.OrderType
+			}
+			tickets[station] = ticket
+			stations = append(stations, station)
+		}
+		ticket.Items = append(ticket.Items, printing.TicketItem{Name: name, Quantity: *item.Quantity})
+	}
+
+	var table models.Table
+	if order.TableID != nil {
+		if err := getTableCollection().FindOne(ctx, bson.M{"table_id": order.TableID}).Decode(&table); err == nil && table.TableNumber != nil {
+			for _, ticket := range tickets {
+				ticket.Table = strconv.Itoa(*table.TableNumber)
+			}
+		}
+	}
+
+	jobs := []printing.Job{}
+	unrouted := []string{}
+	for _, station := range stations {
+		var targets []models.Printer
+		for _, printer := range printers {
+			if printer.Station != nil && strings.EqualFold(*printer.Station, station) {
+				targets = append(targets, printer)
+			}
+		}
+		if len(targets) == 0 {
+			for _, printer := range printers {
+				if printer.Station == nil || *printer.Station == "" {
+					targets = append(targets, printer)
+				}
+			}
+		}
+
+		if len(targets) == 0 {
+			unrouted = append(unrouted, station)
+			continue
+		}
+
+		data := tickets[station].Encode()
+		for _, printer := range targets {
+			jobs = append(jobs, printing.Enqueue(printing.Job{
+				PrinterID:   printer.PrinterID,
+				PrinterName: *printer.Name,
+				Address:     *printer.Address,
+				Kind:        "KITCHEN_TICKET",
+				Reference:   order.OrderID,
+			}, data))
+		}
+	}
+
+	return jobs, unrouted, nil
+}
+
+// PrintOrderTickets reprints the kitchen tickets for every item of an order
+func PrintOrderTickets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var order models.Order
+		err := getOrderCollection().FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+
+		var orderItems []models.OrderItem
+		cursor, err := getOrderItemCollection().Find(ctx, bson.M{"order_id": orderId, "voided": bson.M{"$ne": true}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching order items"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &orderItems); err != nil {
+			log.Fatal(err)
+		}
+
+		jobs, unrouted, err := printKitchenTickets(ctx, order, orderItems, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while printing kitchen tickets"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"jobs":              jobs,
+			"unrouted_stations": unrouted,
+		})
+	}
+}
+
+// PrintInvoiceReceipt sends a customer receipt to a receipt printer. The
+// printer can be chosen with printer_id; otherwise the first active
+// receipt printer is used.
+func PrintInvoiceReceipt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		var request struct {
+			PrinterID *string `json:"printer_id"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var printer models.Printer
+		if request.PrinterID != nil {
+			err := getPrinterCollection().FindOne(ctx, bson.M{"printer_id": request.PrinterID}).Decode(&printer)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "printer was not found"})
+				return
+			}
+		} else {
+			printers, err := activePrinters(ctx, "RECEIPT")
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing printers"})
+				return
+			}
+			if len(printers) == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no receipt printer is configured"})
+				return
+			}
+			printer = printers[0]
+		}
+
+		invoice, text, err := renderReceipt(ctx, invoiceId)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while rendering the receipt: " + err.Error()})
+			return
+		}
+
+		job := printing.Enqueue(printing.Job{
+			PrinterID:   printer.PrinterID,
+			PrinterName: *printer.Name,
+			Address:     *printer.Address,
+			Kind:        "RECEIPT",
+			Reference:   invoice.InvoiceID,
+		}, printing.EncodeText(text))
+
+		c.JSON(http.StatusOK, job)
+	}
+}
@@ -0,0 +1,522 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var timeClockValidate = validator.New()
+
+// shiftEarlyClockIn is how long before a scheduled shift a clock-in still
+// counts towards it
+const shiftEarlyClockIn = time.Hour
+
+// clockRequest identifies who is clocking in or out. Clocking for someone
+// other than the signed-in user, as on a shared terminal, needs their PIN.
+type clockRequest struct {
+	UserID *string `json:"user_id"`
+	Pin    *string `json:"pin" validate:"omitempty,numeric,min=4,max=8"`
+	Paid   bool    `json:"paid"`
+}
+
+// clockUser reads a clock request and returns the staff member it is for,
+// and whether their PIN was checked
+func clockUser(ctx context.Context, c *gin.Context) (models.User, clockRequest, bool) {
+	var user models.User
+	var request clockRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return user, request, false
+	}
+
+	validationErr := timeClockValidate.Struct(request)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		return user, request, false
+	}
+
+	uid := c.GetString("uid")
+	if request.UserID == nil {
+		request.UserID = &uid
+	}
+
+	err := getUserCollection().FindOne(ctx, bson.M{"user_id": request.UserID}).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "staff member was not found"})
+		return user, request, false
+	}
+
+	if request.Pin == nil {
+		if user.UserID != uid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "PIN is required to clock someone else"})
+			return user, request, false
+		}
+		return user, request, true
+	}
+
+	if user.Pin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "staff member has no PIN set"})
+		return user, request, false
+	}
+	if user.PinLockedUntil != nil && time.Now().Before(*user.PinLockedUntil) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "PIN is locked after too many incorrect attempts", "locked_until": user.PinLockedUntil})
+		return user, request, false
+	}
+	if ok, _ := VerifyPassword(*request.Pin, *user.Pin); !ok {
+		if err := recordPinFailure(ctx, user.UserID); err != nil {
+			log.Println("incorrect PIN was not counted:", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "PIN is incorrect"})
+		return user, request, false
+	}
+
+	if user.PinFailures > 0 || user.PinLockedUntil != nil {
+		getUserCollection().UpdateOne(ctx,
+			bson.M{"user_id": user.UserID},
+			bson.M{"$set": bson.M{"pin_failures": 0, "pin_locked_until": nil}},
+		)
+	}
+	return user, request, true
+}
+
+// recordPinFailure counts an incorrect PIN and locks the PIN once too
+// many have been entered in a row, so four to eight digits cannot simply
+// be guessed
+func recordPinFailure(ctx context.Context, userId string) error {
+	maxAttempts, lockout := helpers.PinLockout()
+
+	var user models.User
+	err := getUserCollection().FindOneAndUpdate(ctx,
+		bson.M{"user_id": userId},
+		bson.M{"$inc": bson.M{"pin_failures": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		return err
+	}
+
+	if user.PinFailures < maxAttempts {
+		return nil
+	}
+
+	lockedUntil, _ := time.Parse(time.RFC3339, time.Now().Add(lockout).Format(time.RFC3339))
+	_, err = getUserCollection().UpdateOne(ctx,
+		bson.M{"user_id": userId},
+		bson.M{"$set": bson.M{"pin_failures": 0, "pin_locked_until": lockedUntil}},
+	)
+	if err == nil {
+		log.Println("time clock PIN locked for user", userId)
+	}
+	return err
+}
+
+// openTimeEntry returns the time entry a staff member is clocked in on
+func openTimeEntry(ctx context.Context, userId string) (models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := getTimeEntryCollection().FindOne(ctx, bson.M{"user_id": userId, "status": "OPEN"}).Decode(&entry)
+	return entry, err
+}
+
+// onBreak reports whether a time entry's last break has not ended
+func onBreak(entry models.TimeEntry) bool {
+	return len(entry.Breaks) > 0 && entry.Breaks[len(entry.Breaks)-1].End == nil
+}
+
+// entryMinutes returns the minutes worked on a time entry up to its
+// clock-out, or up to until while it is open, and its unpaid break minutes.
+// Breaks only count inside the clocked time.
+func entryMinutes(entry models.TimeEntry, until time.Time) (float64, float64) {
+	end := until
+	if entry.ClockOut != nil {
+		end = *entry.ClockOut
+	}
+	if !end.After(entry.ClockIn) {
+		return 0, 0
+	}
+
+	var unpaid time.Duration
+	for _, b := range entry.Breaks {
+		if b.Paid {
+			continue
+		}
+		start, stop := b.Start, end
+		if b.End != nil && b.End.Before(stop) {
+			stop = *b.End
+		}
+		if start.Before(entry.ClockIn) {
+			start = entry.ClockIn
+		}
+		if stop.After(start) {
+			unpaid += stop.Sub(start)
+		}
+	}
+
+	worked := end.Sub(entry.ClockIn) - unpaid
+	return worked.Minutes(), unpaid.Minutes()
+}
+
+// ClockIn starts a staff member's time entry, linked to the shift they are
+// scheduled for now if any
+func ClockIn() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		user, request, ok := clockUser(ctx, c)
+		if !ok {
+			return
+		}
+
+		_, err := openTimeEntry(ctx, user.UserID)
+		if err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "staff member is already clocked in"})
+			return
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking the time clock"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		entry := models.TimeEntry{
+			UserID:      user.UserID,
+			ClockIn:     now,
+			Breaks:      []models.Break{},
+			Status:      "OPEN",
+			PinVerified: request.Pin != nil,
+			ClockedInBy: c.GetString("uid"),
+		}
+		if user.HourlyRate != nil {
+			entry.HourlyRate = *user.HourlyRate
+		}
+
+		var shift models.Shift
+		err = getShiftCollection().FindOne(ctx,
+			bson.M{
+				"user_id":   user.UserID,
+				"status":    "SCHEDULED",
+				"starts_at": bson.M{"$lte": now.Add(shiftEarlyClockIn)},
+				"ends_at":   bson.M{"$gt": now},
+			},
+			options.FindOne().SetSort(bson.D{{Key: "starts_at", Value: 1}}),
+		).Decode(&shift)
+		if err == nil {
+			entry.ShiftID = &shift.ShiftID
+		}
+
+		entry.CreatedAt = now
+		entry.UpdatedAt = now
+		entry.ID = primitive.NewObjectID()
+		entry.TimeEntryID = entry.ID.Hex()
+
+		// A unique index allows one open entry per person, so a second
+		// clock-in racing this one is refused here
+		_, insertErr := getTimeEntryCollection().InsertOne(ctx, entry)
+		if mongo.IsDuplicateKeyError(insertErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": "staff member is already clocked in"})
+			return
+		}
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "staff member was not clocked in"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// StartBreak starts a break on a staff member's open time entry. Breaks
+// are unpaid unless "paid" is set.
+func StartBreak() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		user, request, ok := clockUser(ctx, c)
+		if !ok {
+			return
+		}
+
+		entry, err := openTimeEntry(ctx, user.UserID)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "staff member is not clocked in"})
+			return
+		}
+
+		if onBreak(entry) {
+			c.JSON(http.StatusConflict, gin.H{"error": "staff member is already on a break"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.Breaks = append(entry.Breaks, models.Break{Start: now, Paid: request.Paid})
+		entry.UpdatedAt = now
+
+		_, err = getTimeEntryCollection().UpdateOne(ctx,
+			bson.M{"time_entry_id": entry.TimeEntryID, "status": "OPEN"},
+			bson.M{"$set": bson.M{"breaks": entry.Breaks, "updated_at": entry.UpdatedAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "break was not started"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// EndBreak ends the break a staff member is on
+func EndBreak() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		user, _, ok := clockUser(ctx, c)
+		if !ok {
+			return
+		}
+
+		entry, err := openTimeEntry(ctx, user.UserID)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "staff member is not clocked in"})
+			return
+		}
+
+		if !onBreak(entry) {
+			c.JSON(http.StatusConflict, gin.H{"error": "staff member is not on a break"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.Breaks[len(entry.Breaks)-1].End = &now
+		entry.UpdatedAt = now
+
+		_, err = getTimeEntryCollection().UpdateOne(ctx,
+			bson.M{"time_entry_id": entry.TimeEntryID, "status": "OPEN"},
+			bson.M{"$set": bson.M{"breaks": entry.Breaks, "updated_at": entry.UpdatedAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "break was not ended"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// ClockOut ends a staff member's time entry, ending any break they are on
+func ClockOut() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		user, _, ok := clockUser(ctx, c)
+		if !ok {
+			return
+		}
+
+		entry, err := openTimeEntry(ctx, user.UserID)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "staff member is not clocked in"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if onBreak(entry) {
+			entry.Breaks[len(entry.Breaks)-1].End = &now
+		}
+
+		entry.ClockOut = &now
+		entry.Status = "CLOSED"
+		entry.WorkedMinutes, entry.BreakMinutes = entryMinutes(entry, now)
+		entry.ClockedOutBy = c.GetString("uid")
+		entry.UpdatedAt = now
+
+		result, err := getTimeEntryCollection().UpdateOne(ctx,
+			bson.M{"time_entry_id": entry.TimeEntryID, "status": "OPEN"},
+			bson.M{"$set": bson.M{
+				"clock_out":      entry.ClockOut,
+				"breaks":         entry.Breaks,
+				"status":         entry.Status,
+				"worked_minutes": entry.WorkedMinutes,
+				"break_minutes":  entry.BreakMinutes,
+				"clocked_out_by": entry.ClockedOutBy,
+				"updated_at":     entry.UpdatedAt,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "staff member was not clocked out"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "staff member is not clocked in"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// GetTimeEntries returns the time entries clocked in within a date range
+// (?from=, ?to=), optionally of one staff member (?user_id=)
+func GetTimeEntries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := parseReportRange(c)
+		if !ok {
+			return
+		}
+
+		filter := bson.M{"clock_in": bson.M{"$gte": from, "$lt": to}}
+		if userId := c.Query("user_id"); userId != "" {
+			filter["user_id"] = userId
+		}
+
+		var entries []models.TimeEntry
+		cursor, err := getTimeEntryCollection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "clock_in", Value: 1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing time entries"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &entries); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+// GetClockedIn returns who is on the clock now, and whether they are on a
+// break
+func GetClockedIn() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entries []models.TimeEntry
+		if err := findAll(ctx, getTimeEntryCollection(), bson.M{"status": "OPEN"}, &entries); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing time entries"})
+			return
+		}
+
+		ids := make([]string, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.UserID
+		}
+		names := serverNames(ctx, ids)
+
+		now := time.Now()
+		staff := []gin.H{}
+		for _, entry := range entries {
+			worked, _ := entryMinutes(entry, now)
+			staff = append(staff, gin.H{
+				"user_id":        entry.UserID,
+				"name":           names[entry.UserID],
+				"time_entry_id":  entry.TimeEntryID,
+				"shift_id":       entry.ShiftID,
+				"clock_in":       entry.ClockIn,
+				"on_break":       onBreak(entry),
+				"worked_minutes": int(worked),
+			})
+		}
+
+		c.JSON(http.StatusOK, staff)
+	}
+}
+
+// UpdateTimeEntry corrects the clock-in or clock-out time of an entry, for
+// example a forgotten clock-out. A manager must authorize it.
+func UpdateTimeEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		entryId := c.Param("time_entry_id")
+
+		var request struct {
+			ClockIn         *time.Time `json:"clock_in"`
+			ClockOut        *time.Time `json:"clock_out"`
+			ManagerEmail    *string    `json:"manager_email"`
+			ManagerPassword *string    `json:"manager_password"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		managerId, ok := authorizeManager(ctx, c, request.ManagerEmail, request.ManagerPassword)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "a manager must authorize time clock corrections"})
+			return
+		}
+
+		var entry models.TimeEntry
+		err := getTimeEntryCollection().FindOne(ctx, bson.M{"time_entry_id": entryId}).Decode(&entry)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "time entry was not found"})
+			return
+		}
+
+		if request.ClockIn != nil {
+			entry.ClockIn = *request.ClockIn
+		}
+		if request.ClockOut != nil {
+			entry.ClockOut = request.ClockOut
+			entry.Status = "CLOSED"
+			if onBreak(entry) {
+				entry.Breaks[len(entry.Breaks)-1].End = request.ClockOut
+			}
+		}
+
+		if entry.ClockOut != nil && !entry.ClockOut.After(entry.ClockIn) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "clock_out must be after clock_in"})
+			return
+		}
+
+		if entry.ClockOut != nil {
+			entry.WorkedMinutes, entry.BreakMinutes = entryMinutes(entry, *entry.ClockOut)
+		}
+		entry.EditedBy = managerId
+		entry.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		_, err = getTimeEntryCollection().UpdateOne(ctx,
+			bson.M{"time_entry_id": entryId},
+			bson.M{"$set": bson.M{
+				"clock_in":       entry.ClockIn,
+				"clock_out":      entry.ClockOut,
+				"breaks":         entry.Breaks,
+				"status":         entry.Status,
+				"worked_minutes": entry.WorkedMinutes,
+				"break_minutes":  entry.BreakMinutes,
+				"edited_by":      entry.EditedBy,
+				"updated_at":     entry.UpdatedAt,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "time entry update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tipValidate = validator.New()
+
+// GetInvoiceTips returns the tips entered on an invoice
+func GetInvoiceTips() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		var tips []models.Tip
+		cursor, err := getTipCollection().Find(ctx, bson.M{"invoice_id": invoiceId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching tips"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &tips); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, tips)
+	}
+}
+
+// CreateTip records a tip on a card-paid invoice. The tip is credited to
+// the given server, or else to the order's server or the signed-in user.
+func CreateTip() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+
+		var tip models.Tip
+		if err := c.BindJSON(&tip); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := tipValidate.Struct(tip)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var invoice models.Invoice
+		err := getInvoiceCollection().FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+
+		// Only a recorded card payment counts; the invoice's payment_method
+		// is whatever the client set
+		cardPayments, err := getPaymentCollection().CountDocuments(ctx, bson.M{"invoice_id": invoice.InvoiceID, "method": "CARD"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while checking payments"})
+			return
+		}
+
+		if cardPayments == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tips can only be entered on card payments"})
+			return
+		}
+
+		if tip.ServerID == nil {
+			tip.ServerID = invoice.ServerID
+		}
+		if tip.ServerID == nil {
+			uid := c.GetString("uid")
+			tip.ServerID = &uid
+		}
+
+		tip.InvoiceID = invoice.InvoiceID
+		tip.OrderID = invoice.OrderID
+		tip.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		tip.ID = primitive.NewObjectID()
+		tip.TipID = tip.ID.Hex()
+
+		result, insertErr := getTipCollection().InsertOne(ctx, tip)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "tip was not created"})
+			return
+		}
+
+		_, err = getInvoiceCollection().UpdateOne(ctx,
+			bson.M{"invoice_id": invoice.InvoiceID},
+			bson.M{"$inc": bson.M{"tip_total": *tip.Amount}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invoice tip total update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -129,6 +129,15 @@ func SignUp() gin.HandlerFunc {
 			return
 		}
 
+		// Everyone signs up as staff; administrators are made through
+		// ADMIN_EMAIL at startup or by another ADMIN
+		role := "STAFF"
+		user.Role = &role
+
+		// Pay rates and time clock PINs are set through /labor
+		user.HourlyRate = nil
+		user.Pin = nil
+
 		// Set timestamps and IDs
 		user.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		user.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
@@ -203,6 +212,236 @@ func GetAllUsers() gin.HandlerFunc {
 	return GetUsers()
 }
 
+// EnsureAdmin makes the user with the given email an ADMIN. Without an
+// email it only warns when nobody can manage roles, which happens when
+// users were created before roles existed.
+func EnsureAdmin(ctx context.Context, email string) error {
+	if email == "" {
+		count, err := getUserCollection().CountDocuments(ctx, bson.M{"role": "ADMIN"})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			log.Println("no ADMIN user: set ADMIN_EMAIL to the email of an existing user to make them one")
+		}
+		return nil
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := getUserCollection().UpdateOne(ctx,
+		bson.M{"email": email, "role": bson.M{"$ne": "ADMIN"}},
+		bson.M{"$set": bson.M{"role": "ADMIN", "updated_at": updatedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		log.Println("made ADMIN:", email)
+		return nil
+	}
+
+	count, err := getUserCollection().CountDocuments(ctx, bson.M{"email": email})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		log.Println("ADMIN_EMAIL does not match any user, sign up with it and restart:", email)
+	}
+	return nil
+}
+
+// userHasRole reports whether the user has one of the given roles
+func userHasRole(ctx context.Context, userId string, roles ...string) bool {
+	var user models.User
+	err := getUserCollection().FindOne(ctx, bson.M{"user_id": userId}).Decode(&user)
+	if err != nil || user.Role == nil {
+		return false
+	}
+
+	for _, role := range roles {
+		if *user.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeManager returns the ID of the manager approving an action. A
+// signed-in manager or admin approves their own actions; anyone else needs
+// a manager to enter their email and password.
+func authorizeManager(ctx context.Context, c *gin.Context, email *string, password *string) (string, bool) {
+	uid := c.GetString("uid")
+	if userHasRole(ctx, uid, "ADMIN", "MANAGER") {
+		return uid, true
+	}
+
+	if email == nil || password == nil {
+		return "", false
+	}
+
+	var manager models.User
+	err := getUserCollection().FindOne(ctx, bson.M{"email": email}).Decode(&manager)
+	if err != nil || manager.Password == nil {
+		return "", false
+	}
+
+	if ok, _ := VerifyPassword(*password, *manager.Password); !ok {
+		return "", false
+	}
+
+	if !userHasRole(ctx, manager.UserID, "ADMIN", "MANAGER") {
+		return "", false
+	}
+
+	return manager.UserID, true
+}
+
+// UpdateUserRole changes a user's role (admin only)
+func UpdateUserRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userId := c.Param("user_id")
+
+		if !userHasRole(ctx, c.GetString("uid"), "ADMIN") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only admins can change roles"})
+			return
+		}
+
+		var user models.User
+		if err := c.BindJSON(&user); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if user.Role == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "role is required"})
+			return
+		}
+
+		validationErr := validate.StructPartial(user, "Role")
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		result, err := getUserCollection().UpdateOne(ctx,
+			bson.M{"user_id": userId},
+			bson.M{"$set": bson.M{"role": user.Role, "updated_at": updatedAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "user role update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateUserLabor sets a staff member's hourly rate and time clock PIN.
+// Staff may set their own PIN; rates and other people's PINs need a
+// manager.
+func UpdateUserLabor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userId := c.Param("user_id")
+
+		var request struct {
+			HourlyRate      *float64 `json:"hourly_rate" validate:"omitempty,gte=0"`
+			Pin             *string  `json:"pin" validate:"omitempty,numeric,min=4,max=8"`
+			ManagerEmail    *string  `json:"manager_email"`
+			ManagerPassword *string  `json:"manager_password"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := validate.Struct(request)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		if request.HourlyRate == nil && request.Pin == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hourly_rate or pin is required"})
+			return
+		}
+
+		if request.HourlyRate != nil || userId != c.GetString("uid") {
+			if _, ok := authorizeManager(ctx, c, request.ManagerEmail, request.ManagerPassword); !ok {
+				c.JSON(http.StatusForbidden, gin.H{"error": "a manager must authorize this change"})
+				return
+			}
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj := bson.M{"updated_at": updatedAt}
+		if request.HourlyRate != nil {
+			updateObj["hourly_rate"] = request.HourlyRate
+		}
+		if request.Pin != nil {
+			updateObj["pin"] = HashPassword(*request.Pin)
+			updateObj["pin_failures"] = 0
+			updateObj["pin_locked_until"] = nil
+		}
+
+		result, err := getUserCollection().UpdateOne(ctx,
+			bson.M{"user_id": userId},
+			bson.M{"$set": updateObj},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "user update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetUserLabor returns a user's hourly rate and whether they have a time
+// clock PIN. Pay rates are left off every other user response, so only
+// managers can read them here.
+func GetUserLabor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !userHasRole(ctx, c.GetString("uid"), "ADMIN", "MANAGER") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only managers can see pay rates"})
+			return
+		}
+
+		var user models.User
+		err := getUserCollection().FindOne(ctx, bson.M{"user_id": c.Param("user_id")}).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":     user.UserID,
+			"hourly_rate": user.HourlyRate,
+			"has_pin":     user.Pin != nil,
+		})
+	}
+}
+
 // Logout logs out a user
 func Logout() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,735 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/helpers"
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/ali-adel-nour/restaurant-management/notify"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var waitlistValidate = validator.New()
+
+const (
+	// dwellHistoryDays is how far back dwell times are averaged
+	dwellHistoryDays = 30
+	// defaultDwell is assumed until there are finished visits to average
+	defaultDwell = 60 * time.Minute
+	// turnaroundTime is the time to clear and reset a table between parties
+	turnaroundTime = 5 * time.Minute
+)
+
+// waitingStatuses are the statuses of parties still in the queue
+var waitingStatuses = []string{"WAITING", "NOTIFIED"}
+
+// dwellTimes are the average recent stays at a table, by party size
+type dwellTimes struct {
+	bySize  map[int]time.Duration
+	overall time.Duration
+}
+
+// of returns the usual stay of a party, or the overall average when
+// parties of that size have not been seen recently
+func (d dwellTimes) of(partySize int) time.Duration {
+	if dwell, ok := d.bySize[partySize]; ok {
+		return dwell
+	}
+	return d.overall
+}
+
+// historicalDwell averages how long parties stayed at their tables over
+// the last dwellHistoryDays
+func historicalDwell(ctx context.Context, now time.Time) (dwellTimes, error) {
+	var orders []models.Order
+	err := findAll(ctx, getOrderCollection(), bson.M{
+		"seated_at":   bson.M{"$gte": now.AddDate(0, 0, -dwellHistoryDays)},
+		"unseated_at": bson.M{"$ne": nil},
+		"status":      bson.M{"$ne": "VOID"},
+	}, &orders)
+	if err != nil {
+		return dwellTimes{}, err
+	}
+
+	sums := map[int]time.Duration{}
+	counts := map[int]int{}
+	var total time.Duration
+	visits := 0
+	for _, order := range orders {
+		if order.SeatedAt == nil || order.UnseatedAt == nil {
+			continue
+		}
+		dwell := order.UnseatedAt.Sub(*order.SeatedAt)
+		total += dwell
+		visits++
+		if order.NumberOfGuests != nil {
+			sums[*order.NumberOfGuests] += dwell
+			counts[*order.NumberOfGuests]++
+		}
+	}
+
+	dwell := dwellTimes{bySize: map[int]time.Duration{}, overall: defaultDwell}
+	if visits > 0 {
+		dwell.overall = total / time.Duration(visits)
+	}
+	for size, sum := range sums {
+		dwell.bySize[size] = sum / time.Duration(counts[size])
+	}
+	return dwell, nil
+}
+
+// estimateWaits estimates the wait of each party in queue order, from the
+// tables as they are now and how long parties usually stay
+func estimateWaits(ctx context.Context, partySizes []int, now time.Time) ([]*int, error) {
+	var tables []models.Table
+	if err := findAll(ctx, getTableCollection(), bson.M{}, &tables); err != nil {
+		return nil, err
+	}
+
+	var occupied []string
+	for _, table := range tables {
+		if status := tableStatus(table); status == "SEATED" || status == "ORDERING" {
+			occupied = append(occupied, table.TableID)
+		}
+	}
+
+	parties, err := latestTableOrders(ctx, occupied)
+	if err != nil {
+		return nil, err
+	}
+
+	dwell, err := historicalDwell(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return queueWaits(tableSlots(tables, parties, dwell, now), partySizes, dwell, now), nil
+}
+
+// tableSlot is a table by how many it seats and when it should next be free
+type tableSlot struct {
+	seats  int
+	freeAt time.Time
+}
+
+// tableSlots gives every table the time it should next be free, from its
+// status and how long the party on it, if any, has sat there
+func tableSlots(tables []models.Table, parties map[string]models.Order, dwell dwellTimes, now time.Time) []tableSlot {
+	var slots []tableSlot
+	for _, table := range tables {
+		seats := tableSeats(table)
+		if seats == 0 {
+			continue
+		}
+
+		freeAt := now
+		switch tableStatus(table) {
+		case "DIRTY":
+			freeAt = now.Add(turnaroundTime)
+		case "RESERVED":
+			freeAt = now.Add(dwell.of(seats) + turnaroundTime)
+		case "SEATED", "ORDERING":
+			size, seatedAt := seats, now
+			if order, ok := parties[table.TableID]; ok {
+				if order.NumberOfGuests != nil {
+					size = *order.NumberOfGuests
+				}
+				if order.SeatedAt != nil {
+					seatedAt = *order.SeatedAt
+				}
+			}
+
+			// A party staying longer than usual is expected to leave soon
+			freeAt = seatedAt.Add(dwell.of(size) + turnaroundTime)
+			if freeAt.Before(now.Add(turnaroundTime)) {
+				freeAt = now.Add(turnaroundTime)
+			}
+		}
+		slots = append(slots, tableSlot{seats: seats, freeAt: freeAt})
+	}
+	return slots
+}
+
+// queueWaits hands out tables to parties in queue order: each party takes
+// the table that seats it and frees first, the smaller one on a tie, and
+// keeps it for its usual stay. Waits are in minutes rounded up to five;
+// parties no table can seat get no estimate.
+func queueWaits(slots []tableSlot, partySizes []int, dwell dwellTimes, now time.Time) []*int {
+	waits := make([]*int, len(partySizes))
+	for i, size := range partySizes {
+		best := -1
+		for j, s := range slots {
+			if s.seats < size {
+				continue
+			}
+			if best < 0 || s.freeAt.Before(slots[best].freeAt) ||
+				(s.freeAt.Equal(slots[best].freeAt) && s.seats < slots[best].seats) {
+				best = j
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		wait := slots[best].freeAt.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		minutes := int(math.Ceil(wait.Minutes()/5)) * 5
+		waits[i] = &minutes
+
+		slots[best].freeAt = now.Add(wait + dwell.of(size) + turnaroundTime)
+	}
+	return waits
+}
+
+// waitingParties returns the parties still in the queue, first come first
+func waitingParties(ctx context.Context) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	cursor, err := getWaitlistCollection().Find(ctx,
+		bson.M{"status": bson.M{"$in": waitingStatuses}},
+		options.Find().SetSort(bson.D{{Key: "joined_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// releaseHeldTable frees the table held for a notified party
+func releaseHeldTable(ctx context.Context, entry models.WaitlistEntry) {
+	if entry.TableID == nil {
+		return
+	}
+
+	var table models.Table
+	if err := getTableCollection().FindOne(ctx, bson.M{"table_id": entry.TableID}).Decode(&table); err != nil {
+		return
+	}
+	if tableStatus(table) != "RESERVED" {
+		return
+	}
+	if err := setTableStatus(ctx, table, "AVAILABLE"); err != nil {
+		log.Println("held table was not released:", err)
+	}
+}
+
+// waitlistRow is a waiting party with its place in the queue and current
+// estimated wait
+type waitlistRow struct {
+	models.WaitlistEntry
+	Position         int  `json:"position"`
+	EstimatedMinutes *int `json:"estimated_minutes"`
+}
+
+// GetWaitlist returns the parties waiting, in order, with their current
+// estimated waits. ?status= lists parties of another status instead.
+func GetWaitlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if status := c.Query("status"); status != "" {
+			var entries []models.WaitlistEntry
+			cursor, err := getWaitlistCollection().Find(ctx, bson.M{"status": status},
+				options.Find().SetSort(bson.D{{Key: "joined_at", Value: -1}}))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing the waitlist"})
+				return
+			}
+			defer cursor.Close(ctx)
+
+			if err = cursor.All(ctx, &entries); err != nil {
+				log.Fatal(err)
+			}
+
+			c.JSON(http.StatusOK, entries)
+			return
+		}
+
+		entries, err := waitingParties(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing the waitlist"})
+			return
+		}
+
+		sizes := make([]int, len(entries))
+		for i, entry := range entries {
+			sizes[i] = *entry.PartySize
+		}
+
+		waits, err := estimateWaits(ctx, sizes, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while estimating waits"})
+			return
+		}
+
+		rows := make([]waitlistRow, len(entries))
+		for i, entry := range entries {
+			rows[i] = waitlistRow{WaitlistEntry: entry, Position: i + 1, EstimatedMinutes: waits[i]}
+		}
+
+		c.JSON(http.StatusOK, rows)
+	}
+}
+
+// GetWaitlistEntry returns a single waitlist entry by ID
+func GetWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		waitlistId := c.Param("waitlist_id")
+		var entry models.WaitlistEntry
+
+		err := getWaitlistCollection().FindOne(ctx, bson.M{"waitlist_id": waitlistId}).Decode(&entry)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the waitlist entry"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// GetWaitQuote estimates the wait of a party of ?party_size= joining the
+// waitlist now
+func GetWaitQuote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		partySize, err := strconv.Atoi(c.Query("party_size"))
+		if err != nil || partySize < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "party_size must be a positive number"})
+			return
+		}
+
+		entries, err := waitingParties(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing the waitlist"})
+			return
+		}
+
+		sizes := make([]int, 0, len(entries)+1)
+		for _, entry := range entries {
+			sizes = append(sizes, *entry.PartySize)
+		}
+		sizes = append(sizes, partySize)
+
+		waits, err := estimateWaits(ctx, sizes, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while estimating waits"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"party_size":        partySize,
+			"parties_ahead":     len(entries),
+			"estimated_minutes": waits[len(waits)-1],
+		})
+	}
+}
+
+// JoinWaitlist adds a walk-in party to the end of the waitlist and quotes
+// their wait
+func JoinWaitlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entry models.WaitlistEntry
+		if err := c.BindJSON(&entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationErr := waitlistValidate.Struct(entry)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		entries, err := waitingParties(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing the waitlist"})
+			return
+		}
+
+		sizes := make([]int, 0, len(entries)+1)
+		for _, waiting := range entries {
+			sizes = append(sizes, *waiting.PartySize)
+		}
+		sizes = append(sizes, *entry.PartySize)
+
+		waits, err := estimateWaits(ctx, sizes, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while estimating waits"})
+			return
+		}
+
+		entry.Status = "WAITING"
+		entry.QuotedMinutes = waits[len(waits)-1]
+		entry.TableID = nil
+		entry.NotifiedAt = nil
+		entry.OrderID = nil
+		entry.SeatedAt = nil
+		entry.LeftAt = nil
+		entry.JoinedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.UpdatedAt = entry.JoinedAt
+		entry.ID = primitive.NewObjectID()
+		entry.WaitlistID = entry.ID.Hex()
+
+		_, insertErr := getWaitlistCollection().InsertOne(ctx, entry)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "party was not added to the waitlist"})
+			return
+		}
+
+		c.JSON(http.StatusOK, waitlistRow{WaitlistEntry: entry, Position: len(entries) + 1, EstimatedMinutes: entry.QuotedMinutes})
+	}
+}
+
+// UpdateWaitlistEntry changes the details of a party still waiting
+func UpdateWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entry models.WaitlistEntry
+		waitlistId := c.Param("waitlist_id")
+
+		if err := c.BindJSON(&entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if entry.PartyName != nil {
+			validationErr := waitlistValidate.StructPartial(entry, "PartyName")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "party_name", Value: entry.PartyName})
+		}
+
+		if entry.PartySize != nil {
+			validationErr := waitlistValidate.StructPartial(entry, "PartySize")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "party_size", Value: entry.PartySize})
+		}
+
+		if entry.Phone != nil {
+			validationErr := waitlistValidate.StructPartial(entry, "Phone")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "phone", Value: entry.Phone})
+		}
+
+		if entry.Notes != nil {
+			validationErr := waitlistValidate.StructPartial(entry, "Notes")
+			if validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "notes", Value: entry.Notes})
+		}
+
+		entry.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: entry.UpdatedAt})
+
+		filter := bson.M{"waitlist_id": waitlistId, "status": bson.M{"$in": waitingStatuses}}
+
+		result, err := getWaitlistCollection().UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: updateObj}},
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "waitlist update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "party is no longer waiting"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// notifyTableReady texts a waiting party that their table is ready
+func notifyTableReady(ctx context.Context, entry models.WaitlistEntry) error {
+	return notify.SendSMS(ctx, notify.Message{
+		To:      *entry.Phone,
+		Subject: "Your table is ready",
+		Body: fmt.Sprintf("Hi %s, your table for %d at %s is ready. Please come to the host stand.",
+			*entry.PartyName, *entry.PartySize, helpers.RestaurantName()),
+	})
+}
+
+// NotifyWaitlistEntry texts a waiting party that their table is ready. A
+// table given with the notice is held for them as RESERVED.
+func NotifyWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		waitlistId := c.Param("waitlist_id")
+
+		var request struct {
+			TableID *string `json:"table_id"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var entry models.WaitlistEntry
+		err := getWaitlistCollection().FindOne(ctx, bson.M{"waitlist_id": waitlistId}).Decode(&entry)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "waitlist entry was not found"})
+			return
+		}
+
+		if entry.Status != "WAITING" && entry.Status != "NOTIFIED" {
+			c.JSON(http.StatusConflict, gin.H{"error": "party is no longer waiting"})
+			return
+		}
+
+		held := false
+		if request.TableID != nil && (entry.TableID == nil || *entry.TableID != *request.TableID) {
+			var table models.Table
+			err := getTableCollection().FindOne(ctx, bson.M{"table_id": request.TableID}).Decode(&table)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+				return
+			}
+
+			if tableSeats(table) < *entry.PartySize {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("table does not seat the party: it seats %d", tableSeats(table))})
+				return
+			}
+
+			if tableStatus(table) != "AVAILABLE" {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("table is %s", tableStatus(table))})
+				return
+			}
+
+			if err := setTableStatus(ctx, table, "RESERVED"); err != nil {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			held = true
+		}
+
+		if err := notifyTableReady(ctx, entry); err != nil {
+			if held {
+				releaseHeldTable(ctx, models.WaitlistEntry{TableID: request.TableID})
+			}
+			c.JSON(http.StatusBadGateway, gin.H{"error": "notification was not sent"})
+			return
+		}
+
+		// A party moved to another table no longer needs the first one
+		if held {
+			releaseHeldTable(ctx, entry)
+			entry.TableID = request.TableID
+		}
+
+		notifiedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.Status = "NOTIFIED"
+		entry.NotifiedAt = &notifiedAt
+		entry.UpdatedAt = notifiedAt
+
+		_, err = getWaitlistCollection().UpdateOne(ctx,
+			bson.M{"waitlist_id": waitlistId},
+			bson.M{"$set": bson.M{
+				"status":      entry.Status,
+				"notified_at": entry.NotifiedAt,
+				"table_id":    entry.TableID,
+				"updated_at":  entry.UpdatedAt,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "waitlist update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// SeatWaitlistEntry seats a waiting party at a table, opening their
+// dine-in order. The table defaults to the one held when they were
+// notified.
+func SeatWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		waitlistId := c.Param("waitlist_id")
+
+		var request struct {
+			TableID *string `json:"table_id"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var entry models.WaitlistEntry
+		err := getWaitlistCollection().FindOne(ctx, bson.M{"waitlist_id": waitlistId}).Decode(&entry)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "waitlist entry was not found"})
+			return
+		}
+
+		if entry.Status != "WAITING" && entry.Status != "NOTIFIED" {
+			c.JSON(http.StatusConflict, gin.H{"error": "party is no longer waiting"})
+			return
+		}
+
+		tableId := request.TableID
+		if tableId == nil {
+			tableId = entry.TableID
+		}
+		if tableId == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "table_id is required"})
+			return
+		}
+
+		var table models.Table
+		err = getTableCollection().FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+
+		if seats := seatingCapacity(ctx, table); seats < *entry.PartySize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("table does not seat the party: it seats %d", seats)})
+			return
+		}
+
+		heldHere := entry.TableID != nil && *entry.TableID == table.TableID
+		switch tableStatus(table) {
+		case "SEATED", "ORDERING":
+			c.JSON(http.StatusConflict, gin.H{"error": "table is occupied"})
+			return
+		case "RESERVED":
+			if !heldHere {
+				c.JSON(http.StatusConflict, gin.H{"error": "table is held for another party"})
+				return
+			}
+		}
+
+		orderType := "DINE_IN"
+		order := models.Order{
+			TableID:        &table.TableID,
+			OrderType:      &orderType,
+			NumberOfGuests: entry.PartySize,
+			TableGroupID:   table.TableGroupID,
+			CreatedBy:      c.GetString("uid"),
+		}
+		order.OrderDate, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if _, err := openOrder(ctx, &order, table); err != nil {
+			if errors.Is(err, errTableDirty) || errors.Is(err, errTableChanged) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !heldHere {
+			releaseHeldTable(ctx, entry)
+		}
+
+		entry.Status = "SEATED"
+		entry.TableID = &table.TableID
+		entry.OrderID = &order.OrderID
+		entry.SeatedAt = order.SeatedAt
+		entry.UpdatedAt = order.CreatedAt
+
+		_, err = getWaitlistCollection().UpdateOne(ctx,
+			bson.M{"waitlist_id": waitlistId},
+			bson.M{"$set": bson.M{
+				"status":     entry.Status,
+				"table_id":   entry.TableID,
+				"order_id":   entry.OrderID,
+				"seated_at":  entry.SeatedAt,
+				"updated_at": entry.UpdatedAt,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "waitlist update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// LeaveWaitlist takes a party that gave up waiting off the waitlist and
+// frees any table held for them
+func LeaveWaitlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		waitlistId := c.Param("waitlist_id")
+
+		var entry models.WaitlistEntry
+		err := getWaitlistCollection().FindOne(ctx, bson.M{"waitlist_id": waitlistId}).Decode(&entry)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "waitlist entry was not found"})
+			return
+		}
+
+		leftAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		result, err := getWaitlistCollection().UpdateOne(ctx,
+			bson.M{"waitlist_id": waitlistId, "status": bson.M{"$in": waitingStatuses}},
+			bson.M{"$set": bson.M{"status": "LEFT", "left_at": leftAt, "updated_at": leftAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "waitlist update failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "party is no longer waiting"})
+			return
+		}
+
+		releaseHeldTable(ctx, entry)
+
+		c.JSON(http.StatusOK, result)
+	}
+}
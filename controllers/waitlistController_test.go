@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+	"github.com/ali-adel-nour/restaurant-management/notify"
+)
+
+func TestTableSlots(t *testing.T) {
+	now := time.Date(2024, 1, 5, 19, 0, 0, 0, time.UTC)
+	dwell := dwellTimes{bySize: map[int]time.Duration{2: 45 * time.Minute}, overall: time.Hour}
+
+	two, four := 2, 4
+	available, dirty, reserved, seated := "AVAILABLE", "DIRTY", "RESERVED", "SEATED"
+	seatedAt, longAgo := now.Add(-15*time.Minute), now.Add(-3*time.Hour)
+
+	tests := []struct {
+		name    string
+		table   models.Table
+		order   *models.Order
+		wantIn  time.Duration
+		wantNot bool
+	}{
+		{"available now", models.Table{TableID: "t1", NumberOfGuests: &four, Status: &available}, nil, 0, false},
+		{"no status is available", models.Table{TableID: "t1", NumberOfGuests: &four}, nil, 0, false},
+		{"dirty needs a reset", models.Table{TableID: "t1", NumberOfGuests: &four, Status: &dirty}, nil, turnaroundTime, false},
+		{"reserved for a full stay", models.Table{TableID: "t1", NumberOfGuests: &four, Status: &reserved}, nil, time.Hour + turnaroundTime, false},
+		{
+			name:   "seated party stays its usual time",
+			table:  models.Table{TableID: "t1", NumberOfGuests: &four, Status: &seated},
+			order:  &models.Order{NumberOfGuests: &two, SeatedAt: &seatedAt},
+			wantIn: 30*time.Minute + turnaroundTime,
+		},
+		{
+			name:   "party past its usual stay leaves soon",
+			table:  models.Table{TableID: "t1", NumberOfGuests: &four, Status: &seated},
+			order:  &models.Order{NumberOfGuests: &two, SeatedAt: &longAgo},
+			wantIn: turnaroundTime,
+		},
+		{"tables without seats are left out", models.Table{TableID: "t1", Status: &available}, nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parties := map[string]models.Order{}
+			if tt.order != nil {
+				parties[tt.table.TableID] = *tt.order
+			}
+
+			slots := tableSlots([]models.Table{tt.table}, parties, dwell, now)
+			if tt.wantNot {
+				if len(slots) != 0 {
+					t.Errorf("slots = %+v, want none", slots)
+				}
+				return
+			}
+			if len(slots) != 1 || !slots[0].freeAt.Equal(now.Add(tt.wantIn)) {
+				t.Errorf("slots = %+v, want the table free in %v", slots, tt.wantIn)
+			}
+		})
+	}
+}
+
+func TestQueueWaits(t *testing.T) {
+	now := time.Date(2024, 1, 5, 19, 0, 0, 0, time.UTC)
+	dwell := dwellTimes{bySize: map[int]time.Duration{}, overall: 40 * time.Minute}
+
+	tests := []struct {
+		name       string
+		slots      []tableSlot
+		partySizes []int
+		want       []int
+	}{
+		{
+			name:       "a free table means no wait",
+			slots:      []tableSlot{{seats: 4, freeAt: now}},
+			partySizes: []int{2},
+			want:       []int{0},
+		},
+		{
+			name:       "waits round up to five minutes",
+			slots:      []tableSlot{{seats: 4, freeAt: now.Add(11 * time.Minute)}},
+			partySizes: []int{2},
+			want:       []int{15},
+		},
+		{
+			name:       "the next party waits for the first one to leave",
+			slots:      []tableSlot{{seats: 4, freeAt: now}},
+			partySizes: []int{2, 2},
+			want:       []int{0, 45},
+		},
+		{
+			name:       "parties are served in queue order",
+			slots:      []tableSlot{{seats: 2, freeAt: now.Add(10 * time.Minute)}, {seats: 6, freeAt: now.Add(30 * time.Minute)}},
+			partySizes: []int{6, 2, 2},
+			want:       []int{30, 10, 55},
+		},
+		{
+			name:       "smaller table first on a tie",
+			slots:      []tableSlot{{seats: 6, freeAt: now}, {seats: 2, freeAt: now}},
+			partySizes: []int{2, 5},
+			want:       []int{0, 0},
+		},
+		{
+			name:       "nothing seats the party",
+			slots:      []tableSlot{{seats: 4, freeAt: now}},
+			partySizes: []int{8, 4},
+			want:       []int{-1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := queueWaits(tt.slots, tt.partySizes, dwell, now)
+			for i, want := range tt.want {
+				if want < 0 {
+					if waits[i] != nil {
+						t.Errorf("party %d waits %d, want no estimate", i, *waits[i])
+					}
+					continue
+				}
+				if waits[i] == nil || *waits[i] != want {
+					t.Errorf("party %d waits %v, want %d", i, waits[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifyTableReady(t *testing.T) {
+	t.Setenv("RESTAURANT_NAME", "Nile Grill")
+	name, phone, size := "Salma", "+201000000000", 3
+	entry := models.WaitlistEntry{PartyName: &name, PartySize: &size, Phone: &phone}
+
+	tests := []struct {
+		name     string
+		err      error
+		wantSent int
+	}{
+		{"texts the party", nil, 1},
+		{"reports a failed text", errors.New("gateway down"), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sms := &notify.Fake{Err: tt.err}
+			notify.SetSMS(sms)
+			defer notify.SetSMS(notify.LogNotifier{})
+
+			if err := notifyTableReady(context.Background(), entry); !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+
+			sent := sms.Sent()
+			if len(sent) != tt.wantSent {
+				t.Fatalf("sent %d texts, want %d", len(sent), tt.wantSent)
+			}
+			if tt.wantSent > 0 && (sent[0].To != phone ||
+				!strings.Contains(sent[0].Body, "Salma, your table for 3 at Nile Grill is ready")) {
+				t.Errorf("text = %+v", sent[0])
+			}
+		})
+	}
+}
@@ -6,14 +6,40 @@ import (
 
 // Collections holds all database collections
 var Collections struct {
-	Users      *mongo.Collection
-	Foods      *mongo.Collection
-	Menus      *mongo.Collection
-	Tables     *mongo.Collection
-	Orders     *mongo.Collection
-	OrderItems *mongo.Collection
-	Invoices   *mongo.Collection
-	Notes      *mongo.Collection
+	Users          *mongo.Collection
+	Foods          *mongo.Collection
+	Menus          *mongo.Collection
+	Tables         *mongo.Collection
+	Orders         *mongo.Collection
+	OrderItems     *mongo.Collection
+	Invoices       *mongo.Collection
+	Notes          *mongo.Collection
+	Discounts      *mongo.Collection
+	OrderDiscounts *mongo.Collection
+	PriceRules     *mongo.Collection
+	TaxCategories  *mongo.Collection
+	Tips           *mongo.Collection
+	Counters       *mongo.Collection
+	Payments       *mongo.Collection
+	Refunds        *mongo.Collection
+	CreditNotes    *mongo.Collection
+	PaymentIntents *mongo.Collection
+	Printers       *mongo.Collection
+	HouseAccounts  *mongo.Collection
+	BusinessDays   *mongo.Collection
+	DrawerSessions *mongo.Collection
+	Areas          *mongo.Collection
+	Sections       *mongo.Collection
+	Reservations   *mongo.Collection
+	Waitlist       *mongo.Collection
+	TableGroups    *mongo.Collection
+	GuestOrders    *mongo.Collection
+	ShiftTemplates *mongo.Collection
+	Shifts         *mongo.Collection
+	TimeEntries    *mongo.Collection
+	Ingredients    *mongo.Collection
+	Recipes        *mongo.Collection
+	StockMovements *mongo.Collection
 }
 
 // InitCollections initializes all database collections
@@ -27,4 +53,30 @@ func InitCollections() {
 	Collections.OrderItems = OpenCollection("orderItems")
 	Collections.Invoices = OpenCollection("invoices")
 	Collections.Notes = OpenCollection("notes")
+	Collections.Discounts = OpenCollection("discounts")
+	Collections.OrderDiscounts = OpenCollection("orderDiscounts")
+	Collections.PriceRules = OpenCollection("priceRules")
+	Collections.TaxCategories = OpenCollection("taxCategories")
+	Collections.Tips = OpenCollection("tips")
+	Collections.Counters = OpenCollection("counters")
+	Collections.Payments = OpenCollection("payments")
+	Collections.Refunds = OpenCollection("refunds")
+	Collections.CreditNotes = OpenCollection("creditNotes")
+	Collections.PaymentIntents = OpenCollection("paymentIntents")
+	Collections.Printers = OpenCollection("printers")
+	Collections.HouseAccounts = OpenCollection("houseAccounts")
+	Collections.BusinessDays = OpenCollection("businessDays")
+	Collections.DrawerSessions = OpenCollection("drawerSessions")
+	Collections.Areas = OpenCollection("areas")
+	Collections.Sections = OpenCollection("sections")
+	Collections.Reservations = OpenCollection("reservations")
+	Collections.Waitlist = OpenCollection("waitlist")
+	Collections.TableGroups = OpenCollection("tableGroups")
+	Collections.GuestOrders = OpenCollection("guestOrders")
+	Collections.ShiftTemplates = OpenCollection("shiftTemplates")
+	Collections.Shifts = OpenCollection("shifts")
+	Collections.TimeEntries = OpenCollection("timeEntries")
+	Collections.Ingredients = OpenCollection("ingredients")
+	Collections.Recipes = OpenCollection("recipes")
+	Collections.StockMovements = OpenCollection("stockMovements")
 }
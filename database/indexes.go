@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// index is a MongoDB index the application relies on for correctness
+type index struct {
+	collection func() *mongo.Collection
+	model      mongo.IndexModel
+}
+
+// indexes lists the unique indexes that back checks made in the
+// controllers, so concurrent requests cannot both pass them
+var indexes = []index{
+	// One invoice per order
+	{
+		collection: func() *mongo.Collection { return Collections.Invoices },
+		model: mongo.IndexModel{
+			Keys:    bson.D{{Key: "order_id", Value: 1}},
+			Options: options.Index().SetName("order_id_unique").SetUnique(true),
+		},
+	},
+	// Invoice numbers are unique within a fiscal year; invoices from before
+	// numbering was introduced have none and are left out
+	{
+		collection: func() *mongo.Collection { return Collections.Invoices },
+		model: mongo.IndexModel{
+			Keys: bson.D{{Key: "fiscal_year", Value: 1}, {Key: "invoice_number", Value: 1}},
+			Options: options.Index().SetName("invoice_number_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"invoice_number": bson.M{"$gt": ""}}),
+		},
+	},
+	// Coupon codes are unique; discounts without one are left out
+	{
+		collection: func() *mongo.Collection { return Collections.Discounts },
+		model: mongo.IndexModel{
+			Keys: bson.D{{Key: "coupon_code", Value: 1}},
+			Options: options.Index().SetName("coupon_code_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"coupon_code": bson.M{"$type": "string"}}),
+		},
+	},
+	// One open business day at a time
+	{
+		collection: func() *mongo.Collection { return Collections.BusinessDays },
+		model: mongo.IndexModel{
+			Keys: bson.D{{Key: "status", Value: 1}},
+			Options: options.Index().SetName("one_open_day").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": "OPEN"}),
+		},
+	},
+	// One open time entry per staff member
+	{
+		collection: func() *mongo.Collection { return Collections.TimeEntries },
+		model: mongo.IndexModel{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("one_open_entry_per_user").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": "OPEN"}),
+		},
+	},
+}
+
+// EnsureIndexes creates the indexes the application needs. Call this after
+// InitCollections(). It fails when existing data breaks a unique index,
+// which has to be cleaned up before the server can start.
+func EnsureIndexes(ctx context.Context) error {
+	for _, index := range indexes {
+		collection := index.collection()
+		if _, err := collection.Indexes().CreateOne(ctx, index.model); err != nil {
+			return fmt.Errorf("index %s on %s: %w", *index.model.Options.Name, collection.Name(), err)
+		}
+	}
+	return nil
+}
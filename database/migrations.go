@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// legacyKeys maps the keys the first versions of the models were stored
+// under, before they had bson tags and the driver lowercased the field
+// names, to the keys they are stored under now
+var legacyKeys = []struct {
+	collection func() *mongo.Collection
+	keys       map[string]string
+}{
+	{
+		collection: func() *mongo.Collection { return Collections.Users },
+		keys: map[string]string{
+			"firstname":    "first_name",
+			"lastname":     "last_name",
+			"refreshtoken": "refresh_token",
+			"createdat":    "created_at",
+			"updatedat":    "updated_at",
+			"userid":       "user_id",
+		},
+	},
+	{
+		collection: func() *mongo.Collection { return Collections.Tables },
+		keys: map[string]string{
+			"numberofguests": "number_of_guests",
+			"tablenumber":    "table_number",
+			"createdat":      "created_at",
+			"updatedat":      "updated_at",
+			"tableid":        "table_id",
+		},
+	},
+	{
+		collection: func() *mongo.Collection { return Collections.Orders },
+		keys: map[string]string{
+			"orderdate": "order_date",
+			"createdat": "created_at",
+			"updatedat": "updated_at",
+			"orderid":   "order_id",
+			"tableid":   "table_id",
+		},
+	},
+	{
+		collection: func() *mongo.Collection { return Collections.OrderItems },
+		keys: map[string]string{
+			"unitprice":   "unit_price",
+			"createdat":   "created_at",
+			"updatedat":   "updated_at",
+			"foodid":      "food_id",
+			"orderitemid": "order_item_id",
+			"orderid":     "order_id",
+		},
+	},
+	{
+		collection: func() *mongo.Collection { return Collections.Invoices },
+		keys: map[string]string{
+			"invoiceid":     "invoice_id",
+			"orderid":       "order_id",
+			"paymentmethod": "payment_method",
+			"paymentstatus": "payment_status",
+			"paymentdue":    "payment_due",
+			"createdat":     "created_at",
+			"updatedat":     "updated_at",
+		},
+	},
+}
+
+// MigrateLegacyKeys renames keys stored by the first versions of the models
+// to the keys they have now. Documents that already have the new key are
+// left alone, so it is safe to run on every start.
+func MigrateLegacyKeys(ctx context.Context) error {
+	for _, legacy := range legacyKeys {
+		collection := legacy.collection()
+		for oldKey, newKey := range legacy.keys {
+			result, err := collection.UpdateMany(ctx,
+				bson.M{oldKey: bson.M{"$exists": true}, newKey: bson.M{"$exists": false}},
+				bson.M{"$rename": bson.M{oldKey: newKey}},
+			)
+			if err != nil {
+				return fmt.Errorf("renaming %s to %s in %s: %w", oldKey, newKey, collection.Name(), err)
+			}
+			if result.ModifiedCount > 0 {
+				log.Printf("migrated %d %s documents from %s to %s", result.ModifiedCount, collection.Name(), oldKey, newKey)
+			}
+		}
+	}
+	return nil
+}
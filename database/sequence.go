@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NextSequence atomically increments and returns the named counter.
+// The counter is created at 1 the first time it is used.
+func NextSequence(ctx context.Context, name string) (int64, error) {
+	upsert := true
+	after := options.After
+	opt := options.FindOneAndUpdateOptions{
+		Upsert:         &upsert,
+		ReturnDocument: &after,
+	}
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	err := Collections.Counters.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": name},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		&opt,
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Seq, nil
+}
+
+// ReleaseSequence gives back a value taken with NextSequence when the
+// document it was meant for could not be saved. It only rolls the counter
+// back if no later value has been handed out in the meantime, so it keeps
+// gaps out of the common case but cannot rule them out: when saves fail
+// while other values are being taken, the failed numbers are skipped.
+// It reports whether the value was given back.
+func ReleaseSequence(ctx context.Context, name string, seq int64) (bool, error) {
+	result, err := Collections.Counters.UpdateOne(
+		ctx,
+		bson.M{"_id": name, "seq": seq},
+		bson.M{"$inc": bson.M{"seq": -1}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// CurrentSequence returns the last value handed out by the named counter,
+// or 0 if it has never been used
+func CurrentSequence(ctx context.Context, name string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	err := Collections.Counters.FindOne(ctx, bson.M{"_id": name}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq, nil
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is something terminals should hear about as it happens, such as a
+// food selling out
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+	At   time.Time   `json:"at"`
+}
+
+// bufferSize is how many events a subscriber can fall behind by before
+// further events are dropped for it
+const bufferSize = 32
+
+// Hub passes published events on to every subscriber. A subscriber that
+// is not keeping up misses events rather than holding up the publisher.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// NewHub creates a hub with no subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: map[chan Event]struct{}{}}
+}
+
+// Subscribe returns a channel of the events published from now on and a
+// function that ends the subscription and closes the channel
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, bufferSize)
+
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends an event to every subscriber
+func (h *Hub) Publish(eventType string, data interface{}) {
+	event := Event{Type: eventType, Data: data, At: time.Now()}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers returns how many subscribers the hub has
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+var defaultHub = NewHub()
+
+// Subscribe subscribes to the default hub
+func Subscribe() (<-chan Event, func()) {
+	return defaultHub.Subscribe()
+}
+
+// Publish sends an event to the subscribers of the default hub
+func Publish(eventType string, data interface{}) {
+	defaultHub.Publish(eventType, data)
+}
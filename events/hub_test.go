@@ -0,0 +1,125 @@
+package events
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+	first, unsubscribeFirst := hub.Subscribe()
+	second, unsubscribeSecond := hub.Subscribe()
+	defer unsubscribeFirst()
+	defer unsubscribeSecond()
+
+	hub.Publish("food.sold_out", "soup")
+
+	for i, ch := range []<-chan Event{first, second} {
+		select {
+		case event := <-ch:
+			if event.Type != "food.sold_out" || event.Data != "soup" {
+				t.Errorf("subscriber %d got %+v", i, event)
+			}
+			if event.At.IsZero() {
+				t.Errorf("subscriber %d got an event without a time", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("subscriber %d got no event", i)
+		}
+	}
+}
+
+func TestHubOnlyNewEvents(t *testing.T) {
+	hub := NewHub()
+	hub.Publish("food.remaining", 1)
+
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	select {
+	case event := <-ch:
+		t.Fatalf("got %+v published before subscribing", event)
+	default:
+	}
+}
+
+func TestHubSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	slow, unsubscribeSlow := hub.Subscribe()
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := hub.Subscribe()
+	defer unsubscribeFast()
+
+	// The slow subscriber never reads; publishing must not block on it,
+	// and the subscriber keeping up still gets every event
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < bufferSize+10; i++ {
+			hub.Publish("food.remaining", i)
+			if event := <-fast; event.Data != i {
+				t.Errorf("fast subscriber got %v, want %d", event.Data, i)
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a slow subscriber")
+	}
+
+	if len(slow) != bufferSize {
+		t.Errorf("slow subscriber has %d events queued, want %d", len(slow), bufferSize)
+	}
+	if event := <-slow; event.Data != 0 {
+		t.Errorf("slow subscriber kept %v first, want the oldest event", event.Data)
+	}
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	hub := NewHub()
+	ch, unsubscribe := hub.Subscribe()
+	if got := hub.Subscribers(); got != 1 {
+		t.Fatalf("Subscribers() = %d, want 1", got)
+	}
+
+	unsubscribe()
+	unsubscribe()
+
+	if got := hub.Subscribers(); got != 0 {
+		t.Errorf("Subscribers() = %d after unsubscribing, want 0", got)
+	}
+	if _, open := <-ch; open {
+		t.Error("channel is still open after unsubscribing")
+	}
+
+	// Publishing to nobody is fine
+	hub.Publish("food.available", nil)
+}
+
+func TestHubConcurrent(t *testing.T) {
+	hub := NewHub()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ch, unsubscribe := hub.Subscribe()
+			hub.Publish("food.remaining", 1)
+			<-ch
+			unsubscribe()
+		}()
+		go func() {
+			defer wg.Done()
+			hub.Publish("food.remaining", 2)
+		}()
+	}
+	wg.Wait()
+
+	if got := hub.Subscribers(); got != 0 {
+		t.Errorf("Subscribers() = %d, want 0", got)
+	}
+}
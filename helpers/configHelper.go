@@ -0,0 +1,248 @@
+package helpers
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RestaurantLocation returns the restaurant's configured time zone.
+// It reads RESTAURANT_TIMEZONE (e.g. "Africa/Cairo") and defaults to UTC.
+func RestaurantLocation() *time.Location {
+	name := os.Getenv("RESTAURANT_TIMEZONE")
+	if name == "" {
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Println("invalid RESTAURANT_TIMEZONE, falling back to UTC:", err)
+		return time.UTC
+	}
+	return location
+}
+
+// TaxInclusivePricing reports whether food prices already include tax.
+// It reads TAX_PRICE_MODE (INCLUSIVE or EXCLUSIVE) and defaults to EXCLUSIVE.
+func TaxInclusivePricing() bool {
+	return strings.ToUpper(os.Getenv("TAX_PRICE_MODE")) == "INCLUSIVE"
+}
+
+// TaxRoundPerLine reports whether tax is rounded on every line instead of
+// once per invoice. It reads TAX_ROUNDING (LINE or INVOICE) and defaults to LINE.
+func TaxRoundPerLine() bool {
+	return strings.ToUpper(os.Getenv("TAX_ROUNDING")) != "INVOICE"
+}
+
+// ServiceChargeRate returns the automatic service charge percentage.
+// It reads SERVICE_CHARGE_RATE and defaults to 12.5.
+func ServiceChargeRate() float64 {
+	rate, err := strconv.ParseFloat(os.Getenv("SERVICE_CHARGE_RATE"), 64)
+	if err != nil || rate < 0 {
+		return 12.5
+	}
+	return rate
+}
+
+// ServiceChargeMinGuests returns the party size above which the service
+// charge is added. It reads SERVICE_CHARGE_MIN_GUESTS and defaults to 6.
+func ServiceChargeMinGuests() int {
+	guests, err := strconv.Atoi(os.Getenv("SERVICE_CHARGE_MIN_GUESTS"))
+	if err != nil || guests < 0 {
+		return 6
+	}
+	return guests
+}
+
+// FiscalYear returns the fiscal year a time falls in, named after the
+// calendar year it starts in. FISCAL_YEAR_START_MONTH (1-12) sets the first
+// month and defaults to January.
+func FiscalYear(t time.Time) int {
+	startMonth, err := strconv.Atoi(os.Getenv("FISCAL_YEAR_START_MONTH"))
+	if err != nil || startMonth < 1 || startMonth > 12 {
+		startMonth = 1
+	}
+
+	local := t.In(RestaurantLocation())
+	if int(local.Month()) < startMonth {
+		return local.Year() - 1
+	}
+	return local.Year()
+}
+
+// PaymentProvider returns the name of the default card payment provider.
+// It reads PAYMENT_PROVIDER and defaults to the built-in simulator.
+func PaymentProvider() string {
+	if name := os.Getenv("PAYMENT_PROVIDER"); name != "" {
+		return name
+	}
+	return "simulator"
+}
+
+// PaymentWebhookSecret returns the secret used to verify payment webhooks.
+// It reads PAYMENT_WEBHOOK_SECRET and is empty when unset; it never falls
+// back to the JWT secret.
+func PaymentWebhookSecret() string {
+	return os.Getenv("PAYMENT_WEBHOOK_SECRET")
+}
+
+// PaymentSimulatorEnabled reports whether the offline payment simulator
+// is registered. It is only on when APP_ENV is "development".
+func PaymentSimulatorEnabled() bool {
+	return strings.ToLower(os.Getenv("APP_ENV")) == "development"
+}
+
+// AdminEmail returns the email of the account made ADMIN at startup, which
+// is how a deployment gets its first administrator. It reads ADMIN_EMAIL
+// and is empty by default.
+func AdminEmail() string {
+	return strings.TrimSpace(os.Getenv("ADMIN_EMAIL"))
+}
+
+// RestaurantName returns the name printed on receipts.
+// It reads RESTAURANT_NAME and defaults to "Restaurant".
+func RestaurantName() string {
+	if name := os.Getenv("RESTAURANT_NAME"); name != "" {
+		return name
+	}
+	return "Restaurant"
+}
+
+// RestaurantAddress returns the address printed on receipts from RESTAURANT_ADDRESS
+func RestaurantAddress() string {
+	return os.Getenv("RESTAURANT_ADDRESS")
+}
+
+// RestaurantPhone returns the phone number printed on receipts from RESTAURANT_PHONE
+func RestaurantPhone() string {
+	return os.Getenv("RESTAURANT_PHONE")
+}
+
+// ReceiptTemplatePath returns the path of a custom receipt template.
+// It reads RECEIPT_TEMPLATE; when empty the built-in template is used.
+func ReceiptTemplatePath() string {
+	return os.Getenv("RECEIPT_TEMPLATE")
+}
+
+// PrintMaxAttempts returns how many times a print job is tried before it
+// is marked failed. It reads PRINT_MAX_ATTEMPTS and defaults to 3.
+func PrintMaxAttempts() int {
+	attempts, err := strconv.Atoi(os.Getenv("PRINT_MAX_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		return 3
+	}
+	return attempts
+}
+
+// OverdueCheckInterval returns how often invoices are checked for being
+// overdue. It reads OVERDUE_CHECK_MINUTES and defaults to 60 minutes.
+func OverdueCheckInterval() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("OVERDUE_CHECK_MINUTES"))
+	if err != nil || minutes < 1 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// ReminderInterval returns how long to wait between reminders for the same
+// overdue invoice. It reads REMINDER_INTERVAL_DAYS and defaults to 7 days.
+func ReminderInterval() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("REMINDER_INTERVAL_DAYS"))
+	if err != nil || days < 1 {
+		days = 7
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ServicePeriods returns the named service periods used for table
+// reporting. It reads SERVICE_PERIODS as NAME=HH:MM-HH:MM pairs separated
+// by commas and defaults to LUNCH=11:00-15:00,DINNER=17:00-23:00. Invalid
+// entries are skipped.
+func ServicePeriods() []ServicePeriod {
+	value := os.Getenv("SERVICE_PERIODS")
+	if value == "" {
+		value = "LUNCH=11:00-15:00,DINNER=17:00-23:00"
+	}
+
+	var periods []ServicePeriod
+	for _, entry := range strings.Split(value, ",") {
+		period, err := ParseServicePeriod(strings.TrimSpace(entry))
+		if err != nil {
+			log.Println("invalid SERVICE_PERIODS entry skipped:", err)
+			continue
+		}
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// ReservationDuration returns how long a table is held for a reservation
+// that does not give its own duration. It reads RESERVATION_MINUTES and
+// defaults to 90 minutes.
+func ReservationDuration() int {
+	minutes, err := strconv.Atoi(os.Getenv("RESERVATION_MINUTES"))
+	if err != nil || minutes < 15 {
+		return 90
+	}
+	return minutes
+}
+
+// GuestTokenSecret returns the secret used to sign table QR code tokens.
+// It reads GUEST_TOKEN_SECRET and is empty when unset; it never falls back
+// to the JWT secret, since every printed code is a token signed with it.
+func GuestTokenSecret() string {
+	return os.Getenv("GUEST_TOKEN_SECRET")
+}
+
+// GuestOrderURL returns the address a table's QR code opens, with the
+// table's token added as ?token=. It reads GUEST_ORDER_URL and defaults to
+// the API's own guest menu.
+func GuestOrderURL() string {
+	if url := os.Getenv("GUEST_ORDER_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:8080/guest/menu"
+}
+
+// OvertimeRules returns when worked hours count as overtime and what they
+// are paid. It reads OVERTIME_DAILY_HOURS (default 8), OVERTIME_WEEKLY_HOURS
+// (default 40) and OVERTIME_MULTIPLIER (default 1.5); a limit of 0 turns
+// that rule off.
+func OvertimeRules() OvertimeRule {
+	limit := func(name string, fallback float64) float64 {
+		hours, err := strconv.ParseFloat(os.Getenv(name), 64)
+		if err != nil || hours < 0 {
+			return fallback
+		}
+		return hours
+	}
+
+	multiplier, err := strconv.ParseFloat(os.Getenv("OVERTIME_MULTIPLIER"), 64)
+	if err != nil || multiplier < 1 {
+		multiplier = 1.5
+	}
+
+	return OvertimeRule{
+		DailyHours:  limit("OVERTIME_DAILY_HOURS", 8),
+		WeeklyHours: limit("OVERTIME_WEEKLY_HOURS", 40),
+		Multiplier:  multiplier,
+	}
+}
+
+// PinLockout returns how many incorrect time clock PINs in a row lock a
+// staff member's PIN, and for how long. It reads PIN_MAX_ATTEMPTS (default
+// 5) and PIN_LOCKOUT_MINUTES (default 15).
+func PinLockout() (int, time.Duration) {
+	attempts, err := strconv.Atoi(os.Getenv("PIN_MAX_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		attempts = 5
+	}
+
+	minutes, err := strconv.Atoi(os.Getenv("PIN_LOCKOUT_MINUTES"))
+	if err != nil || minutes < 1 {
+		minutes = 15
+	}
+	return attempts, time.Duration(minutes) * time.Minute
+}
@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+// PricedLine is an order item with the data needed to price discounts
+// and taxes
+type PricedLine struct {
+	FoodID        string
+	Category      string
+	TaxCategoryID string
+	Quantity      int
+	UnitPrice     float64
+}
+
+// RoundMoney rounds an amount to two decimal places
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// DiscountIsActive reports whether a discount can be used at the given time
+func DiscountIsActive(discount models.Discount, now time.Time) bool {
+	if discount.Active != nil && !*discount.Active {
+		return false
+	}
+	if discount.ValidFrom != nil && now.Before(*discount.ValidFrom) {
+		return false
+	}
+	if discount.ValidTo != nil && now.After(*discount.ValidTo) {
+		return false
+	}
+	if discount.UsageLimit != nil && discount.UsageCount >= *discount.UsageLimit {
+		return false
+	}
+	return true
+}
+
+// DiscountApplies reports whether a line falls within the discount scope
+func DiscountApplies(discount models.Discount, line PricedLine) bool {
+	switch *discount.Scope {
+	case "ITEM":
+		return discount.FoodID != nil && *discount.FoodID == line.FoodID
+	case "CATEGORY":
+		return discount.Category != nil && *discount.Category == line.Category
+	default:
+		return true
+	}
+}
+
+// CalculateDiscount returns the amount a discount takes off the given lines.
+// The result never exceeds the value of the lines it applies to.
+func CalculateDiscount(discount models.Discount, lines []PricedLine) float64 {
+	var eligible float64
+	var unitPrices []float64
+
+	for _, line := range lines {
+		if !DiscountApplies(discount, line) {
+			continue
+		}
+		eligible += float64(line.Quantity) * line.UnitPrice
+		for i := 0; i < line.Quantity; i++ {
+			unitPrices = append(unitPrices, line.UnitPrice)
+		}
+	}
+
+	if eligible <= 0 {
+		return 0
+	}
+
+	value := 0.0
+	if discount.Value != nil {
+		value = *discount.Value
+	}
+
+	var amount float64
+	switch *discount.Type {
+	case "PERCENTAGE":
+		amount = eligible * math.Min(value, 100) / 100
+	case "FIXED":
+		amount = value
+	case "BUY_X_GET_Y":
+		buy, get := 1, 1
+		if discount.BuyQuantity != nil {
+			buy = *discount.BuyQuantity
+		}
+		if discount.GetQuantity != nil {
+			get = *discount.GetQuantity
+		}
+
+		// The cheapest units are the free ones, once per full buy+get group
+		sort.Float64s(unitPrices)
+		free := (len(unitPrices) / (buy + get)) * get
+		for i := 0; i < free; i++ {
+			amount += unitPrices[i]
+		}
+	}
+
+	return RoundMoney(math.Min(amount, eligible))
+}
+
+// AllocateAmount spreads an amount over lines in proportion to their
+// weights. It is used to assign discounts to lines before taxing them.
+func AllocateAmount(amount float64, weights []float64) []float64 {
+	allocated := make([]float64, len(weights))
+
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 {
+		return allocated
+	}
+
+	for i, w := range weights {
+		allocated[i] = amount * w / total
+	}
+	return allocated
+}
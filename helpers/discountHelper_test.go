@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+func TestCalculateDiscount(t *testing.T) {
+	lines := []PricedLine{
+		{FoodID: "pizza", Category: "MAINS", Quantity: 2, UnitPrice: 10},
+		{FoodID: "cola", Category: "DRINKS", Quantity: 3, UnitPrice: 2},
+	}
+
+	percentage, fixed, buyGet := "PERCENTAGE", "FIXED", "BUY_X_GET_Y"
+	item, category, order := "ITEM", "CATEGORY", "ORDER"
+	pizza, salad, drinks := "pizza", "salad", "DRINKS"
+	five, ten, quarter, tooMuch := 5.0, 10.0, 25.0, 150.0
+	buy, get := 2, 1
+
+	tests := []struct {
+		name     string
+		discount models.Discount
+		want     float64
+	}{
+		{"percentage of the order", models.Discount{Type: &percentage, Scope: &order, Value: &ten}, 2.6},
+		{"percentage is capped at 100", models.Discount{Type: &percentage, Scope: &order, Value: &tooMuch}, 26},
+		{"percentage of one food", models.Discount{Type: &percentage, Scope: &item, FoodID: &pizza, Value: &quarter}, 5},
+		{"fixed is capped at the eligible lines", models.Discount{Type: &fixed, Scope: &category, Category: &drinks, Value: &ten}, 6},
+		{"fixed below the eligible lines", models.Discount{Type: &fixed, Scope: &order, Value: &five}, 5},
+		{"cheapest units are free", models.Discount{Type: &buyGet, Scope: &order, BuyQuantity: &buy, GetQuantity: &get}, 2},
+		{"nothing eligible", models.Discount{Type: &percentage, Scope: &item, FoodID: &salad, Value: &quarter}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalculateDiscount(tt.discount, lines); got != tt.want {
+				t.Errorf("CalculateDiscount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocateAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		weights []float64
+		want    []float64
+	}{
+		{"proportional", 10, []float64{30, 10}, []float64{7.5, 2.5}},
+		{"zero weights get nothing", 6, []float64{0, 20, 10}, []float64{0, 4, 2}},
+		{"no weight at all", 5, []float64{0, 0}, []float64{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AllocateAmount(tt.amount, tt.weights)
+			for i := range tt.want {
+				if RoundMoney(got[i]) != tt.want[i] {
+					t.Errorf("AllocateAmount() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package helpers
+
+import "math"
+
+// RoundQuantity rounds a stock quantity to three decimal places
+func RoundQuantity(quantity float64) float64 {
+	return math.Round(quantity*1000) / 1000
+}
@@ -0,0 +1,20 @@
+package helpers
+
+import "testing"
+
+func TestRoundQuantity(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want float64
+	}{
+		{0.1 + 0.2, 0.3},
+		{1.23456, 1.235},
+		{-0.0004, 0},
+	}
+
+	for _, tt := range tests {
+		if got := RoundQuantity(tt.in); got != tt.want {
+			t.Errorf("RoundQuantity(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"sort"
+	"time"
+)
+
+// OvertimeRule says when worked hours become overtime. A limit of 0 is off.
+type OvertimeRule struct {
+	DailyHours  float64 `json:"daily_hours"`
+	WeeklyHours float64 `json:"weekly_hours"`
+	Multiplier  float64 `json:"multiplier"`
+}
+
+// WorkedDay is the hours one person worked on one local day
+type WorkedDay struct {
+	Date     time.Time
+	Hours    float64
+	Regular  float64
+	Overtime float64
+}
+
+// WeekStart returns midnight of the Monday starting t's week in location
+func WeekStart(t time.Time, location *time.Location) time.Time {
+	local := t.In(location)
+	offset := (int(local.Weekday()) + 6) % 7
+	return time.Date(local.Year(), local.Month(), local.Day()-offset, 0, 0, 0, 0, location)
+}
+
+// SplitOvertime fills in the regular and overtime hours of one person's
+// days. Hours past the daily limit are overtime, and so are regular hours
+// past the weekly limit within a week starting on Monday; no hour is
+// counted twice.
+func SplitOvertime(days []WorkedDay, rule OvertimeRule) {
+	sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+
+	var week time.Time
+	weekRegular := 0.0
+	for i := range days {
+		day := &days[i]
+		if start := WeekStart(day.Date, day.Date.Location()); !start.Equal(week) {
+			week = start
+			weekRegular = 0
+		}
+
+		day.Regular = day.Hours
+		day.Overtime = 0
+		if rule.DailyHours > 0 && day.Regular > rule.DailyHours {
+			day.Overtime = day.Regular - rule.DailyHours
+			day.Regular = rule.DailyHours
+		}
+
+		if rule.WeeklyHours > 0 && weekRegular+day.Regular > rule.WeeklyHours {
+			over := weekRegular + day.Regular - rule.WeeklyHours
+			day.Regular -= over
+			day.Overtime += over
+		}
+		weekRegular += day.Regular
+	}
+}
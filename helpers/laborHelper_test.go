@@ -0,0 +1,104 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitOvertime(t *testing.T) {
+	// 2024-01-01 is a Monday
+	day := func(d int, hours float64) WorkedDay {
+		return WorkedDay{Date: time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC), Hours: hours}
+	}
+	standard := OvertimeRule{DailyHours: 8, WeeklyHours: 40, Multiplier: 1.5}
+
+	tests := []struct {
+		name     string
+		days     []WorkedDay
+		rule     OvertimeRule
+		regular  []float64
+		overtime []float64
+	}{
+		{
+			name:     "within both limits",
+			days:     []WorkedDay{day(1, 8), day(2, 6)},
+			rule:     standard,
+			regular:  []float64{8, 6},
+			overtime: []float64{0, 0},
+		},
+		{
+			name:     "past the daily limit",
+			days:     []WorkedDay{day(1, 10)},
+			rule:     standard,
+			regular:  []float64{8},
+			overtime: []float64{2},
+		},
+		{
+			name:     "past the weekly limit",
+			days:     []WorkedDay{day(1, 8), day(2, 8), day(3, 8), day(4, 8), day(5, 8), day(6, 4)},
+			rule:     standard,
+			regular:  []float64{8, 8, 8, 8, 8, 0},
+			overtime: []float64{0, 0, 0, 0, 0, 4},
+		},
+		{
+			name:     "daily overtime is not counted again weekly",
+			days:     []WorkedDay{day(1, 12), day(2, 12), day(3, 12), day(4, 12), day(5, 12), day(6, 4)},
+			rule:     standard,
+			regular:  []float64{8, 8, 8, 8, 8, 0},
+			overtime: []float64{4, 4, 4, 4, 4, 4},
+		},
+		{
+			name:     "weekly limit resets on Monday",
+			days:     []WorkedDay{day(3, 8), day(4, 8), day(5, 8), day(6, 8), day(7, 8), day(8, 8)},
+			rule:     OvertimeRule{WeeklyHours: 36},
+			regular:  []float64{8, 8, 8, 8, 4, 8},
+			overtime: []float64{0, 0, 0, 0, 4, 0},
+		},
+		{
+			name:     "days are sorted first",
+			days:     []WorkedDay{day(2, 4), day(1, 4)},
+			rule:     OvertimeRule{WeeklyHours: 6},
+			regular:  []float64{4, 2},
+			overtime: []float64{0, 2},
+		},
+		{
+			name:     "limits of zero are off",
+			days:     []WorkedDay{day(1, 14)},
+			rule:     OvertimeRule{},
+			regular:  []float64{14},
+			overtime: []float64{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SplitOvertime(tt.days, tt.rule)
+			for i, d := range tt.days {
+				if d.Regular != tt.regular[i] || d.Overtime != tt.overtime[i] {
+					t.Errorf("day %d: got %v regular, %v overtime; want %v, %v",
+						i, d.Regular, d.Overtime, tt.regular[i], tt.overtime[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	tests := []struct {
+		name string
+		in   time.Time
+		want time.Time
+	}{
+		{"monday", time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"sunday", time.Date(2024, 1, 7, 23, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"across a month", time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC), time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WeekStart(tt.in, time.UTC); !got.Equal(tt.want) {
+				t.Errorf("WeekStart(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package helpers
+
+import (
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+// minutesOfDay converts an "HH:MM" string to minutes after midnight
+func minutesOfDay(clock string) (int, bool) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// PriceRuleMatches reports whether a rule is in effect for a food at the
+// given time. The time is converted to the restaurant time zone first and
+// windows that end before they start run past midnight.
+func PriceRuleMatches(rule models.PriceRule, food models.Food, at time.Time) bool {
+	if rule.Active != nil && !*rule.Active {
+		return false
+	}
+	if rule.FoodID != nil && *rule.FoodID != food.FoodID {
+		return false
+	}
+	if rule.MenuID != nil && (food.MenuID == nil || *rule.MenuID != *food.MenuID) {
+		return false
+	}
+	if rule.StartTime == nil || rule.EndTime == nil {
+		return false
+	}
+
+	local := at.In(RestaurantLocation())
+	start, ok := minutesOfDay(*rule.StartTime)
+	if !ok {
+		return false
+	}
+	end, ok := minutesOfDay(*rule.EndTime)
+	if !ok {
+		return false
+	}
+
+	now := local.Hour()*60 + local.Minute()
+	day := int(local.Weekday())
+
+	// Past midnight the window belongs to the day it started on
+	if end <= start && now < end {
+		day = (day + 6) % 7
+	}
+
+	if len(rule.DaysOfWeek) > 0 {
+		found := false
+		for _, d := range rule.DaysOfWeek {
+			if d == day {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if end > start {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// specificity ranks food rules above menu rules above catch-all rules
+func specificity(rule models.PriceRule) int {
+	switch {
+	case rule.FoodID != nil:
+		return 2
+	case rule.MenuID != nil:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ResolvePrice returns the price of a food at the given time and the rule
+// that produced it, or the base food price and nil when no rule applies.
+// Higher priority wins, then the more specific rule.
+func ResolvePrice(food models.Food, rules []models.PriceRule, at time.Time) (float64, *models.PriceRule) {
+	var base float64
+	if food.Price != nil {
+		base = *food.Price
+	}
+
+	var best *models.PriceRule
+	for i := range rules {
+		rule := rules[i]
+		if !PriceRuleMatches(rule, food, at) {
+			continue
+		}
+		if best == nil ||
+			rule.Priority > best.Priority ||
+			(rule.Priority == best.Priority && specificity(rule) > specificity(*best)) {
+			best = &rules[i]
+		}
+	}
+
+	if best == nil {
+		return base, nil
+	}
+
+	if best.Price != nil {
+		return RoundMoney(*best.Price), best
+	}
+	if best.PercentOff != nil {
+		return RoundMoney(base * (1 - *best.PercentOff/100)), best
+	}
+	return base, best
+}
@@ -0,0 +1,150 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+func TestPriceRuleMatches(t *testing.T) {
+	t.Setenv("RESTAURANT_TIMEZONE", "")
+
+	menuId, otherMenu, otherFood := "drinks", "mains", "wine"
+	food := models.Food{FoodID: "beer", MenuID: &menuId}
+	five, eleven, two, seven := "17:00", "23:00", "02:00", "19:00"
+	off := false
+
+	// 2024-01-05 is a Friday
+	friday := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 5, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		rule models.PriceRule
+		at   time.Time
+		want bool
+	}{
+		{"inside the window", models.PriceRule{StartTime: &five, EndTime: &seven}, friday(18, 0), true},
+		{"start is included", models.PriceRule{StartTime: &five, EndTime: &seven}, friday(17, 0), true},
+		{"end is excluded", models.PriceRule{StartTime: &five, EndTime: &seven}, friday(19, 0), false},
+		{"before the window", models.PriceRule{StartTime: &five, EndTime: &seven}, friday(16, 59), false},
+		{"late night before midnight", models.PriceRule{StartTime: &eleven, EndTime: &two}, friday(23, 30), true},
+		{"late night after midnight", models.PriceRule{StartTime: &eleven, EndTime: &two}, friday(1, 30), true},
+		{"late night has ended", models.PriceRule{StartTime: &eleven, EndTime: &two}, friday(2, 0), false},
+		{"on a listed day", models.PriceRule{StartTime: &five, EndTime: &seven, DaysOfWeek: []int{5}}, friday(18, 0), true},
+		{"on another day", models.PriceRule{StartTime: &five, EndTime: &seven, DaysOfWeek: []int{1, 2}}, friday(18, 0), false},
+		{"after midnight belongs to the day before", models.PriceRule{StartTime: &eleven, EndTime: &two, DaysOfWeek: []int{4}}, friday(1, 0), true},
+		{"after midnight is not the new day", models.PriceRule{StartTime: &eleven, EndTime: &two, DaysOfWeek: []int{5}}, friday(1, 0), false},
+		{"inactive", models.PriceRule{StartTime: &five, EndTime: &seven, Active: &off}, friday(18, 0), false},
+		{"other food", models.PriceRule{StartTime: &five, EndTime: &seven, FoodID: &otherFood}, friday(18, 0), false},
+		{"same menu", models.PriceRule{StartTime: &five, EndTime: &seven, MenuID: &menuId}, friday(18, 0), true},
+		{"other menu", models.PriceRule{StartTime: &five, EndTime: &seven, MenuID: &otherMenu}, friday(18, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PriceRuleMatches(tt.rule, food, tt.at); got != tt.want {
+				t.Errorf("PriceRuleMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePrice(t *testing.T) {
+	t.Setenv("RESTAURANT_TIMEZONE", "")
+
+	foodId, menuId := "beer", "drinks"
+	price := 6.0
+	food := models.Food{FoodID: foodId, MenuID: &menuId, Price: &price}
+	start, end := "17:00", "19:00"
+	at := time.Date(2024, 1, 5, 18, 0, 0, 0, time.UTC)
+	happyHour, foodDeal, menuDeal := 4.0, 3.5, 5.0
+	half, third := 50.0, 33.333
+
+	tests := []struct {
+		name      string
+		rules     []models.PriceRule
+		at        time.Time
+		wantPrice float64
+		wantRule  int
+	}{
+		{
+			name:      "no rule keeps the food price",
+			at:        at,
+			wantPrice: 6,
+			wantRule:  -1,
+		},
+		{
+			name:      "rule out of its window",
+			rules:     []models.PriceRule{{StartTime: &start, EndTime: &end, Price: &happyHour}},
+			at:        at.Add(2 * time.Hour),
+			wantPrice: 6,
+			wantRule:  -1,
+		},
+		{
+			name:      "fixed price",
+			rules:     []models.PriceRule{{StartTime: &start, EndTime: &end, Price: &happyHour}},
+			at:        at,
+			wantPrice: 4,
+			wantRule:  0,
+		},
+		{
+			name:      "percent off is rounded",
+			rules:     []models.PriceRule{{StartTime: &start, EndTime: &end, PercentOff: &third}},
+			at:        at,
+			wantPrice: 4,
+			wantRule:  0,
+		},
+		{
+			name: "higher priority wins",
+			rules: []models.PriceRule{
+				{StartTime: &start, EndTime: &end, FoodID: &foodId, Price: &foodDeal},
+				{StartTime: &start, EndTime: &end, PercentOff: &half, Priority: 1},
+			},
+			at:        at,
+			wantPrice: 3,
+			wantRule:  1,
+		},
+		{
+			name: "same priority goes to the more specific rule",
+			rules: []models.PriceRule{
+				{StartTime: &start, EndTime: &end, Price: &happyHour},
+				{StartTime: &start, EndTime: &end, MenuID: &menuId, Price: &menuDeal},
+				{StartTime: &start, EndTime: &end, FoodID: &foodId, Price: &foodDeal},
+			},
+			at:        at,
+			wantPrice: 3.5,
+			wantRule:  2,
+		},
+		{
+			name: "first of equal rules",
+			rules: []models.PriceRule{
+				{StartTime: &start, EndTime: &end, Price: &happyHour},
+				{StartTime: &start, EndTime: &end, Price: &menuDeal},
+			},
+			at:        at,
+			wantPrice: 4,
+			wantRule:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rule := ResolvePrice(food, tt.rules, tt.at)
+			if got != tt.wantPrice {
+				t.Errorf("price = %v, want %v", got, tt.wantPrice)
+			}
+			if tt.wantRule < 0 {
+				if rule != nil {
+					t.Errorf("rule = %+v, want none", rule)
+				}
+				return
+			}
+			if rule != &tt.rules[tt.wantRule] {
+				t.Errorf("rule = %+v, want rule %d", rule, tt.wantRule)
+			}
+		})
+	}
+}
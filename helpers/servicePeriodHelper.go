@@ -0,0 +1,89 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ServicePeriod is a named daily window such as lunch or dinner. Start and
+// End are minutes after midnight; a window that ends before it starts runs
+// past midnight.
+type ServicePeriod struct {
+	Name  string `json:"name"`
+	Start int    `json:"-"`
+	End   int    `json:"-"`
+}
+
+// ParseServicePeriod parses a NAME=HH:MM-HH:MM service period
+func ParseServicePeriod(value string) (ServicePeriod, error) {
+	name, window, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return ServicePeriod{}, fmt.Errorf("%q is not NAME=HH:MM-HH:MM", value)
+	}
+
+	startClock, endClock, ok := strings.Cut(window, "-")
+	if !ok {
+		return ServicePeriod{}, fmt.Errorf("%q is not NAME=HH:MM-HH:MM", value)
+	}
+
+	start, ok := minutesOfDay(strings.TrimSpace(startClock))
+	if !ok {
+		return ServicePeriod{}, fmt.Errorf("%q has an invalid start time", value)
+	}
+	end, ok := minutesOfDay(strings.TrimSpace(endClock))
+	if !ok {
+		return ServicePeriod{}, fmt.Errorf("%q has an invalid end time", value)
+	}
+
+	return ServicePeriod{Name: strings.ToUpper(strings.TrimSpace(name)), Start: start, End: end}, nil
+}
+
+// Contains reports whether a local time falls inside the period
+func (p ServicePeriod) Contains(local time.Time) bool {
+	now := local.Hour()*60 + local.Minute()
+	if p.End > p.Start {
+		return now >= p.Start && now < p.End
+	}
+	return now >= p.Start || now < p.End
+}
+
+// Overlap returns how much of the period, repeated every day in location,
+// falls inside [from, to)
+func (p ServicePeriod) Overlap(from time.Time, to time.Time, location *time.Location) time.Duration {
+	length := p.End - p.Start
+	if length <= 0 {
+		length += 24 * 60
+	}
+
+	var total time.Duration
+	first := from.In(location)
+	day := time.Date(first.Year(), first.Month(), first.Day()-1, 0, 0, 0, 0, location)
+	for !day.After(to) {
+		start := time.Date(day.Year(), day.Month(), day.Day(), 0, p.Start, 0, 0, location)
+		end := time.Date(day.Year(), day.Month(), day.Day(), 0, p.Start+length, 0, 0, location)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return total
+}
+
+// ServicePeriodAt returns the name of the first period containing t in
+// location, or "" when t is outside every period
+func ServicePeriodAt(periods []ServicePeriod, t time.Time, location *time.Location) string {
+	local := t.In(location)
+	for _, period := range periods {
+		if period.Contains(local) {
+			return period.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package helpers
+
+import (
+	"errors"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// TableClaims are the claims of the token on a table's QR code. Version
+// matches the table's QR version, so rotating it retires printed codes.
+type TableClaims struct {
+	TableID string
+	Version int
+	jwt.StandardClaims
+}
+
+// errNoGuestTokenSecret is returned when GUEST_TOKEN_SECRET is not set
+var errNoGuestTokenSecret = errors.New("GUEST_TOKEN_SECRET is not set")
+
+// Matches reports whether the token is for a table at its current QR
+// version
+func (claims *TableClaims) Matches(tableId string, version int) bool {
+	return claims.TableID == tableId && claims.Version == version
+}
+
+// GenerateTableToken signs a guest ordering token for a table. It does not
+// expire; codes are retired by rotating the table's QR version.
+func GenerateTableToken(tableId string, version int) (string, error) {
+	secret := GuestTokenSecret()
+	if secret == "" {
+		return "", errNoGuestTokenSecret
+	}
+
+	claims := &TableClaims{
+		TableID:        tableId,
+		Version:        version,
+		StandardClaims: jwt.StandardClaims{Subject: "table"},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ValidateTableToken checks a guest ordering token and returns its claims
+func ValidateTableToken(signedToken string) (*TableClaims, error) {
+	secret := GuestTokenSecret()
+	if secret == "" {
+		return nil, errNoGuestTokenSecret
+	}
+
+	token, err := jwt.ParseWithClaims(
+		signedToken,
+		&TableClaims{},
+		func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return []byte(secret), nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*TableClaims)
+	if !ok || !token.Valid || claims.Subject != "table" || claims.TableID == "" {
+		return nil, errors.New("the table token is invalid")
+	}
+	return claims, nil
+}
@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func TestTableToken(t *testing.T) {
+	t.Setenv("GUEST_TOKEN_SECRET", "guest-secret")
+	t.Setenv("SECRET_KEY", "staff-secret")
+
+	sign := func(secret string, claims TableClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	signed, err := GenerateTableToken("t1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		tableId string
+		version int
+		valid   bool
+		matches bool
+	}{
+		{
+			name:    "current code",
+			token:   signed,
+			tableId: "t1",
+			version: 2,
+			valid:   true,
+			matches: true,
+		},
+		{
+			name:    "rotated version",
+			token:   signed,
+			tableId: "t1",
+			version: 3,
+			valid:   true,
+			matches: false,
+		},
+		{
+			name:    "other table",
+			token:   signed,
+			tableId: "t2",
+			version: 2,
+			valid:   true,
+			matches: false,
+		},
+		{
+			name:  "signed with the staff secret",
+			token: sign("staff-secret", TableClaims{TableID: "t1", Version: 2, StandardClaims: jwt.StandardClaims{Subject: "table"}}),
+		},
+		{
+			name: "expired",
+			token: sign("guest-secret", TableClaims{TableID: "t1", Version: 2, StandardClaims: jwt.StandardClaims{
+				Subject:   "table",
+				ExpiresAt: time.Now().Add(-time.Hour).Unix(),
+			}}),
+		},
+		{
+			name:  "not a table token",
+			token: sign("guest-secret", TableClaims{TableID: "t1", Version: 2, StandardClaims: jwt.StandardClaims{Subject: "user"}}),
+		},
+		{
+			name:  "no table",
+			token: sign("guest-secret", TableClaims{Version: 2, StandardClaims: jwt.StandardClaims{Subject: "table"}}),
+		},
+		{
+			name:  "tampered",
+			token: signed + "x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ValidateTableToken(tt.token)
+			if (err == nil) != tt.valid {
+				t.Fatalf("valid = %v, want %v (err %v)", err == nil, tt.valid, err)
+			}
+			if !tt.valid {
+				return
+			}
+			if got := claims.Matches(tt.tableId, tt.version); got != tt.matches {
+				t.Errorf("Matches(%q, %d) = %v, want %v", tt.tableId, tt.version, got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestTableTokenNeedsSecret(t *testing.T) {
+	t.Setenv("GUEST_TOKEN_SECRET", "")
+	t.Setenv("SECRET_KEY", "staff-secret")
+
+	if _, err := GenerateTableToken("t1", 1); err == nil {
+		t.Error("token was signed without GUEST_TOKEN_SECRET")
+	}
+	if _, err := ValidateTableToken("anything"); err == nil {
+		t.Error("token was accepted without GUEST_TOKEN_SECRET")
+	}
+}
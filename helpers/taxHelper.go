@@ -0,0 +1,92 @@
+package helpers
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+// TaxableLine is a line amount, after discounts, in one tax category
+type TaxableLine struct {
+	TaxCategoryID string
+	Name          string
+	Rate          float64
+	Amount        float64
+}
+
+// CalculateTaxes groups lines by tax category and returns the breakdown and
+// the tax total. With inclusive pricing the tax is extracted from the line
+// amounts, otherwise it is added on top. Rounding happens per line or once
+// on the invoice's tax total; either way the category lines add up to the
+// total.
+func CalculateTaxes(lines []TaxableLine, inclusive bool, roundPerLine bool) ([]models.TaxLine, float64) {
+	byCategory := map[string]*models.TaxLine{}
+	var order []string
+
+	for _, line := range lines {
+		if line.TaxCategoryID == "" {
+			continue
+		}
+
+		var tax float64
+		if inclusive {
+			tax = line.Amount - line.Amount/(1+line.Rate/100)
+		} else {
+			tax = line.Amount * line.Rate / 100
+		}
+		if roundPerLine {
+			tax = RoundMoney(tax)
+		}
+
+		net := line.Amount
+		if inclusive {
+			net = line.Amount - tax
+		}
+
+		// Takeaway and dine-in rates of one category are reported separately
+		key := line.TaxCategoryID + "|" + strconv.FormatFloat(line.Rate, 'f', 3, 64)
+		taxLine, ok := byCategory[key]
+		if !ok {
+			taxLine = &models.TaxLine{
+				TaxCategoryID: line.TaxCategoryID,
+				Name:          line.Name,
+				Rate:          line.Rate,
+			}
+			byCategory[key] = taxLine
+			order = append(order, key)
+		}
+		taxLine.NetAmount += net
+		taxLine.TaxAmount += tax
+	}
+
+	sort.Strings(order)
+	breakdown := []models.TaxLine{}
+	var total, rounded float64
+	for _, key := range order {
+		taxLine := byCategory[key]
+		total += taxLine.TaxAmount
+		taxLine.TaxAmount = RoundMoney(taxLine.TaxAmount)
+		taxLine.NetAmount = RoundMoney(taxLine.NetAmount)
+		rounded += taxLine.TaxAmount
+		breakdown = append(breakdown, *taxLine)
+	}
+	total = RoundMoney(total)
+
+	// Rounding the categories on their own can be a cent off the total;
+	// the largest category takes the difference
+	if diff := RoundMoney(total - rounded); diff != 0 {
+		largest := 0
+		for i := range breakdown {
+			if breakdown[i].TaxAmount > breakdown[largest].TaxAmount {
+				largest = i
+			}
+		}
+		breakdown[largest].TaxAmount = RoundMoney(breakdown[largest].TaxAmount + diff)
+		if inclusive {
+			breakdown[largest].NetAmount = RoundMoney(breakdown[largest].NetAmount - diff)
+		}
+	}
+
+	return breakdown, total
+}
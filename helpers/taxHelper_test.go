@@ -0,0 +1,78 @@
+package helpers
+
+import "testing"
+
+func TestCalculateTaxes(t *testing.T) {
+	lines := []TaxableLine{
+		{TaxCategoryID: "food", Name: "Food", Rate: 14, Amount: 10.05},
+		{TaxCategoryID: "food", Name: "Food", Rate: 14, Amount: 10.05},
+		{TaxCategoryID: "food", Name: "Food", Rate: 5, Amount: 20},
+		{TaxCategoryID: "", Amount: 100},
+	}
+
+	tests := []struct {
+		name         string
+		inclusive    bool
+		roundPerLine bool
+		wantTotal    float64
+		wantLines    int
+		wantFirstNet float64
+	}{
+		{"exclusive, rounded per line", false, true, 3.82, 2, 20.1},
+		{"exclusive, rounded per invoice", false, false, 3.81, 2, 20.1},
+		{"inclusive, rounded per line", true, true, 3.41, 2, 17.64},
+		{"inclusive, rounded per invoice", true, false, 3.42, 2, 17.63},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown, total := CalculateTaxes(lines, tt.inclusive, tt.roundPerLine)
+			if total != tt.wantTotal {
+				t.Errorf("total = %v, want %v", total, tt.wantTotal)
+			}
+			if len(breakdown) != tt.wantLines {
+				t.Fatalf("got %d tax lines, want %d", len(breakdown), tt.wantLines)
+			}
+			if breakdown[0].Rate != 14 || breakdown[0].NetAmount != tt.wantFirstNet {
+				t.Errorf("first line = %+v, want the 14%% rate with net %v", breakdown[0], tt.wantFirstNet)
+			}
+		})
+	}
+}
+
+func TestCalculateTaxesRoundsOncePerInvoice(t *testing.T) {
+	// Each category's tax is 0.994
+	lines := []TaxableLine{
+		{TaxCategoryID: "food", Name: "Food", Rate: 10, Amount: 9.94},
+		{TaxCategoryID: "drinks", Name: "Drinks", Rate: 20, Amount: 4.97},
+	}
+
+	tests := []struct {
+		name         string
+		roundPerLine bool
+		wantTotal    float64
+		wantLines    []float64
+	}{
+		{"per line", true, 1.98, []float64{0.99, 0.99}},
+		{"per invoice", false, 1.99, []float64{1, 0.99}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown, total := CalculateTaxes(lines, false, tt.roundPerLine)
+			if total != tt.wantTotal {
+				t.Errorf("total = %v, want %v", total, tt.wantTotal)
+			}
+			var sum float64
+			for i, want := range tt.wantLines {
+				if breakdown[i].TaxAmount != want {
+					t.Errorf("%s tax = %v, want %v", breakdown[i].Name, breakdown[i].TaxAmount, want)
+				}
+				sum += breakdown[i].TaxAmount
+			}
+			if RoundMoney(sum) != total {
+				t.Errorf("lines add up to %v, want the total %v", sum, total)
+			}
+		})
+	}
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"log"
 	"os"
+	"time"
 
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
 	"github.com/ali-adel-nour/restaurant-management/database"
+	"github.com/ali-adel-nour/restaurant-management/helpers"
 	"github.com/ali-adel-nour/restaurant-management/middleware"
+	"github.com/ali-adel-nour/restaurant-management/payments"
+	"github.com/ali-adel-nour/restaurant-management/printing"
 	"github.com/ali-adel-nour/restaurant-management/routes"
 	"github.com/gin-gonic/gin"
 )
@@ -22,12 +29,45 @@ func main() {
 	// Initialize collections
 	database.InitCollections()
 
+	// Move documents saved before the models had bson tags to the current keys
+	if err := database.MigrateLegacyKeys(context.Background()); err != nil {
+		log.Fatal("Failed to migrate legacy keys:", err)
+	}
+
+	// Give an existing deployment its first administrator
+	if err := controller.EnsureAdmin(context.Background(), helpers.AdminEmail()); err != nil {
+		log.Fatal("Failed to set up the admin user:", err)
+	}
+
+	// Create the unique indexes that guard against duplicate records
+	if err := database.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal("Failed to create indexes:", err)
+	}
+
+	// Register payment providers; the simulator approves any card, so it is
+	// only available in development
+	if helpers.PaymentSimulatorEnabled() {
+		secret := helpers.PaymentWebhookSecret()
+		if secret == "" {
+			log.Fatal("PAYMENT_WEBHOOK_SECRET is required to register the payment simulator")
+		}
+		payments.Register(payments.NewSimulator(secret))
+	}
+
+	// Start the print queue for kitchen tickets and receipts
+	printing.StartDefault(context.Background(), 2, helpers.PrintMaxAttempts(), 2*time.Second)
+
+	// Mark overdue invoices and send payment reminders in the background
+	controller.StartOverdueScheduler(context.Background(), helpers.OverdueCheckInterval())
+
 	// Create Gin router
 	router := gin.New()
 	router.Use(gin.Logger())
 
 	// Public routes (no authentication required)
 	routes.UserRoutes(router)
+	routes.WebhookRoutes(router)
+	routes.GuestRoutes(router)
 
 	// Protected routes (authentication required)
 	router.Use(middleware.Authentication())
@@ -38,6 +78,26 @@ func main() {
 	routes.OrderItemRoutes(router)
 	routes.InvoiceRoutes(router)
 	routes.NoteRoutes(router)
+	routes.DiscountRoutes(router)
+	routes.PriceRuleRoutes(router)
+	routes.TaxCategoryRoutes(router)
+	routes.ReportRoutes(router)
+	routes.PaymentIntentRoutes(router)
+	routes.PrinterRoutes(router)
+	routes.HouseAccountRoutes(router)
+	routes.BusinessDayRoutes(router)
+	routes.DrawerRoutes(router)
+	routes.AreaRoutes(router)
+	routes.SectionRoutes(router)
+	routes.FloorPlanRoutes(router)
+	routes.ReservationRoutes(router)
+	routes.WaitlistRoutes(router)
+	routes.TableGroupRoutes(router)
+	routes.GuestOrderRoutes(router)
+	routes.ShiftRoutes(router)
+	routes.TimeClockRoutes(router)
+	routes.IngredientRoutes(router)
+	routes.EventRoutes(router)
 
 	// Start server
 	router.Run(":" + port)
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Area is a part of the restaurant floor, such as the patio or the bar
+type Area struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	SortOrder *int               `bson:"sort_order" json:"sort_order"`
+	Width     *float64           `bson:"width" json:"width" validate:"omitempty,gt=0"`
+	Height    *float64           `bson:"height" json:"height" validate:"omitempty,gt=0"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	AreaID    string             `bson:"area_id" json:"area_id"`
+}
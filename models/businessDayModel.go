@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BusinessDay is one trading day, from opening until the end-of-day close.
+// Once CLOSED it holds the Z report and its orders can no longer change.
+type BusinessDay struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Date          string             `bson:"date" json:"date"`
+	Status        string             `bson:"status" json:"status"`
+	OpenedAt      time.Time          `bson:"opened_at" json:"opened_at"`
+	ClosedAt      *time.Time         `bson:"closed_at" json:"closed_at"`
+	ClosedBy      string             `bson:"closed_by" json:"closed_by"`
+	ZNumber       int                `bson:"z_number" json:"z_number"`
+	ZReport       *ZReport           `bson:"z_report" json:"z_report"`
+	BusinessDayID string             `bson:"business_day_id" json:"business_day_id"`
+}
+
+// ZReport summarizes the takings of a business day
+type ZReport struct {
+	BusinessDate  string          `bson:"business_date" json:"business_date"`
+	ZNumber       int             `bson:"z_number" json:"z_number"`
+	From          time.Time       `bson:"from" json:"from"`
+	To            time.Time       `bson:"to" json:"to"`
+	InvoiceCount  int             `bson:"invoice_count" json:"invoice_count"`
+	Covers        int             `bson:"covers" json:"covers"`
+	GrossSales    float64         `bson:"gross_sales" json:"gross_sales"`
+	DiscountTotal float64         `bson:"discount_total" json:"discount_total"`
+	Discounts     []DiscountLine  `bson:"discounts" json:"discounts"`
+	ServiceCharge float64         `bson:"service_charge" json:"service_charge"`
+	TaxTotal      float64         `bson:"tax_total" json:"tax_total"`
+	Taxes         []TaxLine       `bson:"taxes" json:"taxes"`
+	Total         float64         `bson:"total" json:"total"`
+	RefundTotal   float64         `bson:"refund_total" json:"refund_total"`
+	NetTotal      float64         `bson:"net_total" json:"net_total"`
+	Tenders       []TenderTotal   `bson:"tenders" json:"tenders"`
+	Refunds       []TenderTotal   `bson:"refunds" json:"refunds"`
+	VoidCount     int             `bson:"void_count" json:"void_count"`
+	VoidTotal     float64         `bson:"void_total" json:"void_total"`
+	TipTotal      float64         `bson:"tip_total" json:"tip_total"`
+	Drawers       []DrawerSession `bson:"drawers" json:"drawers"`
+}
+
+// TenderTotal is the amount taken or refunded with one payment method
+type TenderTotal struct {
+	Method string  `bson:"method" json:"method"`
+	Count  int     `bson:"count" json:"count"`
+	Amount float64 `bson:"amount" json:"amount"`
+}
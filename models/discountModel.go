@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Discount represents a promotion or coupon code.
+// Type is PERCENTAGE, FIXED or BUY_X_GET_Y and Scope is ITEM (FoodID),
+// CATEGORY (menu Category) or ORDER.
+type Discount struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Type        *string            `bson:"type" json:"type" validate:"required,eq=PERCENTAGE|eq=FIXED|eq=BUY_X_GET_Y"`
+	Scope       *string            `bson:"scope" json:"scope" validate:"required,eq=ITEM|eq=CATEGORY|eq=ORDER"`
+	Value       *float64           `bson:"value" json:"value" validate:"omitempty,gte=0"`
+	FoodID      *string            `bson:"food_id" json:"food_id"`
+	Category    *string            `bson:"category" json:"category"`
+	BuyQuantity *int               `bson:"buy_quantity" json:"buy_quantity" validate:"omitempty,gte=1"`
+	GetQuantity *int               `bson:"get_quantity" json:"get_quantity" validate:"omitempty,gte=1"`
+	CouponCode  *string            `bson:"coupon_code" json:"coupon_code"`
+	UsageLimit  *int               `bson:"usage_limit" json:"usage_limit" validate:"omitempty,gte=1"`
+	UsageCount  int                `bson:"usage_count" json:"usage_count"`
+	ValidFrom   *time.Time         `bson:"valid_from" json:"valid_from"`
+	ValidTo     *time.Time         `bson:"valid_to" json:"valid_to"`
+	Active      *bool              `bson:"active" json:"active"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	DiscountID  string             `bson:"discount_id" json:"discount_id"`
+}
+
+// OrderDiscount records a discount applied to an order
+type OrderDiscount struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrderID         string             `bson:"order_id" json:"order_id"`
+	DiscountID      string             `bson:"discount_id" json:"discount_id"`
+	CouponCode      *string            `bson:"coupon_code" json:"coupon_code"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	OrderDiscountID string             `bson:"order_discount_id" json:"order_discount_id"`
+}
+
+// DiscountLine is a discount amount shown in order totals and invoices
+type DiscountLine struct {
+	DiscountID string  `bson:"discount_id" json:"discount_id"`
+	Name       string  `bson:"name" json:"name"`
+	CouponCode *string `bson:"coupon_code" json:"coupon_code"`
+	Amount     float64 `bson:"amount" json:"amount"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DrawerSession is a cash drawer from the moment it is opened with a float
+// until its cash is counted. Expected is the float plus cash taken and
+// paid in, less cash refunded and paid out; Variance is Counted - Expected.
+type DrawerSession struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BusinessDayID   string             `bson:"business_day_id" json:"business_day_id"`
+	Name            *string            `bson:"name" json:"name" validate:"required,min=1,max=50"`
+	Status          string             `bson:"status" json:"status"`
+	OpeningFloat    *float64           `bson:"opening_float" json:"opening_float" validate:"required,gte=0"`
+	Movements       []CashMovement     `bson:"movements" json:"movements"`
+	CashSales       float64            `bson:"cash_sales" json:"cash_sales"`
+	CashRefunds     float64            `bson:"cash_refunds" json:"cash_refunds"`
+	Expected        float64            `bson:"expected" json:"expected"`
+	Counted         *float64           `bson:"counted" json:"counted"`
+	Variance        float64            `bson:"variance" json:"variance"`
+	OpenedBy        string             `bson:"opened_by" json:"opened_by"`
+	OpenedAt        time.Time          `bson:"opened_at" json:"opened_at"`
+	ClosedBy        string             `bson:"closed_by" json:"closed_by"`
+	ClosedAt        *time.Time         `bson:"closed_at" json:"closed_at"`
+	DrawerSessionID string             `bson:"drawer_session_id" json:"drawer_session_id"`
+}
+
+// CashMovement is cash put into (IN) or taken out of (OUT) a drawer for
+// anything other than a sale, such as change or a supplier paid in cash
+type CashMovement struct {
+	Type      *string   `bson:"type" json:"type" validate:"required,eq=IN|eq=OUT"`
+	Amount    *float64  `bson:"amount" json:"amount" validate:"required,gt=0"`
+	Reason    *string   `bson:"reason" json:"reason" validate:"required,min=2,max=200"`
+	By        string    `bson:"by" json:"by"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
@@ -6,14 +6,25 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Food represents a food item in the restaurant
+// Food represents a food item in the restaurant. A food that is not
+// Available is sold out ("86'd"); when RemainingCount is set, only that
+// many more portions can be ordered. SoldOutReason says why a food is sold
+// out: MANUAL, NO_PORTIONS or INGREDIENT, with SoldOutIngredientID the
+// ingredient that ran out.
 type Food struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name      *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
-	Price     *float64           `bson:"price" json:"price" validate:"required"`
-	FoodImage *string            `bson:"food_image" json:"food_image" validate:"required"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
-	FoodID    string             `bson:"food_id" json:"food_id"`
-	MenuID    *string            `bson:"menu_id" json:"menu_id" validate:"required"`
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name                *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Price               *float64           `bson:"price" json:"price" validate:"required"`
+	FoodImage           *string            `bson:"food_image" json:"food_image" validate:"required"`
+	CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
+	FoodID              string             `bson:"food_id" json:"food_id"`
+	MenuID              *string            `bson:"menu_id" json:"menu_id" validate:"required"`
+	TaxCategoryID       *string            `bson:"tax_category_id" json:"tax_category_id"`
+	Station             *string            `bson:"station" json:"station" validate:"omitempty,max=50"`
+	Cost                *float64           `bson:"cost" json:"cost" validate:"omitempty,gte=0"`
+	Available           *bool              `bson:"available" json:"available"`
+	RemainingCount      *int               `bson:"remaining_count" json:"remaining_count" validate:"omitempty,gte=0,lte=10000"`
+	SoldOutReason       string             `bson:"sold_out_reason" json:"sold_out_reason"`
+	SoldOutIngredientID *string            `bson:"sold_out_ingredient_id" json:"sold_out_ingredient_id"`
 }
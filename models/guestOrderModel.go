@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GuestOrder is an order a guest placed from their phone by scanning the
+// table's QR code. It waits for staff to approve it into the table's order.
+type GuestOrder struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TableID        string             `bson:"table_id" json:"table_id"`
+	TableNumber    *int               `bson:"table_number" json:"table_number"`
+	Items          []GuestOrderItem   `bson:"items" json:"items" validate:"required,min=1,max=20,dive"`
+	NumberOfGuests *int               `bson:"number_of_guests" json:"number_of_guests" validate:"omitempty,min=1"`
+	Note           *string            `bson:"note" json:"note" validate:"omitempty,max=300"`
+	Status         string             `bson:"status" json:"status"`
+	OrderID        *string            `bson:"order_id" json:"order_id"`
+	ReviewedBy     string             `bson:"reviewed_by" json:"reviewed_by"`
+	ReviewedAt     *time.Time         `bson:"reviewed_at" json:"reviewed_at"`
+	RejectReason   *string            `bson:"reject_reason" json:"reject_reason"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	GuestOrderID   string             `bson:"guest_order_id" json:"guest_order_id"`
+}
+
+// GuestOrderItem is one food a guest asked for, with the name and price
+// they were shown
+type GuestOrderItem struct {
+	FoodID    *string `bson:"food_id" json:"food_id" validate:"required"`
+	Quantity  *int    `bson:"quantity" json:"quantity" validate:"required,eq=1|eq=2|eq=3|eq=4|eq=5"`
+	Name      *string `bson:"name" json:"name"`
+	UnitPrice float64 `bson:"unit_price" json:"unit_price"`
+}
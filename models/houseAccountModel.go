@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HouseAccount is a customer who can be invoiced and pay later, such as a
+// company with a monthly tab
+type HouseAccount struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Email       *string            `bson:"email" json:"email" validate:"omitempty,email"`
+	Phone       *string            `bson:"phone" json:"phone"`
+	CreditLimit *float64           `bson:"credit_limit" json:"credit_limit" validate:"omitempty,gte=0"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	AccountID   string             `bson:"account_id" json:"account_id"`
+}
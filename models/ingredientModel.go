@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ingredient is a stocked ingredient. Stock is in the ingredient's unit
+// and only changes through stock movements; it can go below zero when more
+// is sold than was recorded.
+type Ingredient struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name              *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Unit              *string            `bson:"unit" json:"unit" validate:"required,eq=g|eq=kg|eq=ml|eq=l|eq=each"`
+	Stock             float64            `bson:"stock" json:"stock"`
+	LowStockThreshold *float64           `bson:"low_stock_threshold" json:"low_stock_threshold" validate:"omitempty,gte=0"`
+	UnitCost          *float64           `bson:"unit_cost" json:"unit_cost" validate:"omitempty,gte=0"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+	IngredientID      string             `bson:"ingredient_id" json:"ingredient_id"`
+}
@@ -6,14 +6,48 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Invoice represents an invoice for an order
+// Invoice represents an invoice for an order. Lines and amounts are a
+// snapshot taken when the invoice is created.
 type Invoice struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	InvoiceID     string             `json:"invoice_id"`
-	OrderID       string             `json:"order_id"`
-	PaymentMethod *string            `json:"payment_method" validate:"eq=CARD|eq=CASH|eq="`
-	PaymentStatus *string            `json:"payment_status" validate:"required,eq=PENDING|eq=PAID"`
-	PaymentDue    *time.Time         `json:"payment_due"`
-	CreatedAt     time.Time          `json:"created_at"`
-	UpdatedAt     time.Time          `json:"updated_at"`
+	InvoiceID     string             `bson:"invoice_id" json:"invoice_id"`
+	InvoiceNumber string             `bson:"invoice_number" json:"invoice_number"`
+	FiscalYear    int                `bson:"fiscal_year" json:"fiscal_year"`
+	OrderID       string             `bson:"order_id" json:"order_id"`
+	ServerID      *string            `bson:"server_id" json:"server_id"`
+	PaymentMethod *string            `bson:"payment_method" json:"payment_method" validate:"eq=CARD|eq=CASH|eq=GIFT_CARD|eq=MIXED|eq="`
+	PaymentStatus *string            `bson:"payment_status" json:"payment_status" validate:"required,eq=PENDING|eq=PARTIALLY_PAID|eq=OVERDUE|eq=PAID|eq=PARTIALLY_REFUNDED|eq=REFUNDED"`
+	PaymentDue    *time.Time         `bson:"payment_due" json:"payment_due"`
+	AccountID     *string            `bson:"account_id" json:"account_id"`
+	ReminderCount int                `bson:"reminder_count" json:"reminder_count"`
+	LastReminder  *time.Time         `bson:"last_reminder" json:"last_reminder"`
+	Lines         []InvoiceLine      `bson:"lines" json:"lines"`
+	SubTotal      float64            `bson:"sub_total" json:"sub_total"`
+	Discounts     []DiscountLine     `bson:"discounts" json:"discounts"`
+	DiscountTotal float64            `bson:"discount_total" json:"discount_total"`
+	TaxInclusive  bool               `bson:"tax_inclusive" json:"tax_inclusive"`
+	Taxes         []TaxLine          `bson:"taxes" json:"taxes"`
+	TaxTotal      float64            `bson:"tax_total" json:"tax_total"`
+	Covers        int                `bson:"covers" json:"covers"`
+	ServiceRate   float64            `bson:"service_rate" json:"service_rate"`
+	ServiceCharge float64            `bson:"service_charge" json:"service_charge"`
+	Total         float64            `bson:"total" json:"total"`
+	AmountPaid    float64            `bson:"amount_paid" json:"amount_paid"`
+	BalanceDue    float64            `bson:"balance_due" json:"balance_due"`
+	RefundedTotal float64            `bson:"refunded_total" json:"refunded_total"`
+	TipTotal      float64            `bson:"tip_total" json:"tip_total"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// InvoiceLine is an order item as it was priced when the invoice was created
+type InvoiceLine struct {
+	OrderItemID   string  `bson:"order_item_id" json:"order_item_id"`
+	FoodID        string  `bson:"food_id" json:"food_id"`
+	Name          string  `bson:"name" json:"name"`
+	Quantity      int     `bson:"quantity" json:"quantity"`
+	UnitPrice     float64 `bson:"unit_price" json:"unit_price"`
+	LineTotal     float64 `bson:"line_total" json:"line_total"`
+	TaxCategoryID *string `bson:"tax_category_id" json:"tax_category_id"`
+	PriceRule     *string `bson:"price_rule" json:"price_rule"`
 }
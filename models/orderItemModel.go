@@ -9,11 +9,17 @@ import (
 // OrderItem represents an item in an order
 type OrderItem struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Quantity    *int               `json:"quantity" validate:"required,eq=1|eq=2|eq=3|eq=4|eq=5"`
-	UnitPrice   *float64           `json:"unit_price" validate:"required"`
-	CreatedAt   time.Time          `json:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at"`
-	FoodID      *string            `json:"food_id" validate:"required"`
-	OrderItemID string             `json:"order_item_id"`
-	OrderID     string             `json:"order_id" validate:"required"`
+	Quantity    *int               `bson:"quantity" json:"quantity" validate:"required,eq=1|eq=2|eq=3|eq=4|eq=5"`
+	UnitPrice   *float64           `bson:"unit_price" json:"unit_price" validate:"omitempty,gte=0"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	FoodID      *string            `bson:"food_id" json:"food_id" validate:"required"`
+	OrderItemID string             `bson:"order_item_id" json:"order_item_id"`
+	OrderID     string             `bson:"order_id" json:"order_id" validate:"required"`
+	PriceRuleID *string            `bson:"price_rule_id" json:"price_rule_id"`
+	PriceRule   *string            `bson:"price_rule" json:"price_rule"`
+	Voided      bool               `bson:"voided" json:"voided"`
+	VoidReason  *string            `bson:"void_reason" json:"void_reason"`
+	VoidedBy    string             `bson:"voided_by" json:"voided_by"`
+	VoidedAt    *time.Time         `bson:"voided_at" json:"voided_at"`
 }
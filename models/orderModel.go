@@ -8,10 +8,23 @@ import (
 
 // Order represents a customer order
 type Order struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	OrderDate time.Time          `json:"order_date" validate:"required"`
-	CreatedAt time.Time          `json:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at"`
-	OrderID   string             `json:"order_id"`
-	TableID   *string            `json:"table_id" validate:"required"`
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrderDate      time.Time          `bson:"order_date" json:"order_date" validate:"required"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	OrderID        string             `bson:"order_id" json:"order_id"`
+	TableID        *string            `bson:"table_id" json:"table_id" validate:"required"`
+	OrderType      *string            `bson:"order_type" json:"order_type" validate:"omitempty,eq=DINE_IN|eq=TAKEAWAY"`
+	Status         *string            `bson:"status" json:"status" validate:"omitempty,eq=OPEN|eq=CLOSED|eq=VOID"`
+	BusinessDayID  string             `bson:"business_day_id" json:"business_day_id"`
+	NumberOfGuests *int               `bson:"number_of_guests" json:"number_of_guests" validate:"omitempty,min=1"`
+	SeatedAt       *time.Time         `bson:"seated_at" json:"seated_at"`
+	UnseatedAt     *time.Time         `bson:"unseated_at" json:"unseated_at"`
+	ReservationID  *string            `bson:"reservation_id" json:"reservation_id"`
+	TableGroupID   *string            `bson:"table_group_id" json:"table_group_id"`
+	ServerID       *string            `bson:"server_id" json:"server_id"`
+	CreatedBy      string             `bson:"created_by" json:"created_by"`
+	VoidReason     *string            `bson:"void_reason" json:"void_reason"`
+	VoidedBy       string             `bson:"voided_by" json:"voided_by"`
+	VoidedAt       *time.Time         `bson:"voided_at" json:"voided_at"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PaymentIntent tracks a card payment through a payment provider from
+// authorization to capture, void or refund
+type PaymentIntent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	InvoiceID   string             `bson:"invoice_id" json:"invoice_id"`
+	Provider    string             `bson:"provider" json:"provider"`
+	ProviderRef string             `bson:"provider_ref" json:"provider_ref"`
+	Amount      float64            `bson:"amount" json:"amount"`
+	Captured    float64            `bson:"captured" json:"captured"`
+	Refunded    float64            `bson:"refunded" json:"refunded"`
+	Status      string             `bson:"status" json:"status"`
+	Message     string             `bson:"message" json:"message"`
+	PaymentID   *string            `bson:"payment_id" json:"payment_id"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	IntentID    string             `bson:"intent_id" json:"intent_id"`
+}
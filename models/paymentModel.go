@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Payment is one tender settling part or all of an invoice. For cash,
+// Tendered is what the customer handed over and ChangeDue what they get back.
+type Payment struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	InvoiceID       string             `bson:"invoice_id" json:"invoice_id"`
+	Method          *string            `bson:"method" json:"method" validate:"required,eq=CASH|eq=CARD|eq=GIFT_CARD"`
+	Amount          *float64           `bson:"amount" json:"amount" validate:"omitempty,gt=0"`
+	Tendered        *float64           `bson:"tendered" json:"tendered" validate:"omitempty,gt=0"`
+	ChangeDue       float64            `bson:"change_due" json:"change_due"`
+	Refunded        float64            `bson:"refunded" json:"refunded"`
+	Reference       *string            `bson:"reference" json:"reference"`
+	IntentID        *string            `bson:"intent_id" json:"intent_id"`
+	ReceivedBy      string             `bson:"received_by" json:"received_by"`
+	DrawerSessionID *string            `bson:"drawer_session_id" json:"drawer_session_id"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	PaymentID       string             `bson:"payment_id" json:"payment_id"`
+}
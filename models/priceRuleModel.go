@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PriceRule overrides food prices on given days and times (happy hour,
+// lunch specials). A rule applies to one food, every food of a menu, or
+// every food when neither is set. Times are "HH:MM" in the restaurant
+// time zone and DaysOfWeek uses 0 for Sunday; an empty list means every day.
+type PriceRule struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	FoodID      *string            `bson:"food_id" json:"food_id"`
+	MenuID      *string            `bson:"menu_id" json:"menu_id"`
+	DaysOfWeek  []int              `bson:"days_of_week" json:"days_of_week" validate:"omitempty,dive,min=0,max=6"`
+	StartTime   *string            `bson:"start_time" json:"start_time" validate:"required,datetime=15:04"`
+	EndTime     *string            `bson:"end_time" json:"end_time" validate:"required,datetime=15:04"`
+	Price       *float64           `bson:"price" json:"price" validate:"omitempty,gte=0"`
+	PercentOff  *float64           `bson:"percent_off" json:"percent_off" validate:"omitempty,gt=0,lte=100"`
+	Priority    int                `bson:"priority" json:"priority"`
+	Active      *bool              `bson:"active" json:"active"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	PriceRuleID string             `bson:"price_rule_id" json:"price_rule_id"`
+}
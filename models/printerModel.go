@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Printer is an ESC/POS printer reachable over TCP. KITCHEN printers get
+// tickets for their station (or every station when Station is empty);
+// RECEIPT printers print customer receipts.
+type Printer struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Address   *string            `bson:"address" json:"address" validate:"required,hostname_port"`
+	Kind      *string            `bson:"kind" json:"kind" validate:"required,eq=KITCHEN|eq=RECEIPT"`
+	Station   *string            `bson:"station" json:"station"`
+	Active    *bool              `bson:"active" json:"active"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	PrinterID string             `bson:"printer_id" json:"printer_id"`
+}
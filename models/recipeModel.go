@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Recipe lists the ingredients used to make one portion of a food
+type Recipe struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FoodID    string             `bson:"food_id" json:"food_id"`
+	Lines     []RecipeLine       `bson:"lines" json:"lines" validate:"required,min=1,max=50,dive"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	RecipeID  string             `bson:"recipe_id" json:"recipe_id"`
+}
+
+// RecipeLine is the quantity of one ingredient in a portion, in the
+// ingredient's unit
+type RecipeLine struct {
+	IngredientID *string  `bson:"ingredient_id" json:"ingredient_id" validate:"required"`
+	Quantity     *float64 `bson:"quantity" json:"quantity" validate:"required,gt=0"`
+}
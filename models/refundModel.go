@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Refund returns money from one payment of an invoice. Every refund is
+// documented by a credit note. A refund is recorded as PENDING before any
+// money moves and becomes COMPLETED once it has, or FAILED when it could
+// not be made; a PENDING refund that stays PENDING needs reconciling
+// with the payment provider.
+type Refund struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	InvoiceID       string             `bson:"invoice_id" json:"invoice_id"`
+	PaymentID       *string            `bson:"payment_id" json:"payment_id" validate:"required"`
+	Method          string             `bson:"method" json:"method"`
+	Amount          *float64           `bson:"amount" json:"amount" validate:"omitempty,gt=0"`
+	Reason          *string            `bson:"reason" json:"reason" validate:"required,min=2,max=200"`
+	AuthorizedBy    string             `bson:"authorized_by" json:"authorized_by"`
+	DrawerSessionID *string            `bson:"drawer_session_id" json:"drawer_session_id"`
+	CreditNoteID    string             `bson:"credit_note_id" json:"credit_note_id"`
+	Status          string             `bson:"status" json:"status"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	RefundID        string             `bson:"refund_id" json:"refund_id"`
+}
+
+// CreditNote is the document issued for a refund, referencing the
+// original invoice number. It is PENDING with its refund, then ISSUED, or
+// VOID when the refund failed so its number is still accounted for.
+type CreditNote struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CreditNoteNumber string             `bson:"credit_note_number" json:"credit_note_number"`
+	FiscalYear       int                `bson:"fiscal_year" json:"fiscal_year"`
+	InvoiceID        string             `bson:"invoice_id" json:"invoice_id"`
+	InvoiceNumber    string             `bson:"invoice_number" json:"invoice_number"`
+	RefundID         string             `bson:"refund_id" json:"refund_id"`
+	Amount           float64            `bson:"amount" json:"amount"`
+	Reason           string             `bson:"reason" json:"reason"`
+	Status           string             `bson:"status" json:"status"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	CreditNoteID     string             `bson:"credit_note_id" json:"credit_note_id"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reservation is a booking of a table for a party at a given time. The
+// table is held from ReservedAt until EndsAt.
+type Reservation struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PartySize     *int               `bson:"party_size" json:"party_size" validate:"required,min=1"`
+	ReservedAt    *time.Time         `bson:"reserved_at" json:"reserved_at" validate:"required"`
+	Duration      *int               `bson:"duration" json:"duration" validate:"omitempty,min=15,max=720"`
+	EndsAt        time.Time          `bson:"ends_at" json:"ends_at"`
+	CustomerName  *string            `bson:"customer_name" json:"customer_name" validate:"required,min=2,max=100"`
+	CustomerPhone *string            `bson:"customer_phone" json:"customer_phone" validate:"required,min=5,max=20"`
+	CustomerEmail *string            `bson:"customer_email" json:"customer_email" validate:"omitempty,email"`
+	Notes         *string            `bson:"notes" json:"notes" validate:"omitempty,max=500"`
+	TableID       *string            `bson:"table_id" json:"table_id"`
+	Status        *string            `bson:"status" json:"status" validate:"omitempty,eq=BOOKED|eq=CONFIRMED|eq=SEATED|eq=NO_SHOW|eq=CANCELLED"`
+	OrderID       *string            `bson:"order_id" json:"order_id"`
+	SeatedAt      *time.Time         `bson:"seated_at" json:"seated_at"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+	ReservationID string             `bson:"reservation_id" json:"reservation_id"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Section is a group of tables in an area that one server looks after
+type Section struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      *string            `bson:"name" json:"name" validate:"required,min=1,max=100"`
+	AreaID    *string            `bson:"area_id" json:"area_id" validate:"required"`
+	ServerID  *string            `bson:"server_id" json:"server_id"`
+	Color     *string            `bson:"color" json:"color" validate:"omitempty,hexcolor"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	SectionID string             `bson:"section_id" json:"section_id"`
+}
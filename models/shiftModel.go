@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShiftTemplate is a reusable shift such as "Dinner server" used to fill in
+// schedules. Times are "HH:MM" in the restaurant time zone; a shift that
+// ends before it starts runs past midnight. BreakMinutes is the unpaid
+// break planned in the shift.
+type ShiftTemplate struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name            *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Position        *string            `bson:"position" json:"position" validate:"omitempty,max=50"`
+	StartTime       *string            `bson:"start_time" json:"start_time" validate:"required,datetime=15:04"`
+	EndTime         *string            `bson:"end_time" json:"end_time" validate:"required,datetime=15:04"`
+	BreakMinutes    int                `bson:"break_minutes" json:"break_minutes" validate:"min=0,max=240"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	ShiftTemplateID string             `bson:"shift_template_id" json:"shift_template_id"`
+}
+
+// Shift is one scheduled shift of a staff member. It is placed either from
+// a template and a date or by its start and end times.
+type Shift struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          *string            `bson:"user_id" json:"user_id" validate:"required"`
+	ShiftTemplateID *string            `bson:"shift_template_id" json:"shift_template_id"`
+	Date            *string            `bson:"date" json:"date" validate:"omitempty,datetime=2006-01-02"`
+	Position        *string            `bson:"position" json:"position" validate:"omitempty,max=50"`
+	StartsAt        *time.Time         `bson:"starts_at" json:"starts_at"`
+	EndsAt          *time.Time         `bson:"ends_at" json:"ends_at"`
+	BreakMinutes    int                `bson:"break_minutes" json:"break_minutes" validate:"min=0,max=240"`
+	Notes           *string            `bson:"notes" json:"notes" validate:"omitempty,max=300"`
+	Status          string             `bson:"status" json:"status"`
+	CreatedBy       string             `bson:"created_by" json:"created_by"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	ShiftID         string             `bson:"shift_id" json:"shift_id"`
+}
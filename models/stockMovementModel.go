@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockMovement is one entry in the stock ledger of an ingredient.
+// Quantity is the change in stock, negative when stock goes out, and
+// StockAfter the level it left. SALE and SALE_REVERSAL movements are made
+// by order items; the others are entered by staff.
+type StockMovement struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	IngredientID    string             `bson:"ingredient_id" json:"ingredient_id"`
+	Type            *string            `bson:"type" json:"type" validate:"required,eq=PURCHASE|eq=WASTE|eq=ADJUSTMENT|eq=COUNT|eq=SALE|eq=SALE_REVERSAL"`
+	Quantity        *float64           `bson:"quantity" json:"quantity" validate:"required"`
+	StockAfter      float64            `bson:"stock_after" json:"stock_after"`
+	OrderID         *string            `bson:"order_id" json:"order_id"`
+	OrderItemID     *string            `bson:"order_item_id" json:"order_item_id"`
+	Reason          *string            `bson:"reason" json:"reason" validate:"omitempty,max=200"`
+	By              string             `bson:"by" json:"by"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	StockMovementID string             `bson:"stock_movement_id" json:"stock_movement_id"`
+}
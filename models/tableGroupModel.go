@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TableGroup is a set of tables pushed together for one large party. It
+// lasts until the party's check is closed.
+type TableGroup struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name           *string            `bson:"name" json:"name" validate:"omitempty,max=100"`
+	TableIDs       []string           `bson:"table_ids" json:"table_ids" validate:"required,min=2,unique,dive,required"`
+	PrimaryTableID string             `bson:"primary_table_id" json:"primary_table_id"`
+	Seats          int                `bson:"seats" json:"seats"`
+	Status         string             `bson:"status" json:"status"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	DissolvedAt    *time.Time         `bson:"dissolved_at" json:"dissolved_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	GroupID        string             `bson:"group_id" json:"group_id"`
+}
@@ -8,10 +8,22 @@ import (
 
 // Table represents a restaurant table
 type Table struct {
-	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	NumberOfGuests *int               `json:"number_of_guests" validate:"required"`
-	TableNumber    *int               `json:"table_number" validate:"required"`
-	CreatedAt      time.Time          `json:"created_at"`
-	UpdatedAt      time.Time          `json:"updated_at"`
-	TableID        string             `json:"table_id"`
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	NumberOfGuests  *int               `bson:"number_of_guests" json:"number_of_guests" validate:"required"`
+	TableNumber     *int               `bson:"table_number" json:"table_number" validate:"required"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	TableID         string             `bson:"table_id" json:"table_id"`
+	Status          *string            `bson:"status" json:"status" validate:"omitempty,eq=AVAILABLE|eq=RESERVED|eq=SEATED|eq=ORDERING|eq=DIRTY"`
+	StatusChangedAt *time.Time         `bson:"status_changed_at" json:"status_changed_at"`
+	AreaID          *string            `bson:"area_id" json:"area_id"`
+	SectionID       *string            `bson:"section_id" json:"section_id"`
+	Shape           *string            `bson:"shape" json:"shape" validate:"omitempty,eq=ROUND|eq=SQUARE|eq=RECTANGLE|eq=BOOTH"`
+	X               *float64           `bson:"x" json:"x" validate:"omitempty,gte=0"`
+	Y               *float64           `bson:"y" json:"y" validate:"omitempty,gte=0"`
+	Width           *float64           `bson:"width" json:"width" validate:"omitempty,gt=0"`
+	Height          *float64           `bson:"height" json:"height" validate:"omitempty,gt=0"`
+	Rotation        *float64           `bson:"rotation" json:"rotation" validate:"omitempty,gte=0,lt=360"`
+	TableGroupID    *string            `bson:"table_group_id" json:"table_group_id"`
+	QRVersion       int                `bson:"qr_version" json:"qr_version"`
 }
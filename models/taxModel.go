@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaxCategory is a tax rate assignable to foods (food, alcohol, ...).
+// TakeawayRate, when set, replaces Rate on TAKEAWAY orders.
+type TaxCategory struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name          *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Rate          *float64           `bson:"rate" json:"rate" validate:"required,gte=0,lte=100"`
+	TakeawayRate  *float64           `bson:"takeaway_rate" json:"takeaway_rate" validate:"omitempty,gte=0,lte=100"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+	TaxCategoryID string             `bson:"tax_category_id" json:"tax_category_id"`
+}
+
+// TaxLine is the tax charged for one tax category on an invoice
+type TaxLine struct {
+	TaxCategoryID string  `bson:"tax_category_id" json:"tax_category_id"`
+	Name          string  `bson:"name" json:"name"`
+	Rate          float64 `bson:"rate" json:"rate"`
+	NetAmount     float64 `bson:"net_amount" json:"net_amount"`
+	TaxAmount     float64 `bson:"tax_amount" json:"tax_amount"`
+}
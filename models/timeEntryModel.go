@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TimeEntry is one stretch of work on the time clock, from clock-in to
+// clock-out. HourlyRate is the staff member's rate at clock-in and is only
+// shown on the labor report; WorkedMinutes is the clocked time less unpaid
+// breaks.
+type TimeEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        string             `bson:"user_id" json:"user_id"`
+	ShiftID       *string            `bson:"shift_id" json:"shift_id"`
+	ClockIn       time.Time          `bson:"clock_in" json:"clock_in"`
+	ClockOut      *time.Time         `bson:"clock_out" json:"clock_out"`
+	Breaks        []Break            `bson:"breaks" json:"breaks"`
+	Status        string             `bson:"status" json:"status"`
+	HourlyRate    float64            `bson:"hourly_rate" json:"-"`
+	WorkedMinutes float64            `bson:"worked_minutes" json:"worked_minutes"`
+	BreakMinutes  float64            `bson:"break_minutes" json:"break_minutes"`
+	PinVerified   bool               `bson:"pin_verified" json:"pin_verified"`
+	ClockedInBy   string             `bson:"clocked_in_by" json:"clocked_in_by"`
+	ClockedOutBy  string             `bson:"clocked_out_by" json:"clocked_out_by"`
+	EditedBy      string             `bson:"edited_by" json:"edited_by"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+	TimeEntryID   string             `bson:"time_entry_id" json:"time_entry_id"`
+}
+
+// Break is a break taken during a time entry. Paid breaks count as worked
+// time.
+type Break struct {
+	Start time.Time  `bson:"start" json:"start"`
+	End   *time.Time `bson:"end" json:"end"`
+	Paid  bool       `bson:"paid" json:"paid"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tip is a gratuity entered on a card payment. Tips are kept apart from
+// invoice totals so they never count as revenue.
+type Tip struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	InvoiceID string             `bson:"invoice_id" json:"invoice_id"`
+	OrderID   string             `bson:"order_id" json:"order_id"`
+	ServerID  *string            `bson:"server_id" json:"server_id"`
+	Amount    *float64           `bson:"amount" json:"amount" validate:"required,gt=0"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	TipID     string             `bson:"tip_id" json:"tip_id"`
+}
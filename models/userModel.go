@@ -8,16 +8,21 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	FirstName    *string            `json:"first_name" validate:"required,min=2,max=100"`
-	LastName     *string            `json:"last_name" validate:"required,min=2,max=100"`
-	Password     *string            `json:"password" validate:"required,min=6"`
-	Email        *string            `json:"email" validate:"email,required"`
-	Avatar       *string            `json:"avatar"`
-	Phone        *string            `json:"phone" validate:"required"`
-	Token        *string            `json:"token"`
-	RefreshToken *string            `json:"refresh_token"`
-	CreatedAt    time.Time          `json:"created_at"`
-	UpdatedAt    time.Time          `json:"updated_at"`
-	UserID       string             `json:"user_id"`
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FirstName      *string            `bson:"first_name" json:"first_name" validate:"required,min=2,max=100"`
+	LastName       *string            `bson:"last_name" json:"last_name" validate:"required,min=2,max=100"`
+	Password       *string            `bson:"password" json:"password" validate:"required,min=6"`
+	Email          *string            `bson:"email" json:"email" validate:"email,required"`
+	Avatar         *string            `bson:"avatar" json:"avatar"`
+	Phone          *string            `bson:"phone" json:"phone" validate:"required"`
+	Role           *string            `bson:"role" json:"role" validate:"omitempty,eq=ADMIN|eq=MANAGER|eq=STAFF"`
+	HourlyRate     *float64           `bson:"hourly_rate" json:"-" validate:"omitempty,gte=0"`
+	Pin            *string            `bson:"pin" json:"-"`
+	PinFailures    int                `bson:"pin_failures" json:"-"`
+	PinLockedUntil *time.Time         `bson:"pin_locked_until" json:"-"`
+	Token          *string            `bson:"token" json:"token"`
+	RefreshToken   *string            `bson:"refresh_token" json:"refresh_token"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	UserID         string             `bson:"user_id" json:"user_id"`
 }
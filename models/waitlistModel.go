@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WaitlistEntry is a walk-in party waiting for a table
+type WaitlistEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PartyName     *string            `bson:"party_name" json:"party_name" validate:"required,min=2,max=100"`
+	PartySize     *int               `bson:"party_size" json:"party_size" validate:"required,min=1"`
+	Phone         *string            `bson:"phone" json:"phone" validate:"required,min=5,max=20"`
+	Notes         *string            `bson:"notes" json:"notes" validate:"omitempty,max=500"`
+	Status        string             `bson:"status" json:"status"`
+	JoinedAt      time.Time          `bson:"joined_at" json:"joined_at"`
+	QuotedMinutes *int               `bson:"quoted_minutes" json:"quoted_minutes"`
+	TableID       *string            `bson:"table_id" json:"table_id"`
+	NotifiedAt    *time.Time         `bson:"notified_at" json:"notified_at"`
+	OrderID       *string            `bson:"order_id" json:"order_id"`
+	SeatedAt      *time.Time         `bson:"seated_at" json:"seated_at"`
+	LeftAt        *time.Time         `bson:"left_at" json:"left_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+	WaitlistID    string             `bson:"waitlist_id" json:"waitlist_id"`
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Message is a notification for one recipient. To is an email address or
+// phone number, depending on the notifier.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to customers or staff
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+// LogNotifier writes messages to the application log. It is the default
+// until a real channel such as email or SMS is configured.
+type LogNotifier struct{}
+
+// Notify logs the message
+func (LogNotifier) Notify(ctx context.Context, message Message) error {
+	log.Printf("notification to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// Fake records messages in memory instead of sending them. It is meant
+// for tests.
+type Fake struct {
+	mu   sync.Mutex
+	sent []Message
+	Err  error
+}
+
+// Notify records the message, or returns Err when it is set
+func (f *Fake) Notify(ctx context.Context, message Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return f.Err
+	}
+	f.sent = append(f.sent, message)
+	return nil
+}
+
+// Sent returns the messages recorded so far
+func (f *Fake) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sent := make([]Message, len(f.sent))
+	copy(sent, f.sent)
+	return sent
+}
+
+var (
+	mu              sync.RWMutex
+	defaultNotifier Notifier = LogNotifier{}
+	smsNotifier     Notifier = LogNotifier{}
+)
+
+// SetDefault replaces the notifier used by Send
+func SetDefault(notifier Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultNotifier = notifier
+}
+
+// Send delivers a message with the default notifier
+func Send(ctx context.Context, message Message) error {
+	mu.RLock()
+	notifier := defaultNotifier
+	mu.RUnlock()
+	return notifier.Notify(ctx, message)
+}
+
+// SetSMS replaces the notifier used by SendSMS
+func SetSMS(notifier Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	smsNotifier = notifier
+}
+
+// SendSMS delivers a text message to a phone number with the SMS
+// notifier. Until one is configured messages are logged.
+func SendSMS(ctx context.Context, message Message) error {
+	mu.RLock()
+	notifier := smsNotifier
+	mu.RUnlock()
+	return notifier.Notify(ctx, message)
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFake(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantSent int
+	}{
+		{"records messages", nil, 2},
+		{"returns its error", errors.New("mailbox full"), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &Fake{Err: tt.err}
+			for _, to := range []string{"a@example.com", "b@example.com"} {
+				if err := fake.Notify(context.Background(), Message{To: to}); !errors.Is(err, tt.err) {
+					t.Errorf("Notify() err = %v, want %v", err, tt.err)
+				}
+			}
+			if sent := fake.Sent(); len(sent) != tt.wantSent {
+				t.Errorf("sent %d messages, want %d", len(sent), tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestSendUsesConfiguredNotifiers(t *testing.T) {
+	email, sms := &Fake{}, &Fake{}
+	SetDefault(email)
+	SetSMS(sms)
+	defer SetDefault(LogNotifier{})
+	defer SetSMS(LogNotifier{})
+
+	Send(context.Background(), Message{To: "guest@example.com", Subject: "Receipt"})
+	SendSMS(context.Background(), Message{To: "+201000000000", Body: "Your table is ready"})
+
+	if sent := email.Sent(); len(sent) != 1 || sent[0].Subject != "Receipt" {
+		t.Errorf("email notifier got %+v", sent)
+	}
+	if sent := sms.Sent(); len(sent) != 1 || sent[0].To != "+201000000000" {
+		t.Errorf("sms notifier got %+v", sent)
+	}
+}
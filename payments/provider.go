@@ -0,0 +1,88 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Intent statuses reported by providers
+const (
+	StatusAuthorized = "AUTHORIZED"
+	StatusCaptured   = "CAPTURED"
+	StatusVoided     = "VOIDED"
+	StatusRefunded   = "REFUNDED"
+	StatusDeclined   = "DECLINED"
+)
+
+// Webhook event types
+const (
+	EventCaptured = "payment.captured"
+	EventVoided   = "payment.voided"
+	EventRefunded = "payment.refunded"
+	EventFailed   = "payment.failed"
+)
+
+var (
+	// ErrUnknownProvider is returned when no provider is registered under a name
+	ErrUnknownProvider = errors.New("unknown payment provider")
+	// ErrInvalidSignature is returned when a webhook signature does not verify
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// AuthorizeRequest holds a card payment to authorize
+type AuthorizeRequest struct {
+	Amount    float64
+	Currency  string
+	Reference string
+	CardToken string
+}
+
+// Result is a provider's answer to an operation
+type Result struct {
+	ProviderRef string
+	Status      string
+	Message     string
+}
+
+// Event is a verified webhook notification. Amount is the amount captured
+// for capture events and the total refunded on the charge for refund events.
+type Event struct {
+	Type        string  `json:"type"`
+	ProviderRef string  `json:"provider_ref"`
+	Amount      float64 `json:"amount"`
+}
+
+// PaymentProvider is a card payment gateway
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, request AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, providerRef string, amount float64) (Result, error)
+	Void(ctx context.Context, providerRef string) (Result, error)
+	Refund(ctx context.Context, providerRef string, amount float64) (Result, error)
+	VerifyWebhook(payload []byte, signature string) (Event, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]PaymentProvider{}
+)
+
+// Register makes a provider available by its name
+func Register(provider PaymentProvider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[provider.Name()] = provider
+}
+
+// Get returns the provider registered under a name
+func Get(name string) (PaymentProvider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	provider, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
@@ -0,0 +1,150 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DeclineToken is a card token the simulator always declines
+const DeclineToken = "tok_decline"
+
+// Simulator is an in-memory provider for offline use and tests. It keeps
+// authorizations in memory and signs webhooks with HMAC-SHA256.
+type Simulator struct {
+	secret  []byte
+	counter int64
+
+	mu      sync.Mutex
+	charges map[string]*simulatedCharge
+}
+
+type simulatedCharge struct {
+	authorized float64
+	captured   float64
+	refunded   float64
+	status     string
+}
+
+// NewSimulator creates a simulator that signs webhooks with the given secret
+func NewSimulator(secret string) *Simulator {
+	return &Simulator{
+		secret:  []byte(secret),
+		charges: map[string]*simulatedCharge{},
+	}
+}
+
+// Name returns "simulator"
+func (s *Simulator) Name() string {
+	return "simulator"
+}
+
+// Authorize holds the amount unless the card token is DeclineToken
+func (s *Simulator) Authorize(ctx context.Context, request AuthorizeRequest) (Result, error) {
+	if request.Amount <= 0 {
+		return Result{}, errors.New("amount must be positive")
+	}
+
+	ref := fmt.Sprintf("sim_%d", atomic.AddInt64(&s.counter, 1))
+	if request.CardToken == DeclineToken {
+		return Result{ProviderRef: ref, Status: StatusDeclined, Message: "card declined"}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.charges[ref] = &simulatedCharge{authorized: request.Amount, status: StatusAuthorized}
+
+	return Result{ProviderRef: ref, Status: StatusAuthorized}, nil
+}
+
+// Capture takes up to the authorized amount
+func (s *Simulator) Capture(ctx context.Context, providerRef string, amount float64) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	charge, ok := s.charges[providerRef]
+	if !ok {
+		return Result{}, errors.New("unknown charge")
+	}
+	if charge.status != StatusAuthorized {
+		return Result{}, fmt.Errorf("charge is %s", charge.status)
+	}
+	if amount <= 0 || amount > charge.authorized {
+		return Result{}, errors.New("capture exceeds the authorized amount")
+	}
+
+	charge.captured = amount
+	charge.status = StatusCaptured
+	return Result{ProviderRef: providerRef, Status: StatusCaptured}, nil
+}
+
+// Void releases an authorization that was not captured
+func (s *Simulator) Void(ctx context.Context, providerRef string) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	charge, ok := s.charges[providerRef]
+	if !ok {
+		return Result{}, errors.New("unknown charge")
+	}
+	if charge.status != StatusAuthorized {
+		return Result{}, fmt.Errorf("charge is %s", charge.status)
+	}
+
+	charge.status = StatusVoided
+	return Result{ProviderRef: providerRef, Status: StatusVoided}, nil
+}
+
+// Refund returns up to the captured amount
+func (s *Simulator) Refund(ctx context.Context, providerRef string, amount float64) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	charge, ok := s.charges[providerRef]
+	if !ok {
+		return Result{}, errors.New("unknown charge")
+	}
+	if charge.status != StatusCaptured && charge.status != StatusRefunded {
+		return Result{}, fmt.Errorf("charge is %s", charge.status)
+	}
+	if amount <= 0 || charge.refunded+amount > charge.captured {
+		return Result{}, errors.New("refund exceeds the captured amount")
+	}
+
+	charge.refunded += amount
+	if charge.refunded >= charge.captured {
+		charge.status = StatusRefunded
+	}
+	return Result{ProviderRef: providerRef, Status: charge.status}, nil
+}
+
+// Sign returns the hex HMAC-SHA256 signature of a payload
+func (s *Simulator) Sign(payload []byte) string {
+	return hex.EncodeToString(s.signBytes(payload))
+}
+
+// VerifyWebhook checks the signature and decodes the event
+func (s *Simulator) VerifyWebhook(payload []byte, signature string) (Event, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, s.signBytes(payload)) {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
+
+func (s *Simulator) signBytes(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,167 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSimulatorAuthorize(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    AuthorizeRequest
+		wantStatus string
+		wantErr    bool
+	}{
+		{"approved", AuthorizeRequest{Amount: 25, CardToken: "tok_visa"}, StatusAuthorized, false},
+		{"declined", AuthorizeRequest{Amount: 25, CardToken: DeclineToken}, StatusDeclined, false},
+		{"no amount", AuthorizeRequest{Amount: 0, CardToken: "tok_visa"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewSimulator("secret").Authorize(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", result.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSimulatorLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func(s *Simulator, ref string) (Result, error)
+		want string
+		fail bool
+	}{
+		{
+			name: "capture part of the authorization",
+			run:  func(s *Simulator, ref string) (Result, error) { return s.Capture(ctx, ref, 60) },
+			want: StatusCaptured,
+		},
+		{
+			name: "capture more than authorized",
+			run:  func(s *Simulator, ref string) (Result, error) { return s.Capture(ctx, ref, 120) },
+			fail: true,
+		},
+		{
+			name: "void before capture",
+			run:  func(s *Simulator, ref string) (Result, error) { return s.Void(ctx, ref) },
+			want: StatusVoided,
+		},
+		{
+			name: "refund before capture",
+			run:  func(s *Simulator, ref string) (Result, error) { return s.Refund(ctx, ref, 10) },
+			fail: true,
+		},
+		{
+			name: "partial refund keeps the charge captured",
+			run: func(s *Simulator, ref string) (Result, error) {
+				s.Capture(ctx, ref, 100)
+				return s.Refund(ctx, ref, 40)
+			},
+			want: StatusCaptured,
+		},
+		{
+			name: "full refund",
+			run: func(s *Simulator, ref string) (Result, error) {
+				s.Capture(ctx, ref, 100)
+				s.Refund(ctx, ref, 40)
+				return s.Refund(ctx, ref, 60)
+			},
+			want: StatusRefunded,
+		},
+		{
+			name: "refund more than captured",
+			run: func(s *Simulator, ref string) (Result, error) {
+				s.Capture(ctx, ref, 50)
+				return s.Refund(ctx, ref, 51)
+			},
+			fail: true,
+		},
+		{
+			name: "void after capture",
+			run: func(s *Simulator, ref string) (Result, error) {
+				s.Capture(ctx, ref, 100)
+				return s.Void(ctx, ref)
+			},
+			fail: true,
+		},
+		{
+			name: "unknown charge",
+			run:  func(s *Simulator, ref string) (Result, error) { return s.Capture(ctx, "sim_missing", 1) },
+			fail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSimulator("secret")
+			authorized, err := s.Authorize(ctx, AuthorizeRequest{Amount: 100, CardToken: "tok_visa"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := tt.run(s, authorized.ProviderRef)
+			if tt.fail {
+				if err == nil {
+					t.Errorf("got %+v, want an error", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Status != tt.want {
+				t.Errorf("status = %s, want %s", result.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulatorVerifyWebhook(t *testing.T) {
+	s := NewSimulator("secret")
+	payload := []byte(`{"type":"payment.captured","provider_ref":"sim_1","amount":12.5}`)
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+		wantErr   error
+	}{
+		{"valid", payload, s.Sign(payload), nil},
+		{"tampered payload", []byte(`{"type":"payment.captured","provider_ref":"sim_1","amount":99}`), s.Sign(payload), ErrInvalidSignature},
+		{"other secret", payload, NewSimulator("other").Sign(payload), ErrInvalidSignature},
+		{"not hex", payload, "zz", ErrInvalidSignature},
+		{"missing", payload, "", ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := s.VerifyWebhook(tt.payload, tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (event.Type != EventCaptured || event.ProviderRef != "sim_1" || event.Amount != 12.5) {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	Register(NewSimulator("secret"))
+
+	if provider, err := Get("simulator"); err != nil || provider.Name() != "simulator" {
+		t.Errorf("Get(simulator) = %v, %v", provider, err)
+	}
+	if _, err := Get("nowhere"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Get(nowhere) err = %v, want ErrUnknownProvider", err)
+	}
+}
@@ -0,0 +1,109 @@
+package printing
+
+import (
+	"bytes"
+	"strings"
+)
+
+// ESC/POS control sequences understood by most thermal printers
+var (
+	cmdInit        = []byte{0x1b, 0x40}
+	cmdBoldOn      = []byte{0x1b, 0x45, 0x01}
+	cmdBoldOff     = []byte{0x1b, 0x45, 0x00}
+	cmdDoubleOn    = []byte{0x1d, 0x21, 0x11}
+	cmdDoubleOff   = []byte{0x1d, 0x21, 0x00}
+	cmdAlignLeft   = []byte{0x1b, 0x61, 0x00}
+	cmdAlignCenter = []byte{0x1b, 0x61, 0x01}
+	cmdCut         = []byte{0x1d, 0x56, 0x42, 0x00}
+)
+
+// Encoder builds an ESC/POS byte stream
+type Encoder struct {
+	buf bytes.Buffer
+}
+
+// NewEncoder starts a document, resetting the printer to its defaults
+func NewEncoder() *Encoder {
+	e := &Encoder{}
+	e.buf.Write(cmdInit)
+	return e
+}
+
+// Bold turns emphasized printing on or off
+func (e *Encoder) Bold(on bool) *Encoder {
+	if on {
+		e.buf.Write(cmdBoldOn)
+	} else {
+		e.buf.Write(cmdBoldOff)
+	}
+	return e
+}
+
+// Large turns double width and height printing on or off
+func (e *Encoder) Large(on bool) *Encoder {
+	if on {
+		e.buf.Write(cmdDoubleOn)
+	} else {
+		e.buf.Write(cmdDoubleOff)
+	}
+	return e
+}
+
+// Center centers the following lines, or aligns them left again
+func (e *Encoder) Center(on bool) *Encoder {
+	if on {
+		e.buf.Write(cmdAlignCenter)
+	} else {
+		e.buf.Write(cmdAlignLeft)
+	}
+	return e
+}
+
+// Line prints a line of text. Characters outside ASCII are replaced with
+// '?' since the printer code page is not known.
+func (e *Encoder) Line(text string) *Encoder {
+	for _, r := range text {
+		if r == '\t' {
+			r = ' '
+		}
+		if r < 0x20 || r > 0x7e {
+			r = '?'
+		}
+		e.buf.WriteByte(byte(r))
+	}
+	e.buf.WriteByte('\n')
+	return e
+}
+
+// Text prints several lines of text
+func (e *Encoder) Text(text string) *Encoder {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		e.Line(line)
+	}
+	return e
+}
+
+// Feed advances the paper by n lines
+func (e *Encoder) Feed(n int) *Encoder {
+	if n > 255 {
+		n = 255
+	}
+	e.buf.Write([]byte{0x1b, 0x64, byte(n)})
+	return e
+}
+
+// Cut feeds the paper past the cutter and cuts it
+func (e *Encoder) Cut() *Encoder {
+	e.buf.Write(cmdCut)
+	return e
+}
+
+// Bytes returns the encoded document
+func (e *Encoder) Bytes() []byte {
+	return e.buf.Bytes()
+}
+
+// EncodeText prints plain text, such as a rendered receipt, and cuts the paper
+func EncodeText(text string) []byte {
+	return NewEncoder().Text(text).Feed(3).Cut().Bytes()
+}
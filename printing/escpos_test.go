@@ -0,0 +1,68 @@
+package printing
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(e *Encoder)
+		want  []byte
+	}{
+		{
+			name:  "starts with init",
+			build: func(e *Encoder) {},
+			want:  []byte{0x1b, 0x40},
+		},
+		{
+			name:  "bold on and off",
+			build: func(e *Encoder) { e.Bold(true).Bold(false) },
+			want:  []byte{0x1b, 0x40, 0x1b, 0x45, 0x01, 0x1b, 0x45, 0x00},
+		},
+		{
+			name:  "large and centered",
+			build: func(e *Encoder) { e.Large(true).Center(true).Center(false).Large(false) },
+			want:  []byte{0x1b, 0x40, 0x1d, 0x21, 0x11, 0x1b, 0x61, 0x01, 0x1b, 0x61, 0x00, 0x1d, 0x21, 0x00},
+		},
+		{
+			name:  "line replaces tabs and non-ASCII",
+			build: func(e *Encoder) { e.Line("a\tb é") },
+			want:  append([]byte{0x1b, 0x40}, []byte("a b ?\n")...),
+		},
+		{
+			name:  "text splits lines and drops the trailing newline",
+			build: func(e *Encoder) { e.Text("one\ntwo\n") },
+			want:  append([]byte{0x1b, 0x40}, []byte("one\ntwo\n")...),
+		},
+		{
+			name:  "feed is capped at 255 lines",
+			build: func(e *Encoder) { e.Feed(3).Feed(1000) },
+			want:  []byte{0x1b, 0x40, 0x1b, 0x64, 3, 0x1b, 0x64, 255},
+		},
+		{
+			name:  "cut",
+			build: func(e *Encoder) { e.Cut() },
+			want:  []byte{0x1b, 0x40, 0x1d, 0x56, 0x42, 0x00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEncoder()
+			tt.build(e)
+			if got := e.Bytes(); !bytes.Equal(got, tt.want) {
+				t.Errorf("got % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	got := EncodeText("Total 10.00\n")
+	want := append(append([]byte{0x1b, 0x40}, []byte("Total 10.00\n")...), 0x1b, 0x64, 3, 0x1d, 0x56, 0x42, 0x00)
+	if !bytes.Equal(got, want) {
+		t.Errorf("got % x, want % x", got, want)
+	}
+}
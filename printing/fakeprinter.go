@@ -0,0 +1,103 @@
+package printing
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+// FakePrinter is a TCP server that accepts documents like a raw port 9100
+// printer and keeps them in memory. It is meant for tests and local use.
+type FakePrinter struct {
+	address string
+
+	mu       sync.Mutex
+	listener net.Listener
+	done     chan struct{}
+	received [][]byte
+}
+
+// NewFakePrinter starts a fake printer on address; use "127.0.0.1:0" to
+// pick a free port
+func NewFakePrinter(address string) (*FakePrinter, error) {
+	p := &FakePrinter{address: address}
+	if err := p.Online(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Addr returns the address the printer listens on
+func (p *FakePrinter) Addr() string {
+	return p.address
+}
+
+// Online starts accepting connections again after Offline
+func (p *FakePrinter) Online() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.listener != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", p.address)
+	if err != nil {
+		return err
+	}
+
+	p.address = listener.Addr().String()
+	p.listener = listener
+	p.done = make(chan struct{})
+	go p.serve(listener, p.done)
+	return nil
+}
+
+// Offline refuses connections until Online is called, so callers can
+// exercise retries
+func (p *FakePrinter) Offline() error {
+	p.mu.Lock()
+	listener, done := p.listener, p.done
+	p.listener = nil
+	p.mu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+
+	err := listener.Close()
+	<-done
+	return err
+}
+
+// Received returns the documents printed so far
+func (p *FakePrinter) Received() [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	received := make([][]byte, len(p.received))
+	copy(received, p.received)
+	return received
+}
+
+// Close stops the printer
+func (p *FakePrinter) Close() error {
+	return p.Offline()
+}
+
+func (p *FakePrinter) serve(listener net.Listener, done chan struct{}) {
+	defer close(done)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		data, _ := io.ReadAll(conn)
+		conn.Close()
+
+		p.mu.Lock()
+		p.received = append(p.received, data)
+		p.mu.Unlock()
+	}
+}
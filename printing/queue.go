@@ -0,0 +1,213 @@
+package printing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Job statuses
+const (
+	JobQueued  = "QUEUED"
+	JobPrinted = "PRINTED"
+	JobFailed  = "FAILED"
+)
+
+// Job is a document waiting for, or sent to, a printer
+type Job struct {
+	ID          string    `json:"job_id"`
+	PrinterID   string    `json:"printer_id"`
+	PrinterName string    `json:"printer_name"`
+	Address     string    `json:"address"`
+	Kind        string    `json:"kind"`
+	Reference   string    `json:"reference"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	data []byte
+}
+
+// SendFunc delivers a document to a printer address
+type SendFunc func(ctx context.Context, address string, data []byte) error
+
+// Send writes a document to a raw TCP printer, usually on port 9100
+func Send(ctx context.Context, address string, data []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+
+	_, err = conn.Write(data)
+	return err
+}
+
+// historySize is how many finished jobs a queue remembers
+const historySize = 200
+
+// Queue sends print jobs in the background and retries failed ones with
+// a growing delay
+type Queue struct {
+	send        SendFunc
+	maxAttempts int
+	backoff     time.Duration
+	timeout     time.Duration
+
+	jobs    chan *Job
+	counter int64
+
+	mu      sync.Mutex
+	history []*Job
+}
+
+// NewQueue creates a queue that makes up to maxAttempts attempts per job,
+// waiting backoff, 2*backoff, ... between them
+func NewQueue(send SendFunc, maxAttempts int, backoff time.Duration) *Queue {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Queue{
+		send:        send,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		timeout:     5 * time.Second,
+		jobs:        make(chan *Job, 256),
+	}
+}
+
+// Start runs the given number of workers until ctx is cancelled
+func (q *Queue) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go q.work(ctx)
+	}
+}
+
+// Enqueue adds a document to the queue and returns the queued job. It
+// never blocks: when the queue is full the job is recorded as FAILED.
+func (q *Queue) Enqueue(job Job, data []byte) Job {
+	now := time.Now()
+	job.ID = fmt.Sprintf("job-%d", atomic.AddInt64(&q.counter, 1))
+	job.Status = JobQueued
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	job.data = data
+
+	queued := &job
+	q.mu.Lock()
+	q.history = append(q.history, queued)
+	if len(q.history) > historySize {
+		q.history = q.history[len(q.history)-historySize:]
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.jobs <- queued:
+	default:
+		q.mu.Lock()
+		queued.Status = JobFailed
+		queued.LastError = "print queue is full"
+		queued.data = nil
+		job = *queued
+		q.mu.Unlock()
+		log.Printf("print job %s to %s dropped: print queue is full", job.ID, job.PrinterName)
+	}
+	return job
+}
+
+// Jobs returns the most recent jobs, oldest first
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]Job, len(q.history))
+	for i, job := range q.history {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.jobs:
+			q.run(ctx, job)
+		}
+	}
+}
+
+// run makes every attempt for one job, recording the outcome on it
+func (q *Queue) run(ctx context.Context, job *Job) {
+	delay := q.backoff
+	for attempt := 1; attempt <= q.maxAttempts; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, q.timeout)
+		err := q.send(sendCtx, job.Address, job.data)
+		cancel()
+
+		q.mu.Lock()
+		job.Attempts = attempt
+		job.UpdatedAt = time.Now()
+		if err == nil {
+			job.Status = JobPrinted
+			job.LastError = ""
+			job.data = nil
+			q.mu.Unlock()
+			return
+		}
+		job.LastError = err.Error()
+		if attempt == q.maxAttempts {
+			job.Status = JobFailed
+			job.data = nil
+		}
+		q.mu.Unlock()
+
+		if attempt == q.maxAttempts {
+			log.Printf("print job %s to %s failed after %d attempts: %v", job.ID, job.PrinterName, attempt, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+var defaultQueue *Queue
+
+// StartDefault creates the shared queue used by Enqueue and starts its workers
+func StartDefault(ctx context.Context, workers, maxAttempts int, backoff time.Duration) {
+	defaultQueue = NewQueue(Send, maxAttempts, backoff)
+	defaultQueue.Start(ctx, workers)
+}
+
+// Enqueue adds a document to the shared queue
+func Enqueue(job Job, data []byte) Job {
+	if defaultQueue == nil {
+		StartDefault(context.Background(), 1, 3, time.Second)
+	}
+	return defaultQueue.Enqueue(job, data)
+}
+
+// Jobs returns the most recent jobs of the shared queue
+func Jobs() []Job {
+	if defaultQueue == nil {
+		return []Job{}
+	}
+	return defaultQueue.Jobs()
+}
@@ -0,0 +1,132 @@
+package printing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor polls until the job reaches a final status or the test times out
+func waitFor(t *testing.T, q *Queue, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, job := range q.Jobs() {
+			if job.ID == id && job.Status != JobQueued {
+				return job
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestQueuePrintsToFakePrinter(t *testing.T) {
+	printer, err := NewFakePrinter("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer printer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := NewQueue(Send, 3, 10*time.Millisecond)
+	q.Start(ctx, 1)
+
+	data := EncodeText("Table 4\n")
+	job := waitFor(t, q, q.Enqueue(Job{Address: printer.Addr(), Kind: "TICKET"}, data).ID)
+
+	if job.Status != JobPrinted || job.Attempts != 1 {
+		t.Errorf("job = %+v, want PRINTED on the first attempt", job)
+	}
+
+	// The printer records the document after the connection closes
+	deadline := time.Now().Add(time.Second)
+	for len(printer.Received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if received := printer.Received(); len(received) != 1 || !bytes.Equal(received[0], data) {
+		t.Errorf("printer received %q, want %q", received, data)
+	}
+}
+
+func TestQueueRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantStatus   string
+		wantAttempts int
+	}{
+		{"succeeds after a failure", 1, JobPrinted, 2},
+		{"gives up after the last attempt", 5, JobFailed, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			send := func(ctx context.Context, address string, data []byte) error {
+				calls++
+				if calls <= tt.failures {
+					return errors.New("printer offline")
+				}
+				return nil
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			q := NewQueue(send, 3, time.Millisecond)
+			q.Start(ctx, 1)
+
+			job := waitFor(t, q, q.Enqueue(Job{Address: "printer:9100"}, []byte("x")).ID)
+			if job.Status != tt.wantStatus || job.Attempts != tt.wantAttempts {
+				t.Errorf("job = %+v, want %s after %d attempts", job, tt.wantStatus, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestQueueFullFailsInsteadOfBlocking(t *testing.T) {
+	// Without workers nothing leaves the channel
+	q := NewQueue(Send, 1, time.Millisecond)
+	q.jobs = make(chan *Job, 1)
+
+	first := q.Enqueue(Job{Address: "printer:9100"}, []byte("x"))
+	second := q.Enqueue(Job{Address: "printer:9100"}, []byte("y"))
+
+	if first.Status != JobQueued {
+		t.Errorf("first job = %s, want QUEUED", first.Status)
+	}
+	if second.Status != JobFailed || second.LastError == "" {
+		t.Errorf("second job = %+v, want FAILED with an error", second)
+	}
+}
+
+func TestFakePrinterOffline(t *testing.T) {
+	printer, err := NewFakePrinter("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer printer.Close()
+
+	if err := printer.Offline(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Send(ctx, printer.Addr(), []byte("x")); err == nil {
+		t.Error("send to an offline printer succeeded")
+	}
+
+	if err := printer.Online(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Send(ctx, printer.Addr(), []byte("x")); err != nil {
+		t.Errorf("send after coming back online: %v", err)
+	}
+}
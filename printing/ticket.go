@@ -0,0 +1,53 @@
+package printing
+
+import (
+	"fmt"
+	"time"
+)
+
+// TicketItem is one line on a kitchen ticket
+type TicketItem struct {
+	Name     string
+	Quantity int
+}
+
+// KitchenTicket lists the items one station has to prepare for an order
+type KitchenTicket struct {
+	Station   string
+	OrderID   string
+	Table     string
+	OrderType string
+	Items     []TicketItem
+	Reprint   bool
+	CreatedAt time.Time
+}
+
+// Encode lays the ticket out for an ESC/POS printer, with large quantities
+// so they can be read from across the pass
+func (t KitchenTicket) Encode() []byte {
+	e := NewEncoder()
+
+	e.Center(true).Large(true).Bold(true).Line(t.Station).Large(false).Bold(false)
+	if t.Reprint {
+		e.Line("*** REPRINT ***")
+	}
+	e.Center(false)
+
+	if t.Table != "" {
+		e.Large(true).Line("Table " + t.Table).Large(false)
+	}
+	if t.OrderType != "" {
+		e.Line(t.OrderType)
+	}
+	e.Line("Order " + t.OrderID)
+	e.Line(t.CreatedAt.Format("2006-01-02 15:04"))
+	e.Line("--------------------------------")
+
+	e.Large(true)
+	for _, item := range t.Items {
+		e.Line(fmt.Sprintf("%dx %s", item.Quantity, item.Name))
+	}
+	e.Large(false)
+
+	return e.Feed(3).Cut().Bytes()
+}
@@ -0,0 +1,101 @@
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Page layout for PDF output, in points. A4 with Courier keeps the
+// fixed-width columns of the text receipt intact.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	pageMargin   = 40
+	fontSize     = 10
+	lineHeight   = 12
+	linesPerPage = (pageHeight - 2*pageMargin) / lineHeight
+)
+
+// PDF lays out plain text, one line per row, in a PDF document using the
+// built-in Courier font. It needs no external fonts or services.
+func PDF(text string) []byte {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+
+	var pages [][]string
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// Objects 1-3 are the catalog, page tree and font; each page then
+	// takes two objects, the page and its content stream
+	var buf bytes.Buffer
+	offsets := []int{}
+	startObject := func() {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", len(offsets))
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	startObject()
+	buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	startObject()
+	fmt.Fprintf(&buf, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(pages))
+
+	startObject()
+	buf.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, lineHeight, pageMargin, pageHeight-pageMargin-fontSize)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", escapePDFString(line))
+		}
+		content.WriteString("ET\n")
+
+		startObject()
+		fmt.Fprintf(&buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			pageWidth, pageHeight, 5+2*i)
+
+		startObject()
+		fmt.Fprintf(&buf, "<< /Length %d >>\nstream\n", content.Len())
+		buf.Write(content.Bytes())
+		buf.WriteString("endstream\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// escapePDFString encodes a line for a PDF string literal. Characters the
+// standard fonts cannot show are replaced with '?'.
+func escapePDFString(s string) []byte {
+	var out []byte
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			out = append(out, '\\', byte(r))
+		case r == '\t':
+			out = append(out, ' ')
+		case r < 0x20 || r > 0xff || (r >= 0x7f && r < 0xa0):
+			out = append(out, '?')
+		default:
+			out = append(out, byte(r))
+		}
+	}
+	return out
+}
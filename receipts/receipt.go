@@ -0,0 +1,116 @@
+package receipts
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+// DefaultTemplate is the receipt layout used when no RECEIPT_TEMPLATE is set
+//
+//go:embed templates/receipt.tmpl
+var DefaultTemplate string
+
+// Restaurant is the header printed at the top of a receipt
+type Restaurant struct {
+	Name    string
+	Address string
+	Phone   string
+}
+
+// Receipt holds everything a receipt template can print
+type Receipt struct {
+	Restaurant Restaurant
+	Invoice    models.Invoice
+	Lines      []models.InvoiceLine
+	Payments   []models.Payment
+	Location   *time.Location
+	PrintedAt  time.Time
+}
+
+// Render fills a receipt template. Besides the text/template built-ins,
+// templates can use money, left, right, center, date and deref to lay out
+// fixed-width columns.
+func Render(receipt Receipt, templateText string) (string, error) {
+	location := receipt.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	funcs := template.FuncMap{
+		"money": func(amount float64) string {
+			return fmt.Sprintf("%.2f", amount)
+		},
+		"left": func(width int, s string) string {
+			return pad(s, width, false)
+		},
+		"right": func(width int, s string) string {
+			return pad(s, width, true)
+		},
+		"center": func(width int, s string) string {
+			s = truncate(s, width)
+			return strings.Repeat(" ", (width-utf8.RuneCountInString(s))/2) + s
+		},
+		"date": func(t time.Time) string {
+			return t.In(location).Format("2006-01-02 15:04")
+		},
+		"deref": deref,
+	}
+
+	tmpl, err := template.New("receipt").Funcs(funcs).Parse(templateText)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, receipt); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// deref returns the value behind an optional model field, or its zero
+// value when the field is not set
+func deref(v interface{}) interface{} {
+	switch p := v.(type) {
+	case *string:
+		if p == nil {
+			return ""
+		}
+		return *p
+	case *float64:
+		if p == nil {
+			return 0.0
+		}
+		return *p
+	case *int:
+		if p == nil {
+			return 0
+		}
+		return *p
+	}
+	return v
+}
+
+// truncate shortens s to at most width characters
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
+
+// pad fits s into a column of the given width, aligned left or right
+func pad(s string, width int, alignRight bool) string {
+	s = truncate(s, width)
+	padding := strings.Repeat(" ", width-utf8.RuneCountInString(s))
+	if alignRight {
+		return padding + s
+	}
+	return s + padding
+}
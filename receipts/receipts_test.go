@@ -0,0 +1,171 @@
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ali-adel-nour/restaurant-management/models"
+)
+
+// row lays out a label and amount as the default template does
+func row(label string, amount string) string {
+	return fmt.Sprintf("%-28s%12s", label, amount)
+}
+
+func sampleReceipt() Receipt {
+	cash, card := "CASH", "CARD"
+	cashAmount, cardAmount := 20.0, 21.65
+	paid := "PAID"
+	cairo := time.FixedZone("EET", 2*60*60)
+
+	return Receipt{
+		Restaurant: Restaurant{Name: "Nile Grill", Address: "1 Nile Street", Phone: "+20 2 1234"},
+		Invoice: models.Invoice{
+			InvoiceNumber: "INV-2024-000042",
+			CreatedAt:     time.Date(2024, 3, 5, 18, 30, 0, 0, time.UTC),
+			Covers:        2,
+			SubTotal:      40,
+			Discounts:     []models.DiscountLine{{Name: "Happy hour", Amount: 5}},
+			Taxes:         []models.TaxLine{{Name: "VAT", Rate: 14, TaxAmount: 4.9}},
+			ServiceRate:   0,
+			Total:         39.9,
+			TipTotal:      2,
+			BalanceDue:    0,
+			PaymentStatus: &paid,
+		},
+		Lines: []models.InvoiceLine{
+			{Name: "Koshari", Quantity: 2, UnitPrice: 12.5, LineTotal: 25},
+			{Name: "Mint tea", Quantity: 3, UnitPrice: 5, LineTotal: 15},
+		},
+		Payments: []models.Payment{
+			{Method: &cash, Amount: &cashAmount, ChangeDue: 5},
+			{Method: &card, Amount: &cardAmount, Refunded: 1.75},
+		},
+		Location: cairo,
+	}
+}
+
+func TestRenderDefaultTemplate(t *testing.T) {
+	out, err := Render(sampleReceipt(), DefaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"               Nile Grill",
+		"             1 Nile Street",
+		fmt.Sprintf("%-12s%28s", "Invoice", "INV-2024-000042"),
+		fmt.Sprintf("%-12s%28s", "Date", "2024-03-05 20:30"),
+		fmt.Sprintf("%-12s%28s", "Guests", "2"),
+		"Koshari",
+		row("  2 x 12.50", "25.00"),
+		row("  3 x 5.00", "15.00"),
+		row("Subtotal", "40.00"),
+		row("Happy hour", "-5.00"),
+		row("VAT 14.00%", "4.90"),
+		row("TOTAL", "39.90"),
+		row("Paid CASH", "20.00"),
+		row("  Change", "5.00"),
+		row("Paid CARD", "21.65"),
+		row("  Refunded", "-1.75"),
+		row("Tips", "2.00"),
+		row("Balance due", "0.00"),
+		row("Status", "PAID"),
+	}
+
+	lines := strings.Split(out, "\n")
+	next := 0
+	for _, line := range lines {
+		if next < len(want) && line == want[next] {
+			next++
+		}
+	}
+	if next < len(want) {
+		t.Errorf("receipt is missing %q (or it is out of order):\n%s", want[next], out)
+	}
+
+	for _, unwanted := range []string{"Service charge", "tax included"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("receipt shows %q although it does not apply:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestRenderOptionalSections(t *testing.T) {
+	receipt := sampleReceipt()
+	receipt.Invoice.ServiceRate = 12.5
+	receipt.Invoice.ServiceCharge = 5
+	receipt.Invoice.TaxInclusive = true
+	receipt.Invoice.Discounts = nil
+	receipt.Invoice.TipTotal = 0
+	receipt.Restaurant.Address = ""
+
+	out, err := Render(receipt, DefaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{row("Service charge 12.50%", "5.00"), "  (tax included in prices)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("receipt is missing %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"Happy hour", "Tips", "Nile Street"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("receipt shows %q:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestRenderCustomTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{"money", `{{money .Invoice.Total}}`, "39.90", false},
+		{"columns are cut to width", `[{{left 4 "Koshari"}}][{{right 6 "ab"}}]`, "[Kosh][    ab]", false},
+		{"center", `[{{center 6 "ab"}}]`, "[  ab]", false},
+		{"deref of unset fields", `[{{deref .Invoice.PaymentMethod}}]`, "[]", false},
+		{"date defaults to UTC", `{{date .Invoice.CreatedAt}}`, "2024-03-05 18:30", false},
+		{"bad template", `{{money`, "", true},
+		{"unknown field", `{{.Invoice.Nothing}}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := sampleReceipt()
+			receipt.Location = nil
+
+			got, err := Render(receipt, tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDF(t *testing.T) {
+	pdf := PDF("Nile Grill (Cairo)\nTotal 39.90 é\n")
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("not a PDF document:\n%s", pdf)
+	}
+	for _, want := range []string{`(Nile Grill \(Cairo\)) Tj`, "(Total 39.90 \xe9) Tj", "/Count 1"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("PDF is missing %q", want)
+		}
+	}
+
+	long := strings.Repeat("line\n", linesPerPage+1)
+	if pages := PDF(long); !bytes.Contains(pages, []byte("/Count 2")) {
+		t.Errorf("%d lines did not take two pages", linesPerPage+1)
+	}
+}
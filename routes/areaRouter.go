@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AreaRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/areas", controller.GetAreas())
+	incomingRoutes.GET("/areas/:area_id", controller.GetAreaByID())
+	incomingRoutes.POST("/areas", controller.CreateArea())
+	incomingRoutes.PATCH("/areas/:area_id", controller.UpdateArea())
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func BusinessDayRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/businessDays", controller.GetBusinessDays())
+	incomingRoutes.GET("/businessDays/current", controller.GetCurrentBusinessDay())
+	incomingRoutes.POST("/businessDays/close", controller.CloseBusinessDay())
+	incomingRoutes.GET("/businessDays/:business_day_id", controller.GetBusinessDayByID())
+	incomingRoutes.GET("/businessDays/:business_day_id/report", controller.GetBusinessDayReport())
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func DiscountRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/discounts", controller.GetAllDiscounts())
+	incomingRoutes.GET("/discounts/:discount_id", controller.GetDiscountByID())
+	incomingRoutes.POST("/discounts", controller.CreateDiscount())
+	incomingRoutes.PATCH("/discounts/:discount_id", controller.UpdateDiscount())
+	incomingRoutes.GET("/orders/:order_id/discounts", controller.GetOrderDiscounts())
+	incomingRoutes.POST("/orders/:order_id/discounts", controller.ApplyOrderDiscount())
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func DrawerRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/drawers", controller.GetDrawerSessions())
+	incomingRoutes.GET("/drawers/:drawer_session_id", controller.GetDrawerSessionByID())
+	incomingRoutes.POST("/drawers", controller.OpenDrawerSession())
+	incomingRoutes.POST("/drawers/:drawer_session_id/movements", controller.AddCashMovement())
+	incomingRoutes.POST("/drawers/:drawer_session_id/close", controller.CloseDrawerSession())
+}
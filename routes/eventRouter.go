@@ -0,0 +1,11 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func EventRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/events", controller.StreamEvents())
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func FloorPlanRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/floorplan", controller.GetFloorPlan())
+	incomingRoutes.PUT("/floorplan", controller.SaveFloorPlan())
+}
@@ -11,4 +11,7 @@ func FoodRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/foods/:food_id", controller.GetFoodByID())
 	incomingRoutes.POST("/foods", controller.CreateFood())
 	incomingRoutes.PATCH("/foods/:food_id", controller.UpdateFood())
+	incomingRoutes.PATCH("/foods/:food_id/availability", controller.SetFoodAvailability())
+	incomingRoutes.GET("/foods/:food_id/recipe", controller.GetRecipe())
+	incomingRoutes.PUT("/foods/:food_id/recipe", controller.SetRecipe())
 }
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GuestOrderRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/guestOrders", controller.GetGuestOrders())
+	incomingRoutes.POST("/guestOrders/:guest_order_id/approve", controller.ApproveGuestOrder())
+	incomingRoutes.POST("/guestOrders/:guest_order_id/reject", controller.RejectGuestOrder())
+}
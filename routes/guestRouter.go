@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GuestRoutes are used by guests who scanned a table's QR code and are
+// verified by the code's table token rather than by user token
+func GuestRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/guest/menu", controller.GetGuestMenu())
+	incomingRoutes.POST("/guest/orders", controller.CreateGuestOrder())
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HouseAccountRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/houseAccounts", controller.GetHouseAccounts())
+	incomingRoutes.GET("/houseAccounts/:account_id", controller.GetHouseAccountByID())
+	incomingRoutes.POST("/houseAccounts", controller.CreateHouseAccount())
+	incomingRoutes.PATCH("/houseAccounts/:account_id", controller.UpdateHouseAccount())
+	incomingRoutes.GET("/houseAccounts/:account_id/invoices", controller.GetHouseAccountInvoices())
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func IngredientRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/ingredients", controller.GetIngredients())
+	incomingRoutes.GET("/ingredients/:ingredient_id", controller.GetIngredient())
+	incomingRoutes.POST("/ingredients", controller.CreateIngredient())
+	incomingRoutes.PATCH("/ingredients/:ingredient_id", controller.UpdateIngredient())
+	incomingRoutes.POST("/ingredients/:ingredient_id/movements", controller.CreateStockMovement())
+	incomingRoutes.GET("/stockMovements", controller.GetStockMovements())
+}
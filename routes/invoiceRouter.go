@@ -9,7 +9,18 @@ import (
 func InvoiceRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/invoices", controller.GetAllInvoices())
 	incomingRoutes.GET("/invoices/:invoice_id", controller.GetInvoiceByID())
+	incomingRoutes.GET("/invoices/:invoice_id/detail", controller.GetInvoiceDetail())
+	incomingRoutes.GET("/invoices/:invoice_id/pdf", controller.GetInvoicePDF())
+	incomingRoutes.GET("/invoices/:invoice_id/receipt.txt", controller.GetInvoiceReceipt())
 	incomingRoutes.POST("/invoices", controller.CreateInvoice())
 	incomingRoutes.PATCH("/invoices/:invoice_id", controller.UpdateInvoice())
-
+	incomingRoutes.POST("/invoices/:invoice_id/remind", controller.SendInvoiceReminder())
+	incomingRoutes.GET("/invoices/:invoice_id/payments", controller.GetInvoicePayments())
+	incomingRoutes.POST("/invoices/:invoice_id/payments", controller.CreatePayment())
+	incomingRoutes.GET("/invoices/:invoice_id/refunds", controller.GetInvoiceRefunds())
+	incomingRoutes.POST("/invoices/:invoice_id/refunds", controller.CreateRefund())
+	incomingRoutes.GET("/invoices/:invoice_id/tips", controller.GetInvoiceTips())
+	incomingRoutes.POST("/invoices/:invoice_id/tips", controller.CreateTip())
+	incomingRoutes.GET("/creditNotes", controller.GetCreditNotes())
+	incomingRoutes.GET("/creditNotes/:credit_note_id", controller.GetCreditNoteByID())
 }
@@ -11,4 +11,9 @@ func OrderRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/orders/:order_id", controller.GetOrderByID())
 	incomingRoutes.POST("/orders", controller.CreateOrder())
 	incomingRoutes.PATCH("/orders/:order_id", controller.UpdateOrder())
+	incomingRoutes.GET("/orders/:order_id/total", controller.GetOrderTotal())
+	incomingRoutes.POST("/orders/:order_id/void", controller.VoidOrder())
+	incomingRoutes.POST("/orders/:order_id/seat", controller.SeatOrder())
+	incomingRoutes.POST("/orders/:order_id/unseat", controller.UnseatOrder())
+	incomingRoutes.PATCH("/orders/:order_id/server", controller.ReassignOrder())
 }
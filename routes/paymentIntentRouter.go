@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PaymentIntentRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.POST("/invoices/:invoice_id/intents", controller.CreatePaymentIntent())
+	incomingRoutes.GET("/paymentIntents/:intent_id", controller.GetPaymentIntentByID())
+	incomingRoutes.POST("/paymentIntents/:intent_id/capture", controller.CapturePaymentIntent())
+	incomingRoutes.POST("/paymentIntents/:intent_id/void", controller.VoidPaymentIntent())
+}
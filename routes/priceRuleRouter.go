@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PriceRuleRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/priceRules", controller.GetAllPriceRules())
+	incomingRoutes.GET("/priceRules/:price_rule_id", controller.GetPriceRuleByID())
+	incomingRoutes.POST("/priceRules", controller.CreatePriceRule())
+	incomingRoutes.PATCH("/priceRules/:price_rule_id", controller.UpdatePriceRule())
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PrinterRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/printers", controller.GetPrinters())
+	incomingRoutes.GET("/printers/:printer_id", controller.GetPrinterByID())
+	incomingRoutes.POST("/printers", controller.CreatePrinter())
+	incomingRoutes.PATCH("/printers/:printer_id", controller.UpdatePrinter())
+	incomingRoutes.GET("/printJobs", controller.GetPrintJobs())
+	incomingRoutes.POST("/orders/:order_id/tickets", controller.PrintOrderTickets())
+	incomingRoutes.POST("/invoices/:invoice_id/print", controller.PrintInvoiceReceipt())
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReportRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/reports/tips", controller.GetTipReport())
+	incomingRoutes.GET("/reports/aging", controller.GetAgingReport())
+	incomingRoutes.GET("/reports/invoiceGaps", controller.GetInvoiceGapReport())
+	incomingRoutes.GET("/reports/sales", controller.GetSalesReport())
+	incomingRoutes.GET("/reports/sales/periods", controller.GetSalesByPeriod())
+	incomingRoutes.GET("/reports/sales/foods", controller.GetSalesByFood())
+	incomingRoutes.GET("/reports/sales/categories", controller.GetSalesByCategory())
+	incomingRoutes.GET("/reports/menuEngineering", controller.GetMenuEngineeringReport())
+	incomingRoutes.GET("/reports/tables", controller.GetTableTurnoverReport())
+	incomingRoutes.GET("/reports/servers", controller.GetServerReport())
+	incomingRoutes.GET("/reports/labor", controller.GetLaborReport())
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReservationRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/reservations", controller.GetReservations())
+	incomingRoutes.GET("/reservations/availability", controller.GetAvailability())
+	incomingRoutes.GET("/reservations/:reservation_id", controller.GetReservationByID())
+	incomingRoutes.POST("/reservations", controller.CreateReservation())
+	incomingRoutes.PATCH("/reservations/:reservation_id", controller.UpdateReservation())
+	incomingRoutes.PATCH("/reservations/:reservation_id/status", controller.UpdateReservationStatus())
+}
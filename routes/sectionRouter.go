@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SectionRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/sections", controller.GetSections())
+	incomingRoutes.GET("/sections/:section_id", controller.GetSectionByID())
+	incomingRoutes.POST("/sections", controller.CreateSection())
+	incomingRoutes.PATCH("/sections/:section_id", controller.UpdateSection())
+}
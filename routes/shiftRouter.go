@@ -0,0 +1,18 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ShiftRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/shiftTemplates", controller.GetShiftTemplates())
+	incomingRoutes.POST("/shiftTemplates", controller.CreateShiftTemplate())
+	incomingRoutes.PATCH("/shiftTemplates/:shift_template_id", controller.UpdateShiftTemplate())
+	incomingRoutes.GET("/schedule", controller.GetSchedule())
+	incomingRoutes.GET("/shifts/:shift_id", controller.GetShift())
+	incomingRoutes.POST("/shifts", controller.CreateShift())
+	incomingRoutes.PATCH("/shifts/:shift_id", controller.UpdateShift())
+	incomingRoutes.POST("/shifts/:shift_id/cancel", controller.CancelShift())
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TableGroupRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/tableGroups", controller.GetTableGroups())
+	incomingRoutes.GET("/tableGroups/:group_id", controller.GetTableGroup())
+	incomingRoutes.POST("/tableGroups", controller.CreateTableGroup())
+	incomingRoutes.POST("/tableGroups/:group_id/dissolve", controller.DissolveTableGroup())
+}
@@ -8,7 +8,12 @@ import (
 
 func TableRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/tables", controller.GetAllTables())
+	incomingRoutes.GET("/tables/board", controller.GetTableBoard())
 	incomingRoutes.GET("/tables/:table_id", controller.GetTableByID())
 	incomingRoutes.POST("/tables", controller.CreateTable())
 	incomingRoutes.PATCH("/tables/:table_id", controller.UpdateTable())
+	incomingRoutes.PATCH("/tables/:table_id/status", controller.UpdateTableStatus())
+	incomingRoutes.PATCH("/tables/:table_id/server", controller.ReassignTable())
+	incomingRoutes.GET("/tables/:table_id/qr", controller.GetTableQRCode())
+	incomingRoutes.POST("/tables/:table_id/qr/rotate", controller.RotateTableQRCode())
 }
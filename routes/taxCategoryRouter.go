@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TaxCategoryRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/taxCategories", controller.GetAllTaxCategories())
+	incomingRoutes.GET("/taxCategories/:tax_category_id", controller.GetTaxCategoryByID())
+	incomingRoutes.POST("/taxCategories", controller.CreateTaxCategory())
+	incomingRoutes.PATCH("/taxCategories/:tax_category_id", controller.UpdateTaxCategory())
+}
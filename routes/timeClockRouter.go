@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TimeClockRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/timeclock", controller.GetTimeEntries())
+	incomingRoutes.GET("/timeclock/active", controller.GetClockedIn())
+	incomingRoutes.POST("/timeclock/in", controller.ClockIn())
+	incomingRoutes.POST("/timeclock/out", controller.ClockOut())
+	incomingRoutes.POST("/timeclock/break/start", controller.StartBreak())
+	incomingRoutes.POST("/timeclock/break/end", controller.EndBreak())
+	incomingRoutes.PATCH("/timeclock/:time_entry_id", controller.UpdateTimeEntry())
+}
@@ -12,4 +12,7 @@ func UserRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.POST("/users/signup", controller.SignUp())
 	incomingRoutes.POST("/users/login", controller.Login())
 	incomingRoutes.POST("/users/logout", middleware.Authentication(), controller.Logout())
+	incomingRoutes.PATCH("/users/:user_id/role", middleware.Authentication(), controller.UpdateUserRole())
+	incomingRoutes.GET("/users/:user_id/labor", middleware.Authentication(), controller.GetUserLabor())
+	incomingRoutes.PATCH("/users/:user_id/labor", middleware.Authentication(), controller.UpdateUserLabor())
 }
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WaitlistRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/waitlist", controller.GetWaitlist())
+	incomingRoutes.GET("/waitlist/quote", controller.GetWaitQuote())
+	incomingRoutes.GET("/waitlist/:waitlist_id", controller.GetWaitlistEntry())
+	incomingRoutes.POST("/waitlist", controller.JoinWaitlist())
+	incomingRoutes.PATCH("/waitlist/:waitlist_id", controller.UpdateWaitlistEntry())
+	incomingRoutes.POST("/waitlist/:waitlist_id/notify", controller.NotifyWaitlistEntry())
+	incomingRoutes.POST("/waitlist/:waitlist_id/seat", controller.SeatWaitlistEntry())
+	incomingRoutes.POST("/waitlist/:waitlist_id/leave", controller.LeaveWaitlist())
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	controller "github.com/ali-adel-nour/restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRoutes are called by payment providers and are verified by
+// signature rather than by user token
+func WebhookRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.POST("/webhooks/payments/:provider", controller.PaymentWebhook())
+}